@@ -1,38 +1,562 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"myapp/internal/audit"
 	"myapp/internal/chat"
+	"myapp/internal/events"
+	"myapp/internal/health"
+	"myapp/internal/jobs"
+	"myapp/internal/metrics"
+	"myapp/internal/migrations"
+	"myapp/internal/privacy"
+	"myapp/internal/retention"
+	"myapp/internal/tracing"
 	"myapp/pkg/config"
 	"myapp/pkg/database"
+	"myapp/pkg/encryption"
+	"myapp/pkg/idempotency"
+	"myapp/pkg/identity"
 	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
+	"myapp/pkg/migrate"
+	"myapp/pkg/ratelimit"
+	"myapp/pkg/redact"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func main() {
 	// confikg yükleme
 	cfg := config.Load() // bu da özel fonksiyonmuş
 
+	// PII kuralları config'ten gelir; hata olursa logger henüz yok
+	customRules, err := redact.ParseRules(cfg.RedactPatterns)
+	if err != nil {
+		log.Fatalf("invalid REDACT_PATTERNS: %v", err)
+	}
+	redactor := redact.New(append(redact.Default(), customRules...)...)
+
 	//logger açma
-	logger.Init(cfg.Env == "dev")
-	defer logger.Log.Sync()
+	var logOpts []zap.Option
+	if cfg.RedactLogs {
+		logOpts = append(logOpts, zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return redact.NewCore(c, redactor)
+		}))
+	}
+	logger.Init(cfg.Env == "dev", logOpts...)
 
 	//database
-	db := database.Connect(cfg.DatabaseURL)
-	db.AutoMigrate(&chat.ChatMessage{})
+	db, err := database.Connect(database.Config{
+		Driver:          cfg.DatabaseDriver,
+		DSN:             cfg.DatabaseURL,
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+	})
+	if err != nil {
+		logger.Log.Fatal("database connection failed", zap.Error(err))
+	}
+	logger.Log.Info("database connected", zap.String("driver", cfg.DatabaseDriver))
+
+	// şema versiyonlu migration'larla kurulur (internal/migrations)
+	migrator, err := migrations.New(db, cfg.MigrateLockTimeout)
+	if err != nil {
+		logger.Log.Fatal("invalid migrations", zap.Error(err))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(migrator, os.Args[2:]))
+	}
+	migrateOnStart(migrator, cfg.MigrateOnStart)
+
+	// kim neyi okudu/değiştirdi; config'in hash'i her açılışta yazılır
+	auditLog := audit.NewGormLog(db, cfg.AuditHashChain)
+	if err := audit.RecordConfig(context.Background(), auditLog, cfg.Redacted()); err != nil {
+		logger.Log.Error("failed to audit config", zap.Error(err))
+	}
 	//echo başlatma
 	e := echo.New()
+	e.HTTPErrorHandler = chat.HTTPErrorHandler
+	// sıra önemli: recover en içte ki access log panic'in 500'ünü görsün
+	// metrikler ve span'ler Client/Repository/Service decorator'ları ve HTTP middleware'i ile toplanır
+	m := metrics.New()
+	tr, shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Enabled:        cfg.TracingEnabled,
+		ServiceName:    cfg.ServiceName,
+		ServiceVersion: health.Version,
+	})
+	if err != nil {
+		logger.Log.Fatal("tracing setup failed", zap.Error(err))
+	}
+	e.Use(middleware.RequestID(), tr.Middleware(), m.Middleware(), middleware.AccessLog(), middleware.Recover(), identity.Middleware())
+
+	// worker ve dispatcher bu context iptal edilince yeni iş almayı bırakır
+	background, stopBackground := context.WithCancel(context.Background())
+
+	var repoOpts []chat.RepositoryOption
+	var jobOpts []jobs.StoreOption
+	var idempotencyOpts []idempotency.GormOption
+	var reencryptor *chat.Reencryptor
+	var keyring encryption.Keyring
+	if kms := loadKMS(cfg); kms != nil {
+		keyring = encryption.NewKeyring(audit.InstrumentKeyStore(encryption.NewGormStore(db), auditLog), kms, cfg.EncryptionKeyCacheTTL)
+		repoOpts = append(repoOpts, chat.WithEncryption(keyring))
+		// async job'lar ve saklanan idempotency cevapları da mesaj içeriği taşır
+		jobOpts = append(jobOpts, jobs.WithEncryption(keyring))
+		idempotencyOpts = append(idempotencyOpts, idempotency.WithEncryption(keyring))
+		// mevcut şifresiz satırlar ve eski key'li satırlar arka planda güncel key'e taşınır
+		reencryptor = chat.NewReencryptor(db, keyring, chat.ReencryptConfig{
+			Interval:  cfg.EncryptionReencryptInterval,
+			BatchSize: cfg.EncryptionReencryptBatch,
+			KeyMaxAge: cfg.EncryptionKeyMaxAge,
+		}, jobs.NewSealedTable(db, keyring), idempotency.NewSealedTable(db, keyring))
+		reencryptor.Start(background)
+	}
+	chatRepo := m.InstrumentRepository(tr.InstrumentRepository(chat.NewRepository(db, cfg.DBTimeout, repoOpts...), cfg.DatabaseDriver))
+
+	// process içi tüketiciler bus'a abone olur
+	bus := events.NewBus()
+	sinks := []events.Sink{bus}
+	if cfg.EventsWebhookURL != "" {
+		sinks = append(sinks, events.NewHTTPSink(cfg.EventsWebhookURL, cfg.EventsWebhookSecret, cfg.OutboxPublishTimeout))
+	}
+	if cfg.NATSURL != "" {
+		nc, err := nats.Connect(cfg.NATSURL, nats.Name("llm-chat-service"))
+		if err != nil {
+			logger.Log.Fatal("nats connection failed", zap.Error(err))
+		}
+		defer nc.Close()
+		sinks = append(sinks, events.NewNATSSink(nc, cfg.NATSSubjectPrefix))
+	}
+	outbox := events.NewGormOutbox(db)
+	m.RegisterQueue("outbox", outbox.Backlog)
+	dispatcher := events.NewDispatcher(outbox, events.DispatcherConfig{
+		PollInterval:   cfg.OutboxPollInterval,
+		BatchSize:      cfg.OutboxBatchSize,
+		PublishTimeout: cfg.OutboxPublishTimeout,
+		RetryBaseDelay: cfg.OutboxRetryBaseDelay,
+		RetryMaxDelay:  cfg.OutboxRetryMaxDelay,
+	}, sinks...)
+	dispatcher.Start(background)
+
+	retryPolicy := chat.RetryPolicy{
+		MaxAttempts: cfg.LLMRetryMaxAttempts,
+		BaseDelay:   cfg.LLMRetryBaseDelay,
+		MaxDelay:    cfg.LLMRetryMaxDelay,
+		Budget:      cfg.LLMRetryBudget,
+	}
+	breakerConfig := chat.BreakerConfig{
+		FailureThreshold: cfg.BreakerFailureThreshold,
+		OpenTimeout:      cfg.BreakerOpenTimeout,
+		HalfOpenMaxCalls: cfg.BreakerHalfOpenMaxCalls,
+	}
+	var providers []chat.Provider
+	var probes []health.Checker
+	// retry'lar provider başına yapılır; yedeklere de bütçeden pay kalsın
+	providerRetryPolicy := retryPolicy.Split(len(cfg.LLMProviders))
+	for _, p := range cfg.LLMProviders {
+		providerClient := chat.NewProviderClient(chat.ProviderConfig{
+			Name:    p.Name,
+			BaseURL: p.BaseURL,
+			APIKey:  p.APIKey,
+			Model:   p.Model,
+			Timeout: cfg.LLMTimeout,
+		})
+		providers = append(providers, chat.Provider{
+			Name:    p.Name,
+			Model:   p.Model,
+			Client:  chat.NewRetryingClient(m.InstrumentClient(tr.InstrumentClient(providerClient, p.Name, p.Model), p.Name, p.Model), providerRetryPolicy),
+			Breaker: chat.NewCircuitBreaker(breakerConfig),
+		})
+		probes = append(probes, health.Cached(health.CheckFunc("llm:"+p.Name, providerClient.(chat.Pinger).Ping), cfg.LLMProbeCacheTTL))
+	}
+	failover := chat.NewFailoverClient(providers...)
+	var client chat.Client = failover
+	if cfg.ModelRoutesFile != "" {
+		routes, err := chat.LoadRouterConfig(cfg.ModelRoutesFile)
+		if err != nil {
+			logger.Log.Fatal("invalid model routes", zap.Error(err))
+		}
+		var classifier chat.Classifier
+		if routes.Classifier != nil {
+			primary := cfg.LLMProviders[0]
+			classifierClient := chat.NewProviderClient(chat.ProviderConfig{
+				Name:    "classifier",
+				BaseURL: primary.BaseURL,
+				APIKey:  primary.APIKey,
+				Model:   routes.Classifier.Model,
+				Timeout: cfg.LLMTimeout,
+			})
+			classifierClient = tr.InstrumentClient(classifierClient, "classifier", routes.Classifier.Model)
+			classifierClient = m.InstrumentClient(classifierClient, "classifier", routes.Classifier.Model)
+			classifier = chat.NewLLMClassifier(chat.NewRetryingClient(classifierClient, retryPolicy), routes.Classifier.Labels)
+		}
+		client = chat.NewRouter(failover, routes, classifier)
+	}
+	// en dışta: classifier da maskelenmiş prompt'u görür
+	if cfg.RedactPrompts {
+		client = chat.NewRedactingClient(client, redactor)
+	}
+
+	var locker chat.SessionLocker
+	switch cfg.SessionLocker {
+	case "local":
+		locker = chat.NewLocalLocker()
+	case "mysql":
+		if cfg.DatabaseDriver != database.MySQL {
+			logger.Log.Fatal("mysql session locker requires the mysql driver", zap.String("driver", cfg.DatabaseDriver))
+		}
+		locker = chat.NewMySQLLocker(db, cfg.SessionLockWait)
+	case "postgres":
+		if cfg.DatabaseDriver != database.Postgres {
+			logger.Log.Fatal("postgres session locker requires the postgres driver", zap.String("driver", cfg.DatabaseDriver))
+		}
+		locker = chat.NewPostgresLocker(db, cfg.SessionLockWait)
+	default:
+		logger.Log.Fatal("invalid session locker", zap.String("locker", cfg.SessionLocker))
+	}
+
+	serviceOpts := []chat.Option{chat.WithLocker(locker)}
+	if cfg.ModerationProviders != "" {
+		var moderators []chat.Moderator
+		for _, name := range strings.Split(cfg.ModerationProviders, ",") {
+			switch strings.TrimSpace(name) {
+			case "local":
+				rules, err := chat.LoadModerationRules(cfg.ModerationRulesFile)
+				if err != nil {
+					logger.Log.Fatal("invalid moderation rules", zap.Error(err))
+				}
+				moderator, err := chat.NewKeywordModerator(rules)
+				if err != nil {
+					logger.Log.Fatal("invalid moderation rules", zap.Error(err))
+				}
+				moderators = append(moderators, moderator)
+			case "openai":
+				var moderator chat.Moderator = chat.NewOpenAIModerator(cfg.ApiKey, cfg.ModerationTimeout)
+				if cfg.RedactPrompts {
+					moderator = chat.NewRedactingModerator(moderator, redactor)
+				}
+				moderators = append(moderators, moderator)
+			default:
+				logger.Log.Fatal("invalid moderation provider", zap.String("provider", name))
+			}
+		}
+		action := chat.ModerationAction(cfg.ModerationAction)
+		if action != chat.ModerationReject && action != chat.ModerationRefuse {
+			logger.Log.Fatal("invalid moderation action", zap.String("action", cfg.ModerationAction))
+		}
+		serviceOpts = append(serviceOpts, chat.WithModeration(chat.ModerationConfig{
+			Moderator:      chat.NewMultiModerator(moderators...),
+			Action:         action,
+			RefusalMessage: cfg.ModerationRefusalMessage,
+			FailOpen:       cfg.ModerationFailOpen,
+		}))
+	}
+	if cfg.GuardrailAction != "" {
+		action := chat.GuardAction(cfg.GuardrailAction)
+		if action != chat.GuardWarn && action != chat.GuardStrip && action != chat.GuardBlock {
+			logger.Log.Fatal("invalid guardrail action", zap.String("action", cfg.GuardrailAction))
+		}
+		var judge chat.Judge
+		if cfg.GuardrailJudgeModel != "" {
+			primary := cfg.LLMProviders[0]
+			var judgeClient chat.Client = chat.NewProviderClient(chat.ProviderConfig{
+				Name:    "guardrail-judge",
+				BaseURL: primary.BaseURL,
+				APIKey:  primary.APIKey,
+				Model:   cfg.GuardrailJudgeModel,
+				Timeout: cfg.LLMTimeout,
+			})
+			judgeClient = tr.InstrumentClient(judgeClient, "guardrail-judge", cfg.GuardrailJudgeModel)
+			judgeClient = m.InstrumentClient(judgeClient, "guardrail-judge", cfg.GuardrailJudgeModel)
+			judgeClient = chat.NewRetryingClient(judgeClient, retryPolicy)
+			if cfg.RedactPrompts {
+				judgeClient = chat.NewRedactingClient(judgeClient, redactor)
+			}
+			judge = chat.NewLLMJudge(judgeClient)
+		}
+		serviceOpts = append(serviceOpts, chat.WithGuardrail(chat.NewGuardrail(chat.GuardrailConfig{
+			Action:    action,
+			Threshold: cfg.GuardrailThreshold,
+			Judge:     judge,
+		})))
+	}
+	chatService := m.InstrumentService(tr.InstrumentService(chat.NewService(chatRepo, client, serviceOpts...)))
+
+	chatHandler := tr.InstrumentHandler(chat.NewHandler(chatService))
+
+	var chatMiddleware []echo.MiddlewareFunc
+	if cfg.RateLimitEnabled {
+		proxies, err := ratelimit.ParseProxies(cfg.RateLimitTrustedProxies)
+		if err != nil {
+			logger.Log.Fatal("invalid rate limit config", zap.Error(err))
+		}
+		keyFunc, err := ratelimit.KeyFuncByName(cfg.RateLimitKey, proxies)
+		if err != nil {
+			logger.Log.Fatal("invalid rate limit config", zap.Error(err))
+		}
+		if (cfg.RateLimitKey == "api_key" || cfg.RateLimitKey == "user") && len(proxies) == 0 {
+			logger.Log.Warn("RATE_LIMIT_TRUSTED_PROXIES is empty, rate limiting by client IP", zap.String("key", cfg.RateLimitKey))
+		}
+		chatMiddleware = append(chatMiddleware, ratelimit.Middleware(ratelimit.Config{
+			Rule:    ratelimit.PerMinute(cfg.RateLimitPerMinute, cfg.RateLimitBurst),
+			Store:   ratelimit.NewMemoryStore(),
+			KeyFunc: keyFunc,
+		}))
+	}
 
-	chatRepo := chat.NewRepository(db)
+	var idempotencyStore idempotency.Store
+	switch cfg.IdempotencyStore {
+	case "memory":
+		idempotencyStore = idempotency.NewMemoryStore()
+	case "db":
+		idempotencyStore = idempotency.NewGormStore(db, idempotencyOpts...)
+	default:
+		logger.Log.Fatal("invalid idempotency store", zap.String("store", cfg.IdempotencyStore))
+	}
+	// rate limit önce: replay'ler de kotadan düşsün
+	chatMiddleware = append(chatMiddleware, idempotency.Middleware(idempotency.Config{
+		Store: idempotencyStore,
+		TTL:   cfg.IdempotencyTTL,
+	}))
 
-	client := chat.NewClient(cfg.ApiKey)
+	e.POST("v1/chat", chatHandler.Send, chatMiddleware...)
+	e.GET("v1/chat/:sessionId", chatHandler.ShowHistory, audit.Middleware(auditLog, audit.HistoryRead, audit.Param("sessionId")))
 
-	chatService := chat.NewService(chatRepo, client)
+	jobStore := jobs.NewGormStore(db, jobOpts...)
+	m.RegisterQueue("jobs", jobStore.Depth)
+	var deliverer jobs.Deliverer
+	if cfg.WebhookSecret != "" {
+		deliverer = jobs.NewHTTPDeliverer(cfg.WebhookSecret, cfg.WebhookTimeout, cfg.WebhookAllowPrivate)
+	}
+	worker := jobs.NewWorker(jobStore, chatService, deliverer, jobs.Config{
+		Workers:            cfg.JobWorkers,
+		PollInterval:       cfg.JobPollInterval,
+		JobTimeout:         cfg.JobTimeout,
+		MaxAttempts:        cfg.JobMaxAttempts,
+		WebhookMaxAttempts: cfg.WebhookMaxAttempts,
+		WebhookBaseDelay:   cfg.WebhookBaseDelay,
+		WebhookTimeout:     cfg.WebhookTimeout,
+	})
+	worker.Start(background)
 
-	chatHandler := chat.NewHandler(chatService)
+	jobHandler := jobs.NewHandler(jobStore, deliverer != nil, cfg.WebhookAllowPrivate)
+	e.POST("v1/chat/async", jobHandler.Enqueue, chatMiddleware...)
+	e.GET("v1/jobs/:id", jobHandler.Show)
 
-	e.POST("v1/chat", chatHandler.Send)
-	e.GET("v1/chat/:sessionId", chatHandler.ShowHistory)
+	privacyHandler := privacy.NewHandler(privacy.NewService(chatRepo, jobStore, idempotencyStore, privacy.NewGormAuditStore(db)))
+	e.GET("v1/me/export", privacyHandler.Export, audit.Middleware(auditLog, audit.UserExport, audit.Self))
+	e.DELETE("v1/me", privacyHandler.Erase, audit.Middleware(auditLog, audit.UserErase, audit.Self))
+	e.GET("v1/me/search", chat.NewSearchHandler(chatRepo).Search, audit.Middleware(auditLog, audit.MessageSearch, audit.Self))
+
+	var purger *retention.Purger
+	if cfg.RetentionPolicyFile != "" {
+		policy, err := retention.LoadPolicy(cfg.RetentionPolicyFile)
+		if err != nil {
+			logger.Log.Fatal("invalid retention policy", zap.Error(err))
+		}
+		purger = retention.NewPurger(db, policy, retention.Config{
+			Interval:         cfg.RetentionInterval,
+			BatchSize:        cfg.RetentionBatchSize,
+			DryRun:           cfg.RetentionDryRun,
+			OutboxMaxAgeDays: cfg.RetentionOutboxMaxDays,
+		}, m)
+		purger.Start(background)
+	}
+
+	healthHandler := health.NewHandler(cfg.ReadinessTimeout,
+		health.Database(db),
+		health.Migrations(migrator),
+		health.AnyOf("llm", probes...),
+	)
+	e.GET("healthz", healthHandler.Healthz)
+	e.GET("readyz", healthHandler.Readyz)
+	e.GET("metrics", echo.WrapHandler(m.Handler()))
+
+	diagnosticsHandler := chat.NewDiagnosticsHandler(failover)
+	debug := e.Group("debug", middleware.AdminAuth(cfg.AdminToken))
+	debug.GET("/providers", diagnosticsHandler.Providers)
+	debug.GET("/info", health.NewInfoHandler(cfg.Redacted(), db), audit.Middleware(auditLog, audit.ConfigRead, nil))
+	debug.GET("/moderation", chat.NewModerationHandler(chatRepo).Flagged, audit.Middleware(auditLog, audit.ModerationRead, nil))
+	if keyring != nil {
+		debug.POST("/keys/:tenant/rotate", chat.NewKeyHandler(keyring).Rotate, audit.Middleware(auditLog, audit.KeyRotate, audit.Param("tenant")))
+	}
+	auditHandler := audit.NewHandler(auditLog)
+	debug.GET("/audit", auditHandler.Query, audit.Middleware(auditLog, audit.AuditRead, nil))
+	debug.GET("/audit/verify", auditHandler.Verify)
+	if purger != nil {
+		retentionHandler := retention.NewHandler(purger, retention.NewGormHoldStore(db))
+		debug.GET("/retention", retentionHandler.Report)
+		debug.GET("/holds", retentionHandler.Holds)
+		debug.PUT("/holds/:sessionId", retentionHandler.PlaceHold, audit.Middleware(auditLog, audit.LegalHoldPlace, audit.Param("sessionId")))
+		debug.DELETE("/holds/:sessionId", retentionHandler.ReleaseHold, audit.Middleware(auditLog, audit.LegalHoldRelease, audit.Param("sessionId")))
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	go func() {
+		if err := e.Start(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Fatal("server failed", zap.Error(err))
+		}
+	}()
+	<-signals.Done()
+	logger.Log.Info("shutting down", zap.Duration("timeout", cfg.ShutdownTimeout))
+	healthHandler.ShuttingDown()
+
+	// yeni istek kabul edilmez; süren turn'ler deadline'a kadar beklenir,
+	// deadline dolarsa iptal edilip Interrupted olarak kaydedilir
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	stopBackground()
+	serverDone := make(chan error, 1)
+	go func() { serverDone <- e.Shutdown(ctx) }()
+	if err := chatService.Drain(ctx); err != nil {
+		logger.Log.Warn("in-flight turns interrupted", zap.Error(err))
+	}
+	worker.Wait()
+	dispatcher.Wait()
+	if reencryptor != nil {
+		reencryptor.Wait()
+	}
+	if purger != nil {
+		purger.Wait()
+	}
+	if err := <-serverDone; err != nil {
+		logger.Log.Warn("http server did not shut down cleanly", zap.Error(err))
+		e.Close()
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Log.Warn("tracing shutdown failed", zap.Error(err))
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	logger.Log.Info("shutdown complete")
+	logger.Log.Sync()
+}
+
+// migrateOnStart açıksa bekleyen migration'ları uygular, kapalıysa şema
+// güncel değilse başlamayı reddeder.
+func migrateOnStart(migrator *migrate.Migrator, apply bool) {
+	ctx := context.Background()
+	if !apply {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			logger.Log.Fatal("migration check failed", zap.Error(err))
+		}
+		if len(pending) > 0 {
+			logger.Log.Fatal("database schema is not up to date, run `myapp migrate up`",
+				zap.Stringer("next", pending[0]), zap.Int("pending", len(pending)))
+		}
+		return
+	}
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		logger.Log.Info("migration applied", zap.Stringer("migration", m))
+	}
+	if err != nil {
+		logger.Log.Fatal("migration failed", zap.Error(err))
+	}
+}
+
+// runMigrate `myapp migrate [up | down [n] | status]` komutudur; exit kodunu döner.
+func runMigrate(migrator *migrate.Migrator, args []string) int {
+	ctx := context.Background()
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Println("applied", m)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "usage: myapp migrate down [n]")
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, n)
+		for _, m := range reverted {
+			fmt.Println("reverted", m)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Modified {
+				state += " (modified)"
+			}
+			if s.Unknown {
+				state += " (unknown to this binary)"
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "usage: myapp migrate [up | down [n] | status]")
+		return 2
+	}
+	return 0
+}
 
-	e.Logger.Fatal(e.Start(":8080"))
+// loadKMS şifreleme kapalıysa nil döner; key dosyası tek master key'e göre önceliklidir.
+func loadKMS(cfg *config.Config) encryption.KMS {
+	if cfg.EncryptionKeyFile != "" {
+		kms, err := encryption.LoadKeyFile(cfg.EncryptionKeyFile)
+		if err != nil {
+			logger.Log.Fatal("invalid encryption key file", zap.Error(err))
+		}
+		return kms
+	}
+	if cfg.EncryptionMasterKey == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.EncryptionMasterKey)
+	if err != nil {
+		logger.Log.Fatal("invalid ENCRYPTION_MASTER_KEY", zap.Error(err))
+	}
+	kms, err := encryption.NewLocalKMS(cfg.EncryptionMasterKeyID, map[string][]byte{cfg.EncryptionMasterKeyID: key})
+	if err != nil {
+		logger.Log.Fatal("invalid ENCRYPTION_MASTER_KEY", zap.Error(err))
+	}
+	return kms
 }
@@ -0,0 +1,265 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"myapp/pkg/identity"
+	"myapp/pkg/middleware"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Action string
+
+const (
+	HistoryRead      Action = "history.read"
+	ModerationRead   Action = "moderation.read"
+	MessageSearch    Action = "message.search"
+	UserExport       Action = "user.export"
+	UserErase        Action = "user.erase"
+	KeyIssue         Action = "key.issue"
+	KeyRotate        Action = "key.rotate"
+	KeyRewrap        Action = "key.rewrap"
+	LegalHoldPlace   Action = "legal_hold.place"
+	LegalHoldRelease Action = "legal_hold.release"
+	ConfigLoad       Action = "config.load"
+	ConfigRead       Action = "config.read"
+	AuditRead        Action = "audit.read"
+)
+
+// Entry audit log satırıdır. Satırlar sadece eklenir; Log'da güncelleme ya da
+// silme yoktur. Hash zinciri açıksa her satır bir öncekinin hash'ini içerir ve
+// araya ekleme, silme ya da değiştirme Verify ile bulunur.
+type Entry struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+	Action    Action    `gorm:"size:64;index" json:"action"`
+	Actor     string    `gorm:"size:255;index" json:"actor"`
+	TenantID  string    `gorm:"size:255;index" json:"tenantId,omitempty"`
+	Target    string    `gorm:"size:255;index" json:"target,omitempty"`
+	Status    int       `json:"status,omitempty"` // HTTP kaynaklı kayıtlarda cevabın status'u
+	IP        string    `gorm:"size:64" json:"ip,omitempty"`
+	RequestID string    `gorm:"size:128" json:"requestId,omitempty"`
+	Detail    string    `gorm:"size:1024" json:"detail,omitempty"`
+	// PrevHash unique olduğu için iki replica aynı satırın arkasına ekleyemez;
+	// zincir kapalıyken NULL'dır.
+	PrevHash *string `gorm:"size:64;uniqueIndex" json:"prevHash,omitempty"`
+	Hash     string  `gorm:"size:64" json:"hash,omitempty"`
+}
+
+func (Entry) TableName() string {
+	return "audit_log"
+}
+
+// SystemActor background işlerin (re-encryption, retention vb.) aktörüdür.
+const SystemActor = "system"
+
+// NewEntry aktör, tenant, IP ve request id'yi context'ten doldurur.
+func NewEntry(ctx context.Context, action Action, target string) Entry {
+	id := identity.FromContext(ctx)
+	actor := id.UserID
+	if actor == "" {
+		actor = SystemActor
+	}
+	return Entry{
+		Action:    action,
+		Actor:     actor,
+		TenantID:  id.TenantID,
+		Target:    target,
+		IP:        id.ClientIP,
+		RequestID: middleware.RequestIDFromContext(ctx),
+	}
+}
+
+type Filter struct {
+	Action   Action
+	Actor    string
+	TenantID string
+	Target   string
+	From     time.Time
+	To       time.Time
+	BeforeID uint64 // sayfalama; sıfırdan büyükse bu ID'den eskiler
+	Limit    int
+}
+
+// Verification hash zincirinin kontrol sonucudur. Head dışarıda (ör. günlük
+// bir rapora) saklanırsa zincirin sonundan silinen satırlar da fark edilir.
+type Verification struct {
+	Entries  int64  `json:"entries"`
+	Valid    bool   `json:"valid"`
+	BrokenAt uint64 `json:"brokenAt,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Head     string `json:"head,omitempty"`
+}
+
+type Log interface {
+	Record(ctx context.Context, entry Entry) error
+	// Query filtreye uyan kayıtları yeniden eskiye döner.
+	Query(ctx context.Context, filter Filter) ([]Entry, error)
+	// Verify zincirli kayıtları baştan sona kontrol eder.
+	Verify(ctx context.Context) (Verification, error)
+}
+
+type gormLog struct {
+	db      *gorm.DB
+	chained bool
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// maxChainAttempts başka bir replica aynı anda ekleme yaptığında kaç kez
+// zincirin sonunun tekrar okunacağıdır.
+const maxChainAttempts = 5
+
+// NewGormLog chained true ise kayıtlar hash zinciriyle yazılır. Zincir
+// sonradan açılırsa önceki kayıtlar zincire dahil olmaz.
+func NewGormLog(db *gorm.DB, chained bool) Log {
+	return &gormLog{
+		db:      db,
+		chained: chained,
+		now:     time.Now,
+	}
+}
+
+func (l *gormLog) Record(ctx context.Context, entry Entry) error {
+	// DB milisaniye saklar; hash'in tekrar hesaplanabilmesi için yuvarlanır
+	entry.CreatedAt = l.now().UTC().Truncate(time.Millisecond)
+	db := l.db.WithContext(ctx)
+	if !l.chained {
+		return db.Create(&entry).Error
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var err error
+	for range maxChainAttempts {
+		var last Entry
+		prev := ""
+		err = db.Where("hash <> ''").Order("id desc").Take(&last).Error
+		switch {
+		case err == nil:
+			prev = last.Hash
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		entry.ID = 0
+		entry.PrevHash = &prev
+		entry.Hash = entry.hash(prev)
+		err = db.Create(&entry).Error
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+	}
+	return err
+}
+
+func (l *gormLog) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	q := l.db.WithContext(ctx)
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.Actor != "" {
+		q = q.Where("actor = ?", f.Actor)
+	}
+	if f.TenantID != "" {
+		q = q.Where("tenant_id = ?", f.TenantID)
+	}
+	if f.Target != "" {
+		q = q.Where("target = ?", f.Target)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+	if f.BeforeID > 0 {
+		q = q.Where("id < ?", f.BeforeID)
+	}
+	var entries []Entry
+	err := q.Order("id desc").Limit(f.Limit).Find(&entries).Error
+	return entries, err
+}
+
+const verifyBatch = 1000
+
+func (l *gormLog) Verify(ctx context.Context) (Verification, error) {
+	v := Verification{Valid: true}
+	var lastID uint64
+	for {
+		var batch []Entry
+		err := l.db.WithContext(ctx).Where("hash <> '' AND id > ?", lastID).
+			Order("id").Limit(verifyBatch).Find(&batch).Error
+		if err != nil {
+			return v, err
+		}
+		for _, e := range batch {
+			lastID = e.ID
+			if !v.check(e) {
+				return v, nil
+			}
+		}
+		if len(batch) < verifyBatch {
+			return v, nil
+		}
+	}
+}
+
+// check zincirin sıradaki kaydını doğrular; zincir kırıksa false döner.
+func (v *Verification) check(e Entry) bool {
+	v.Entries++
+	switch {
+	case e.PrevHash == nil || *e.PrevHash != v.Head:
+		v.broken(e.ID, "previous hash does not match, an entry was removed or inserted")
+	case e.hash(v.Head) != e.Hash:
+		v.broken(e.ID, "entry was modified")
+	default:
+		v.Head = e.Hash
+		return true
+	}
+	return false
+}
+
+func (v *Verification) broken(id uint64, reason string) {
+	v.Valid = false
+	v.BrokenAt = id
+	v.Reason = reason
+}
+
+func (e Entry) hash(prev string) string {
+	fields := []string{
+		prev,
+		strconv.FormatInt(e.CreatedAt.UnixMilli(), 10),
+		string(e.Action),
+		e.Actor,
+		e.TenantID,
+		e.Target,
+		strconv.Itoa(e.Status),
+		e.IP,
+		e.RequestID,
+		e.Detail,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordConfig açılıştaki config'in hash'ini yazar; ardışık config.load
+// kayıtlarında hash'in değişmesi deploy'lar arasında config değiştiğini gösterir.
+// cfg secret'ları temizlenmiş olmalıdır.
+func RecordConfig(ctx context.Context, log Log, cfg any) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	entry := NewEntry(ctx, ConfigLoad, "")
+	entry.Detail = "sha256:" + hex.EncodeToString(sum[:])
+	return log.Record(ctx, entry)
+}
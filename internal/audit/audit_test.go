@@ -0,0 +1,222 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"myapp/internal/chat"
+	"myapp/internal/privacy"
+	"myapp/pkg/encryption"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func chainedLog(t *testing.T, n int) *memoryLog {
+	t.Helper()
+	log := NewMemoryLog(true).(*memoryLog)
+	for i := 0; i < n; i++ {
+		require.NoError(t, log.Record(context.Background(), Entry{Action: HistoryRead, Actor: "u1", Target: "s1"}))
+	}
+	return log
+}
+
+func TestVerify_ValidChain(t *testing.T) {
+	log := chainedLog(t, 3)
+
+	v, err := log.Verify(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, v.Valid)
+	assert.Equal(t, int64(3), v.Entries)
+	assert.Equal(t, log.entries[2].Hash, v.Head)
+	assert.Equal(t, log.entries[1].Hash, *log.entries[2].PrevHash)
+}
+
+func TestVerify_DetectsModifiedEntry(t *testing.T) {
+	log := chainedLog(t, 3)
+	log.entries[1].Actor = "someone-else"
+
+	v, _ := log.Verify(context.Background())
+
+	assert.False(t, v.Valid)
+	assert.Equal(t, uint64(2), v.BrokenAt)
+	assert.Equal(t, "entry was modified", v.Reason)
+}
+
+func TestVerify_DetectsRemovedEntry(t *testing.T) {
+	log := chainedLog(t, 3)
+	log.entries = append(log.entries[:1], log.entries[2:]...)
+
+	v, _ := log.Verify(context.Background())
+
+	assert.False(t, v.Valid)
+	assert.Equal(t, uint64(3), v.BrokenAt)
+}
+
+func TestVerify_RehashedEntryBreaksNextLink(t *testing.T) {
+	// hash'i de yeniden hesaplayan biri sonraki kaydın PrevHash'ini bozar
+	log := chainedLog(t, 3)
+	e := &log.entries[1]
+	e.Target = "s2"
+	e.Hash = e.hash(*e.PrevHash)
+
+	v, _ := log.Verify(context.Background())
+
+	assert.False(t, v.Valid)
+	assert.Equal(t, uint64(3), v.BrokenAt)
+}
+
+func TestQuery_Filters(t *testing.T) {
+	log := NewMemoryLog(false)
+	ctx := context.Background()
+	log.Record(ctx, Entry{Action: HistoryRead, Actor: "u1", TenantID: "acme", Target: "s1"})
+	log.Record(ctx, Entry{Action: HistoryRead, Actor: "u2", TenantID: "acme", Target: "s2"})
+	log.Record(ctx, Entry{Action: UserExport, Actor: "u1", TenantID: "acme", Target: "u1"})
+
+	entries, _ := log.Query(ctx, Filter{Actor: "u1", Limit: 10})
+	require.Len(t, entries, 2)
+	assert.Equal(t, UserExport, entries[0].Action, "newest first")
+
+	entries, _ = log.Query(ctx, Filter{Action: HistoryRead, BeforeID: 2, Limit: 10})
+	require.Len(t, entries, 1)
+	assert.Equal(t, "s1", entries[0].Target)
+}
+
+func serve(t *testing.T, log Log, handler echo.HandlerFunc, headers map[string]string) *httptest.ResponseRecorder {
+	logger.Log = zap.NewNop()
+	e := echo.New()
+	e.HTTPErrorHandler = chat.HTTPErrorHandler
+	e.Use(identity.Middleware())
+	e.GET("/v1/chat/:sessionId", handler, Middleware(log, HistoryRead, Param("sessionId")))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chat/s1", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_RecordsRead(t *testing.T) {
+	//arrange
+	log := NewMemoryLog(true)
+	ok := func(c echo.Context) error { return c.JSON(http.StatusOK, echo.Map{}) }
+
+	//act
+	serve(t, log, ok, map[string]string{
+		identity.HeaderUserID:    "u1",
+		identity.HeaderTenantID:  "acme",
+		echo.HeaderXForwardedFor: "203.0.113.7",
+		echo.HeaderXRequestID:    "req-1",
+	})
+
+	//assert
+	entries, _ := log.Query(context.Background(), Filter{Limit: 10})
+	require.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, HistoryRead, e.Action)
+	assert.Equal(t, "u1", e.Actor)
+	assert.Equal(t, "acme", e.TenantID)
+	assert.Equal(t, "s1", e.Target)
+	assert.Equal(t, "203.0.113.7", e.IP)
+	assert.Equal(t, http.StatusOK, e.Status)
+	assert.NotEmpty(t, e.Hash)
+}
+
+func TestMiddleware_RecordsFailedAttempt(t *testing.T) {
+	log := NewMemoryLog(false)
+	notFound := func(c echo.Context) error { return chat.ErrSessionNotFound }
+
+	rec := serve(t, log, notFound, map[string]string{echo.HeaderAuthorization: "Bearer x"})
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	entries, _ := log.Query(context.Background(), Filter{Limit: 10})
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.StatusNotFound, entries[0].Status)
+	assert.Equal(t, "anonymous", entries[0].Actor, "an unverified Authorization header is not an admin")
+}
+
+func TestMiddleware_AdminActorAfterAdminAuth(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	log := NewMemoryLog(false)
+	e := echo.New()
+	e.Use(identity.Middleware())
+	debug := e.Group("debug", middleware.AdminAuth("t0ken"))
+	debug.GET("/info", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, Middleware(log, ConfigRead, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/info", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer t0ken")
+
+	//act
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	//assert
+	entries, _ := log.Query(context.Background(), Filter{Limit: 10})
+	require.Len(t, entries, 1)
+	assert.Equal(t, AdminActor, entries[0].Actor)
+}
+
+func TestMiddleware_SelfRecordsSubjectNotUserID(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	log := NewMemoryLog(true)
+	e := echo.New()
+	e.Use(identity.Middleware())
+	e.GET("/v1/me/export", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, Middleware(log, UserExport, Self))
+	req := httptest.NewRequest(http.MethodGet, "/v1/me/export", nil)
+	req.Header.Set(identity.HeaderUserID, "u1")
+
+	//act
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	//assert
+	entries, _ := log.Query(context.Background(), Filter{Limit: 10})
+	require.Len(t, entries, 1)
+	assert.Equal(t, privacy.Subject("u1"), entries[0].Target)
+	assert.Equal(t, privacy.Subject("u1"), entries[0].Actor)
+}
+
+type failingLog struct{ Log }
+
+func (failingLog) Record(context.Context, Entry) error { return errors.New("db down") }
+
+func TestMiddleware_AuditFailureDoesNotFailRequest(t *testing.T) {
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+
+	rec := serve(t, failingLog{}, ok, nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestKeyStore_RecordsIssuance(t *testing.T) {
+	//arrange
+	log := NewMemoryLog(false)
+	store := InstrumentKeyStore(encryption.NewMemoryStore(), log)
+
+	//act
+	err := store.Create(context.Background(), &encryption.DataKey{TenantID: "acme", Version: 2, MasterKeyID: "m1"})
+
+	//assert
+	require.NoError(t, err)
+	entries, _ := log.Query(context.Background(), Filter{Limit: 10})
+	require.Len(t, entries, 1)
+	assert.Equal(t, KeyIssue, entries[0].Action)
+	assert.Equal(t, SystemActor, entries[0].Actor)
+	assert.Equal(t, "acme", entries[0].Target)
+	assert.Equal(t, "version=2 masterKey=m1", entries[0].Detail)
+
+	// aynı versiyon tekrar oluşturulamaz ve kaydedilmez
+	assert.ErrorIs(t, store.Create(context.Background(), &encryption.DataKey{TenantID: "acme", Version: 2}), encryption.ErrKeyExists)
+	entries, _ = log.Query(context.Background(), Filter{Limit: 10})
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"myapp/internal/chat"
+	"myapp/pkg/logger"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+type Handler interface {
+	Query(c echo.Context) error
+	Verify(c echo.Context) error
+}
+
+type handler struct {
+	log Log
+}
+
+// NewHandler audit log'u sorgulamak için admin endpoint'idir.
+func NewHandler(log Log) Handler {
+	return &handler{
+		log: log,
+	}
+}
+
+// Query action, actor, tenant, target, from, to (RFC3339), limit ve before
+// ile filtreler. Sonraki sayfa için dönen nextBefore, before'a verilir.
+func (h *handler) Query(c echo.Context) error {
+	f := Filter{
+		Action:   Action(c.QueryParam("action")),
+		Actor:    c.QueryParam("actor"),
+		TenantID: c.QueryParam("tenant"),
+		Target:   c.QueryParam("target"),
+		Limit:    defaultQueryLimit,
+	}
+	var err error
+	if f.From, err = queryTime(c, "from"); err != nil {
+		return chat.ErrInvalidRequest
+	}
+	if f.To, err = queryTime(c, "to"); err != nil {
+		return chat.ErrInvalidRequest
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 || f.Limit > maxQueryLimit {
+			return chat.ErrInvalidRequest
+		}
+	}
+	if v := c.QueryParam("before"); v != "" {
+		if f.BeforeID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return chat.ErrInvalidRequest
+		}
+	}
+
+	entries, err := h.log.Query(c.Request().Context(), f)
+	if err != nil {
+		logger.FromContext(c.Request().Context()).Error("audit query failed", zap.Error(err))
+		return chat.ErrStorage
+	}
+	res := echo.Map{"entries": entries}
+	if len(entries) == f.Limit {
+		res["nextBefore"] = entries[len(entries)-1].ID
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Verify(c echo.Context) error {
+	v, err := h.log.Verify(c.Request().Context())
+	if err != nil {
+		logger.FromContext(c.Request().Context()).Error("audit verification failed", zap.Error(err))
+		return chat.ErrStorage
+	}
+	if !v.Valid {
+		logger.FromContext(c.Request().Context()).Error("audit log chain is broken",
+			zap.Uint64("entryID", v.BrokenAt), zap.String("reason", v.Reason))
+	}
+	return c.JSON(http.StatusOK, v)
+}
+
+func queryTime(c echo.Context, name string) (time.Time, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"myapp/pkg/encryption"
+	"myapp/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+type keyStore struct {
+	encryption.Store
+	log Log
+}
+
+// InstrumentKeyStore yeni data key oluşturulmasını ve yeniden sarılmasını
+// kaydeder. Key'ler ilk mesajda ya da rotasyonda, istek içinde veya
+// Reencryptor'da oluşabildiği için Keyring'in altındaki store'da yakalanır.
+func InstrumentKeyStore(next encryption.Store, log Log) encryption.Store {
+	return &keyStore{Store: next, log: log}
+}
+
+func (s *keyStore) Create(ctx context.Context, key *encryption.DataKey) error {
+	if err := s.Store.Create(ctx, key); err != nil {
+		return err
+	}
+	s.record(ctx, KeyIssue, key.TenantID, fmt.Sprintf("version=%d masterKey=%s", key.Version, key.MasterKeyID))
+	return nil
+}
+
+func (s *keyStore) Rewrap(ctx context.Context, id uint, oldMasterKeyID string, wrapped []byte, masterKeyID string) error {
+	if err := s.Store.Rewrap(ctx, id, oldMasterKeyID, wrapped, masterKeyID); err != nil {
+		return err
+	}
+	s.record(ctx, KeyRewrap, "", fmt.Sprintf("id=%d masterKey=%s->%s", id, oldMasterKeyID, masterKeyID))
+	return nil
+}
+
+func (s *keyStore) record(ctx context.Context, action Action, tenantID, detail string) {
+	entry := NewEntry(ctx, action, tenantID)
+	entry.Detail = detail
+	if err := s.log.Record(context.WithoutCancel(ctx), entry); err != nil {
+		logger.FromContext(ctx).Error("failed to write audit entry", zap.String("action", string(action)), zap.Error(err))
+	}
+}
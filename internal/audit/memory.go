@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type memoryLog struct {
+	mu      sync.Mutex
+	entries []Entry
+	chained bool
+	now     func() time.Time
+}
+
+// NewMemoryLog testler ve tek process'li denemeler içindir; restart'ta kayıtlar kaybolur.
+func NewMemoryLog(chained bool) Log {
+	return &memoryLog{chained: chained, now: time.Now}
+}
+
+func (l *memoryLog) Record(_ context.Context, entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.ID = uint64(len(l.entries) + 1)
+	entry.CreatedAt = l.now().UTC().Truncate(time.Millisecond)
+	if l.chained {
+		prev := ""
+		for i := len(l.entries) - 1; i >= 0; i-- {
+			if l.entries[i].Hash != "" {
+				prev = l.entries[i].Hash
+				break
+			}
+		}
+		entry.PrevHash = &prev
+		entry.Hash = entry.hash(prev)
+	}
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *memoryLog) Query(_ context.Context, f Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []Entry
+	for _, e := range slices.Backward(l.entries) {
+		if f.matches(e) {
+			out = append(out, e)
+		}
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (l *memoryLog) Verify(_ context.Context) (Verification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v := Verification{Valid: true}
+	for _, e := range l.entries {
+		if e.Hash != "" && !v.check(e) {
+			break
+		}
+	}
+	return v, nil
+}
+
+func (f Filter) matches(e Entry) bool {
+	switch {
+	case f.Action != "" && e.Action != f.Action,
+		f.Actor != "" && e.Actor != f.Actor,
+		f.TenantID != "" && e.TenantID != f.TenantID,
+		f.Target != "" && e.Target != f.Target,
+		!f.From.IsZero() && e.CreatedAt.Before(f.From),
+		!f.To.IsZero() && !e.CreatedAt.Before(f.To),
+		f.BeforeID > 0 && e.ID >= f.BeforeID:
+		return false
+	}
+	return true
+}
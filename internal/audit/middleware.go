@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"myapp/internal/chat"
+	"myapp/internal/privacy"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+// Target isteğin hangi kayda dokunduğunu söyler.
+type Target func(c echo.Context) string
+
+// Param target'ı path parametresinden alır.
+func Param(name string) Target {
+	return func(c echo.Context) string {
+		return c.Param(name)
+	}
+}
+
+// Self target'ı isteği yapan kullanıcıdır (ör. /v1/me). Audit kayıtları
+// erasure'dan sonra da kaldığı için ham id yerine privacy.Subject tutulur;
+// Middleware bu kayıtlarda actor'ü de aynı hash'le yazar.
+func Self(c echo.Context) string {
+	return privacy.Subject(identity.FromContext(c.Request().Context()).UserID)
+}
+
+// AdminActor AdminAuth'un doğruladığı admin token'ıyla gelen, gateway kimliği olmayan isteklerin aktörüdür.
+const AdminActor = "admin"
+
+// Middleware route'a eklenir ve handler bittikten sonra, başarısız istekler
+// dahil, status'la birlikte kaydı yazar. Kayıt yazılamazsa cevap etkilenmez,
+// hata loglanır.
+func Middleware(log Log, action Action, target Target) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			ctx := c.Request().Context()
+			entry := NewEntry(ctx, action, "")
+			if target != nil {
+				entry.Target = target(c)
+			}
+			if userID := identity.FromContext(ctx).UserID; userID != "" && entry.Target == privacy.Subject(userID) {
+				entry.Actor = entry.Target
+			}
+			if entry.IP == "" {
+				entry.IP = c.RealIP()
+			}
+			if identity.FromContext(ctx).UserID == "" {
+				entry.Actor = "anonymous"
+				if middleware.IsAdmin(ctx) {
+					entry.Actor = AdminActor
+				}
+			}
+			entry.Status = c.Response().Status
+			if err != nil {
+				// hata henüz yazılmadı; HTTPErrorHandler'ın döneceği status kaydedilir
+				entry.Status = chat.ErrorStatus(err)
+			}
+			if recErr := log.Record(ctx, entry); recErr != nil {
+				logger.FromContext(ctx).Error("failed to write audit entry",
+					zap.String("action", string(action)), zap.Error(recErr))
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+type BreakerConfig struct {
+	FailureThreshold int           // closed -> open için ardışık hata sayısı
+	OpenTimeout      time.Duration // open kaldıktan sonra half-open'a geçme süresi
+	HalfOpenMaxCalls int           // half-open'da izin verilen deneme; hepsi başarılıysa closed
+}
+
+// CircuitBreaker tek bir provider'ın sağlığını izler. Provider art arda
+// hata verince devre açılır ve OpenTimeout boyunca çağrı yapılmaz; sonra
+// birkaç deneme çağrısına izin verilir.
+type CircuitBreaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu               sync.Mutex
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight int
+	halfOpenSuccess  int
+	lastError        string
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.HalfOpenMaxCalls < 1 {
+		cfg.HalfOpenMaxCalls = 1
+	}
+	return &CircuitBreaker{
+		cfg:   cfg,
+		now:   time.Now,
+		state: BreakerClosed,
+	}
+}
+
+// Allow çağrı yapılıp yapılamayacağını söyler; izin verilirse sonuç Record ile bildirilmelidir.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = BreakerHalfOpen
+		b.halfOpenInFlight = 0
+		b.halfOpenSuccess = 0
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.halfOpenInFlight >= b.cfg.HalfOpenMaxCalls {
+			return ErrCircuitOpen
+		}
+		b.halfOpenInFlight++
+	}
+	return nil
+}
+
+// Record Allow'dan sonra yapılan çağrının sonucunu işler. failure false ise
+// (ör. client'ın iptal ettiği istek) çağrı hata sayılmaz. Half-open'da ise
+// sadece err'siz çağrı başarı sayılır; diğerleri deneme hakkını geri verir.
+func (b *CircuitBreaker) Record(failure bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if failure && err != nil {
+		b.lastError = err.Error()
+	}
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if failure {
+			b.trip()
+			return
+		}
+		if err != nil {
+			b.halfOpenInFlight--
+			return
+		}
+		b.halfOpenSuccess++
+		if b.halfOpenSuccess >= b.cfg.HalfOpenMaxCalls {
+			b.state = BreakerClosed
+			b.failures = 0
+		}
+	case BreakerClosed:
+		if !failure {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.trip()
+		}
+	}
+}
+
+func (b *CircuitBreaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.failures = 0
+}
+
+// BreakerStatus diagnostics endpoint'inde gösterilen anlık durumdur.
+type BreakerStatus struct {
+	State     BreakerState `json:"state"`
+	Failures  int          `json:"consecutiveFailures"`
+	OpenedAt  *time.Time   `json:"openedAt,omitempty"`
+	LastError string       `json:"lastError,omitempty"`
+}
+
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		status.State = BreakerHalfOpen
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// isProviderFailure hatanın provider'ın sağlıksız olduğunu gösterip
+// göstermediğine karar verir. Client'ın isteği iptal etmesi ya da bizim
+// bozuk isteğimiz (400, 401...) provider'ın suçu değildir.
+func isProviderFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBreaker(cfg BreakerConfig) (*CircuitBreaker, *time.Time) {
+	now := time.Unix(1756212819, 0)
+	b := NewCircuitBreaker(cfg)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	//arrange
+	b, _ := newTestBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute})
+	boom := errors.New("boom")
+
+	//act
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, b.Allow())
+		b.Record(true, boom)
+	}
+
+	//assert
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+	status := b.Status()
+	assert.Equal(t, BreakerOpen, status.State)
+	assert.Equal(t, "boom", status.LastError)
+	assert.NotNil(t, status.OpenedAt)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	b.Allow()
+	b.Record(true, errors.New("boom"))
+	b.Allow()
+	b.Record(false, nil)
+	b.Allow()
+	b.Record(true, errors.New("boom"))
+
+	assert.Equal(t, BreakerClosed, b.Status().State)
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_HalfOpenRecovery(t *testing.T) {
+	//arrange
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxCalls: 1})
+	b.Allow()
+	b.Record(true, errors.New("boom"))
+
+	//act & assert
+	*now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, b.Status().State)
+	assert.NoError(t, b.Allow())
+	// deneme sürerken başka çağrıya izin yok
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+
+	b.Record(false, nil)
+	assert.Equal(t, BreakerClosed, b.Status().State)
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_HalfOpenFailureReopens(t *testing.T) {
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	b.Allow()
+	b.Record(true, errors.New("boom"))
+
+	*now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Record(true, errors.New("still down"))
+
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+	assert.Equal(t, BreakerOpen, b.Status().State)
+}
+
+func TestBreaker_HalfOpenIgnoresNonFailureErrors(t *testing.T) {
+	//arrange
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxCalls: 1})
+	b.Allow()
+	b.Record(true, errors.New("boom"))
+	*now = now.Add(time.Minute)
+
+	//act
+	assert.NoError(t, b.Allow())
+	b.Record(false, context.Canceled)
+
+	//assert
+	assert.Equal(t, BreakerHalfOpen, b.Status().State, "a canceled probe must not close the breaker")
+	assert.NoError(t, b.Allow(), "the probe slot is released")
+	b.Record(false, nil)
+	assert.Equal(t, BreakerClosed, b.Status().State)
+}
+
+func TestIsProviderFailure(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(t, isProviderFailure(context.Background(), nil))
+	assert.False(t, isProviderFailure(canceled, context.Canceled))
+	assert.False(t, isProviderFailure(context.Background(), &openai.Error{StatusCode: http.StatusBadRequest}))
+	assert.True(t, isProviderFailure(context.Background(), &openai.Error{StatusCode: http.StatusTooManyRequests}))
+	assert.True(t, isProviderFailure(context.Background(), &openai.Error{StatusCode: http.StatusBadGateway}))
+	assert.True(t, isProviderFailure(context.Background(), errors.New("connection refused")))
+}
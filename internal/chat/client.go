@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"myapp/pkg/logger"
+	"time"
 
 	"github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
@@ -11,29 +12,90 @@ import (
 )
 
 type Client interface {
-	GetCompletion(message string, messages []ChatMessage) (response string, err error)
+	// GetCompletion messages geçmişini (yeni mesaj hariç) ve yeni mesajı LLM'e gönderir.
+	// ctx iptal edilirse upstream istek de iptal edilir.
+	GetCompletion(ctx context.Context, message string, messages []ChatMessage) (Completion, error)
+}
+
+// Pinger provider'a ucuz bir istek atarak erişilebilir olup olmadığını söyler;
+// readiness kontrolleri kullanır.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Completion LLM cevabı ve cevabın nasıl alındığına dair metadata.
+type Completion struct {
+	Message  string
+	Attempts int    // retry dahil toplam deneme sayısı
+	Provider string // cevabı veren provider
+	Model    string
+	Route    string // model'i seçen routing kuralı
+
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// ProviderConfig OpenAI ya da OpenAI uyumlu bir endpoint'in (ör. vLLM, Ollama) ayarlarıdır.
+type ProviderConfig struct {
+	Name    string
+	BaseURL string // boşsa OpenAI
+	APIKey  string
+	Model   string
+	Timeout time.Duration
 }
 
 type client struct {
-	openai openai.Client
+	openai  openai.Client
+	name    string
+	model   string
+	timeout time.Duration
+}
+
+// NewClient GPT-4o kullanan varsayılan OpenAI client'ını döner.
+func NewClient(apiKey string, timeout time.Duration, opts ...option.RequestOption) Client {
+	return NewProviderClient(ProviderConfig{
+		Name:    "openai",
+		APIKey:  apiKey,
+		Model:   openai.ChatModelGPT4o,
+		Timeout: timeout,
+	}, opts...)
 }
 
-func NewClient(apiKey string) Client {
+// NewProviderClient timeout sıfırdan büyükse her completion çağrısına deadline koyar.
+// SDK'nın kendi retry'ı kapalıdır, retry NewRetryingClient ile yapılır.
+// opts testlerde base URL gibi ayarları değiştirmek için kullanılır.
+func NewProviderClient(cfg ProviderConfig, opts ...option.RequestOption) Client {
+	base := []option.RequestOption{option.WithAPIKey(cfg.APIKey), option.WithMaxRetries(0)}
+	if cfg.BaseURL != "" {
+		base = append(base, option.WithBaseURL(cfg.BaseURL))
+	}
 	return &client{
-		openai: (openai.NewClient(option.WithAPIKey(apiKey))),
+		openai:  (openai.NewClient(append(base, opts...)...)),
+		name:    cfg.Name,
+		model:   cfg.Model,
+		timeout: cfg.Timeout,
 	}
 }
 
-func (c *client) GetCompletion(message string, messages []ChatMessage) (response string, err error) {
-	logger.Log.Info("Client received user message",
+// Ping model listesini ister; token harcamaz.
+func (c *client) Ping(ctx context.Context) error {
+	_, err := c.openai.Models.List(ctx)
+	return err
+}
+
+func (c *client) GetCompletion(ctx context.Context, message string, messages []ChatMessage) (Completion, error) {
+	log := logger.FromContext(ctx)
+	log.Info("Client received user message",
 		zap.String("message", message))
+	model := c.model
+	if override := ModelFromContext(ctx); override != "" {
+		model = override
+	}
 	param := openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(message),
-		},
 		Seed:  openai.Int(1),
-		Model: openai.ChatModelGPT4o,
+		Model: model,
 	}
+	// önce geçmiş, en sonda yeni kullanıcı mesajı
 	for _, msg := range messages {
 		switch msg.Kind {
 		case UserPrompt:
@@ -42,27 +104,42 @@ func (c *client) GetCompletion(message string, messages []ChatMessage) (response
 			param.Messages = append(param.Messages, openai.AssistantMessage(msg.Message))
 		}
 	}
+	param.Messages = append(param.Messages, openai.UserMessage(message))
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
 
-	completion, err := c.openai.Chat.Completions.New(context.TODO(), param)
+	completion, err := c.openai.Chat.Completions.New(ctx, param)
 	if err != nil {
-		logger.Log.Error("Failed to get OpenAI completion",
+		log.Error("Failed to get OpenAI completion",
 			zap.String("message", message),
 			zap.Any("chat_history", messages),
 			zap.Error(err))
-		return "", err
+		return Completion{}, err
 	}
 
 	// completion doluysa response'u al
 	if len(completion.Choices) == 0 {
-		logger.Log.Warn("OpenAI returned empty choices",
+		log.Warn("OpenAI returned empty choices",
 			zap.String("message", message),
 			zap.Any("chat_history", messages))
-		return "", fmt.Errorf("no choices returned by OpenAI")
+		return Completion{}, fmt.Errorf("no choices returned by OpenAI")
 	}
 
-	response = completion.Choices[0].Message.Content
-	logger.Log.Info("OpenAI responed successfully",
+	response := completion.Choices[0].Message.Content
+	log.Info("OpenAI responed successfully",
 		zap.String("response", response),
 		zap.String("message", message))
-	return
+	return Completion{
+		Message:  response,
+		Attempts: 1,
+		Provider: c.name,
+		Model:    completion.Model,
+
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+	}, nil
 }
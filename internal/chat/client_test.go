@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2/option"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const completionJSON = `{
+	"id": "chatcmpl-1",
+	"object": "chat.completion",
+	"created": 1756212819,
+	"model": "gpt-4o",
+	"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "merhaba, size nasıl yardımcı olabilirim?"}}],
+	"usage": {"prompt_tokens": 10, "completion_tokens": 8, "total_tokens": 18}
+}`
+
+// newTestClient sahte bir OpenAI sunucusuna bağlı client döner.
+func newTestClient(t *testing.T, timeout time.Duration, h http.HandlerFunc) Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewClient("test-key", timeout, option.WithBaseURL(srv.URL))
+}
+
+func TestGetCompletion_SendsHistoryThenMessage(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	var body struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	c := newTestClient(t, 0, func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, completionJSON)
+	})
+	history := []ChatMessage{
+		{Kind: UserPrompt, Message: "selam"},
+		{Kind: LLMOutput, Message: "selam, nasılsın?"},
+	}
+
+	//act
+	completion, err := c.GetCompletion(context.Background(), "merhaba", history)
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, "merhaba, size nasıl yardımcı olabilirim?", completion.Message)
+	assert.Equal(t, 1, completion.Attempts)
+	assert.Equal(t, int64(10), completion.PromptTokens)
+	assert.Equal(t, int64(8), completion.CompletionTokens)
+	if assert.Len(t, body.Messages, 3) {
+		assert.Equal(t, "user", body.Messages[0].Role)
+		assert.Equal(t, "assistant", body.Messages[1].Role)
+		assert.Equal(t, "merhaba", body.Messages[2].Content)
+	}
+}
+
+func TestGetCompletion_CanceledContextAbortsUpstream(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	upstreamCanceled := make(chan struct{})
+	c := newTestClient(t, 0, func(w http.ResponseWriter, r *http.Request) {
+		// body okunmadan server bağlantının kapandığını fark etmez
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+		close(upstreamCanceled)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	//act
+	start := time.Now()
+	_, err := c.GetCompletion(ctx, "merhaba", nil)
+
+	//assert
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	select {
+	case <-upstreamCanceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request was not canceled")
+	}
+}
+
+func TestGetCompletion_Timeout(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	c := newTestClient(t, 20*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	})
+
+	//act
+	_, err := c.GetCompletion(context.Background(), "merhaba", nil)
+
+	//assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+type DiagnosticsHandler interface {
+	Providers(c echo.Context) error
+}
+
+type diagnosticsHandler struct {
+	failover *FailoverClient
+}
+
+func NewDiagnosticsHandler(failover *FailoverClient) DiagnosticsHandler {
+	return &diagnosticsHandler{
+		failover: failover,
+	}
+}
+
+// Providers failover zincirindeki provider'ları sırasıyla ve breaker durumlarıyla döner.
+func (h *diagnosticsHandler) Providers(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"providers": h.failover.Status(),
+	})
+}
@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"myapp/pkg/encryption"
+)
+
+// messageAAD ciphertext'i tenant'a ve session'a bağlar; başka bir session'ın
+// satırına kopyalanan ciphertext açılmaz.
+func messageAAD(msg *ChatMessage) []byte {
+	return []byte(msg.TenantID + "/" + msg.SessionID)
+}
+
+// sealMessage mesajın şifrelenmiş bir kopyasını döner; msg değişmez.
+func sealMessage(ctx context.Context, keys encryption.Keyring, msg *ChatMessage) (*ChatMessage, error) {
+	version, key, err := keys.Current(ctx, msg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return sealWith(msg, version, key)
+}
+
+func sealWith(msg *ChatMessage, version int, key []byte) (*ChatMessage, error) {
+	sealed, err := encryption.Seal(key, []byte(msg.Message), messageAAD(msg))
+	if err != nil {
+		return nil, err
+	}
+	row := *msg
+	row.Message = sealed
+	row.KeyVersion = version
+	return &row, nil
+}
+
+// openMessage KeyVersion sıfırsa (şifrelenmemiş eski satır) mesajı olduğu gibi bırakır.
+func openMessage(ctx context.Context, keys encryption.Keyring, msg *ChatMessage) error {
+	if msg.KeyVersion == 0 {
+		return nil
+	}
+	key, err := keys.Key(ctx, msg.TenantID, msg.KeyVersion)
+	if err != nil {
+		return err
+	}
+	plaintext, err := encryption.Open(key, msg.Message, messageAAD(msg))
+	if err != nil {
+		return fmt.Errorf("message %d: %w", msg.ID, err)
+	}
+	msg.Message = string(plaintext)
+	msg.KeyVersion = 0
+	return nil
+}
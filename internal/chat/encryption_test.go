@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"myapp/pkg/encryption"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyring(t *testing.T) encryption.Keyring {
+	t.Helper()
+	kms, err := encryption.NewLocalKMS("m1", map[string][]byte{"m1": bytes.Repeat([]byte{1}, encryption.KeySize)})
+	require.NoError(t, err)
+	return encryption.NewKeyring(encryption.NewMemoryStore(), kms, time.Minute)
+}
+
+func TestSealMessage_RoundTrip(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	keys := testKeyring(t)
+	msg := &ChatMessage{ID: 7, Message: "merhaba", SessionID: "s1", TenantID: "acme"}
+
+	//act
+	row, err := sealMessage(ctx, keys, msg)
+	require.NoError(t, err)
+	err = openMessage(ctx, keys, row)
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, "merhaba", msg.Message, "caller's message must not be modified")
+	assert.Equal(t, "merhaba", row.Message)
+	assert.Equal(t, 0, row.KeyVersion)
+}
+
+func TestSealMessage_UsesCurrentKeyVersion(t *testing.T) {
+	ctx := context.Background()
+	keys := testKeyring(t)
+	msg := &ChatMessage{Message: "merhaba", SessionID: "s1", TenantID: "acme"}
+
+	row, err := sealMessage(ctx, keys, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 1, row.KeyVersion)
+	assert.NotEqual(t, "merhaba", row.Message)
+
+	_, err = keys.Rotate(ctx, "acme")
+	require.NoError(t, err)
+	rotated, err := sealMessage(ctx, keys, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rotated.KeyVersion)
+
+	// eski versiyonla yazılan satır hâlâ okunur
+	assert.NoError(t, openMessage(ctx, keys, row))
+	assert.Equal(t, "merhaba", row.Message)
+}
+
+func TestOpenMessage_RejectsMovedCiphertext(t *testing.T) {
+	ctx := context.Background()
+	keys := testKeyring(t)
+	row, err := sealMessage(ctx, keys, &ChatMessage{Message: "gizli", SessionID: "s1", TenantID: "acme"})
+	require.NoError(t, err)
+
+	row.SessionID = "s2"
+	err = openMessage(ctx, keys, row)
+
+	assert.ErrorIs(t, err, encryption.ErrDecrypt)
+}
+
+func TestOpenMessage_LeavesPlaintextRows(t *testing.T) {
+	msg := &ChatMessage{Message: "eski satır", SessionID: "s1"}
+
+	err := openMessage(context.Background(), testKeyring(t), msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "eski satır", msg.Message)
+}
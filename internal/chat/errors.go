@@ -0,0 +1,98 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Error chat domain'inin typed hatasıdır. Code client'a dönen, değişmeyen
+// makine-okunur koddur; Message ise varsayılan insan-okunur mesajdır.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrInvalidRequest    = &Error{Code: "invalid_request", Message: "bad request"}
+	ErrInvalidMessage    = &Error{Code: "invalid_message", Message: "invalid message"}
+	ErrInvalidSessionID  = &Error{Code: "invalid_session_id", Message: "invalid sessionId"}
+	ErrSessionNotFound   = &Error{Code: "session_not_found", Message: "sessionId not found"}
+	ErrSessionBusy       = &Error{Code: "session_busy", Message: "session is busy, try again"}
+	ErrUpstreamLLM       = &Error{Code: "upstream_llm_error", Message: "unable to perform chat completion"}
+	ErrStorage           = &Error{Code: "storage_error", Message: "storage error"}
+	ErrTimeout           = &Error{Code: "timeout", Message: "request timed out"}
+	ErrCanceled          = &Error{Code: "request_canceled", Message: "request canceled"}
+	ErrShuttingDown      = &Error{Code: "shutting_down", Message: "service is shutting down, try again"}
+	ErrContentFlagged    = &Error{Code: "content_flagged", Message: "message violates the content policy"}
+	ErrModeration        = &Error{Code: "moderation_unavailable", Message: "unable to moderate message"}
+	ErrPromptRejected    = &Error{Code: "prompt_rejected", Message: "prompt looks like a prompt injection attempt"}
+	ErrSearchUnavailable = &Error{Code: "search_unavailable", Message: "message search is not available"}
+	ErrTurnSuperseded    = &Error{Code: "turn_superseded", Message: "session has newer turns, the interrupted turn cannot be resumed"}
+)
+
+// wrap alttaki hatayı kaybetmeden domain hatasıyla sarar; errors.Is ikisi için de çalışır.
+func wrap(kind *Error, err error) error {
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// classify context kaynaklı hataları ayırır; deadline dolması ya da client'ın
+// bağlantıyı kapatması storage/LLM hatası değildir.
+func classify(kind *Error, err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return wrap(ErrTimeout, err)
+	case errors.Is(err, context.Canceled):
+		return wrap(ErrCanceled, err)
+	}
+	return wrap(kind, err)
+}
+
+// publicError handler'ın 5xx durumunda client'a göstermek istediği mesajı taşır.
+// Aynı ErrStorage hatası chat endpoint'inde "unable to perform chat completion",
+// history endpoint'inde "unable to respond session history" olarak döner.
+type publicError struct {
+	err     error
+	message string
+}
+
+func (e *publicError) Error() string {
+	return e.err.Error()
+}
+
+func (e *publicError) Unwrap() error {
+	return e.err
+}
+
+func withPublicMessage(err error, message string) error {
+	return &publicError{err: err, message: message}
+}
+
+func publicMessage(err error) (string, bool) {
+	var pe *publicError
+	if errors.As(err, &pe) {
+		return pe.message, true
+	}
+	return "", false
+}
+
+// InterruptedTurnError prompt'u kaydedilip cevabı shutdown yüzünden
+// alınamayan turn'ü tarif eder; ErrShuttingDown ile birlikte döner ve
+// Service.Resume ile tamamlanabilir.
+type InterruptedTurnError struct {
+	SessionID string
+	PromptSeq int64
+	Err       error
+}
+
+func (e *InterruptedTurnError) Error() string {
+	return fmt.Sprintf("turn %d of session %s interrupted: %v", e.PromptSeq, e.SessionID, e.Err)
+}
+
+func (e *InterruptedTurnError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,93 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"myapp/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Provider failover zincirindeki tek bir LLM backend'idir.
+type Provider struct {
+	Name    string
+	Model   string
+	Client  Client
+	Breaker *CircuitBreaker
+}
+
+// ProviderStatus diagnostics için provider ve breaker durumudur.
+type ProviderStatus struct {
+	Name    string        `json:"name"`
+	Model   string        `json:"model"`
+	Breaker BreakerStatus `json:"breaker"`
+}
+
+// FailoverClient provider'ları sırayla dener; devresi açık olanı atlar,
+// hata veren provider'dan sonrakine geçer.
+type FailoverClient struct {
+	providers []Provider
+}
+
+func NewFailoverClient(providers ...Provider) *FailoverClient {
+	return &FailoverClient{providers: providers}
+}
+
+func (f *FailoverClient) GetCompletion(ctx context.Context, message string, messages []ChatMessage) (Completion, error) {
+	log := logger.FromContext(ctx)
+	if len(f.providers) == 0 {
+		return Completion{}, fmt.Errorf("no LLM providers configured")
+	}
+	var lastErr error
+
+	for i, p := range f.providers {
+		if err := p.Breaker.Allow(); err != nil {
+			log.Warn("skipping provider, circuit open", zap.String("provider", p.Name))
+			if lastErr == nil {
+				lastErr = fmt.Errorf("%s: %w", p.Name, err)
+			}
+			continue
+		}
+
+		callCtx := ctx
+		if i > 0 {
+			// router'ın seçtiği model birincil provider içindir, yedekler kendi modelini kullanır
+			callCtx = WithModel(ctx, "")
+		}
+		completion, err := p.Client.GetCompletion(callCtx, message, messages)
+		failure := isProviderFailure(ctx, err)
+		p.Breaker.Record(failure, err)
+		if err == nil {
+			if completion.Provider == "" {
+				completion.Provider = p.Name
+			}
+			if i > 0 {
+				log.Warn("completion served by fallback provider", zap.String("provider", p.Name))
+			}
+			return completion, nil
+		}
+		if !failure {
+			// iptal edilen ya da bozuk istek başka provider'da da düzelmez
+			return Completion{}, err
+		}
+
+		log.Warn("provider failed, trying next",
+			zap.String("provider", p.Name),
+			zap.Error(err))
+		lastErr = fmt.Errorf("%s: %w", p.Name, err)
+	}
+
+	return Completion{}, fmt.Errorf("all providers failed: %w", lastErr)
+}
+
+func (f *FailoverClient) Status() []ProviderStatus {
+	statuses := make([]ProviderStatus, 0, len(f.providers))
+	for _, p := range f.providers {
+		statuses = append(statuses, ProviderStatus{
+			Name:    p.Name,
+			Model:   p.Model,
+			Breaker: p.Breaker.Status(),
+		})
+	}
+	return statuses
+}
@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/openai/openai-go/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func newTestProvider(name string, client Client) Provider {
+	return Provider{
+		Name:    name,
+		Model:   name + "-model",
+		Client:  client,
+		Breaker: NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}),
+	}
+}
+
+func TestFailover_FallsBackAndRecordsProvider(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	primary := NewMockClient(ctrl)
+	secondary := NewMockClient(ctrl)
+	f := NewFailoverClient(newTestProvider("openai", primary), newTestProvider("secondary", secondary))
+
+	primary.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).
+		Return(Completion{}, errors.New("connection refused")).Times(1)
+	secondary.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).
+		Return(Completion{Message: "selam", Attempts: 1}, nil).Times(1)
+
+	//act
+	completion, err := f.GetCompletion(context.Background(), "merhaba", nil)
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, "selam", completion.Message)
+	assert.Equal(t, "secondary", completion.Provider)
+	assert.Equal(t, BreakerOpen, f.Status()[0].Breaker.State)
+}
+
+func TestFailover_SkipsOpenCircuit(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	primary := NewMockClient(ctrl)
+	secondary := NewMockClient(ctrl)
+	p := newTestProvider("openai", primary)
+	p.Breaker.Allow()
+	p.Breaker.Record(true, errors.New("down"))
+	f := NewFailoverClient(p, newTestProvider("local", secondary))
+
+	// primary'nin devresi açık, hiç çağrılmamalı
+	secondary.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(Completion{Message: "selam", Provider: "local"}, nil).Times(1)
+
+	completion, err := f.GetCompletion(context.Background(), "merhaba", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "local", completion.Provider)
+}
+
+func TestFailover_BadRequestIsNotFailedOver(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	primary := NewMockClient(ctrl)
+	secondary := NewMockClient(ctrl)
+	f := NewFailoverClient(newTestProvider("openai", primary), newTestProvider("secondary", secondary))
+	badRequest := &openai.Error{StatusCode: http.StatusBadRequest}
+
+	primary.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).Return(Completion{}, badRequest).Times(1)
+
+	_, err := f.GetCompletion(context.Background(), "merhaba", nil)
+
+	assert.ErrorIs(t, err, badRequest)
+	assert.Equal(t, BreakerClosed, f.Status()[0].Breaker.State)
+}
+
+func TestFailover_AllProvidersFail(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	primary := NewMockClient(ctrl)
+	secondary := NewMockClient(ctrl)
+	f := NewFailoverClient(newTestProvider("openai", primary), newTestProvider("secondary", secondary))
+	lastErr := errors.New("secondary down")
+
+	primary.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).Return(Completion{}, errors.New("openai down")).Times(1)
+	secondary.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).Return(Completion{}, lastErr).Times(1)
+
+	_, err := f.GetCompletion(context.Background(), "merhaba", nil)
+
+	assert.ErrorIs(t, err, lastErr)
+}
+
+func TestDiagnostics_Providers(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	p := newTestProvider("openai", NewMockClient(ctrl))
+	p.Breaker.Allow()
+	p.Breaker.Record(true, errors.New("down"))
+	f := NewFailoverClient(p, newTestProvider("local", NewMockClient(ctrl)))
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	//act
+	err := NewDiagnosticsHandler(f).Providers(c)
+
+	//assert
+	assert.NoError(t, err)
+	var body struct {
+		Providers []ProviderStatus
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	if assert.Len(t, body.Providers, 2) {
+		assert.Equal(t, "openai", body.Providers[0].Name)
+		assert.Equal(t, BreakerOpen, body.Providers[0].Breaker.State)
+		assert.Equal(t, "local-model", body.Providers[1].Model)
+		assert.Equal(t, BreakerClosed, body.Providers[1].Breaker.State)
+	}
+}
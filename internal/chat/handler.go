@@ -2,6 +2,7 @@ package chat
 
 import (
 	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -23,51 +24,65 @@ func NewHandler(service Service) Handler {
 	}
 }
 
+// hatalar HTTPErrorHandler tarafından JSON'a çevrilir, handler sadece döner.
 func (h *handler) Send(c echo.Context) error {
-	logger.Log.Info("received send request")
+	log := logger.FromContext(c.Request().Context())
+	log.Info("received send request")
 	input := new(Chat)
 	if err := c.Bind(input); err != nil {
-		logger.Log.Warn("failed to bind request", zap.Error(err))
-		return c.String(http.StatusBadRequest, "bad request")
+		log.Warn("failed to bind request", zap.Error(err))
+		return ErrInvalidRequest
 	}
-	if input.SessionID == "" {
-		input.SessionID = uuid.New().String()
+	if input.SessionID != "" {
+		c.Set(middleware.SessionIDKey, input.SessionID)
 	}
-	if _, err := uuid.Parse(input.SessionID); err != nil {
-		logger.Log.Warn("UUID is not correct format", zap.Error(err))
-		return c.String(http.StatusBadRequest, "uuid is not correct format")
+	if err := ValidateInput(input.SessionID, input.Message); err != nil {
+		log.Warn("invalid chat input", zap.Error(err))
+		return err
 	}
-	if len(input.Message) < 3 || len(input.Message) > 2048 {
-		logger.Log.Warn("Message is not correct format")
-		return c.String(http.StatusBadRequest, "message length should be between 3 and 2048")
-	}
-	response, err := h.service.SendMessage(input.SessionID, input.Message)
+	response, err := h.service.SendMessage(c.Request().Context(), input.SessionID, input.Message)
 	if err != nil {
-		logger.Log.Error("service error occured", zap.Error(err))
-		return c.String(http.StatusInternalServerError, "service error occured")
+		log.Error("service error occured", zap.Error(err))
+		return withPublicMessage(err, "unable to perform chat completion")
 	}
 
-	logger.Log.Info("request sent successfully",
-		zap.String("sessionID", input.SessionID),
+	c.Set(middleware.SessionIDKey, response.SessionID)
+	log.Info("request sent successfully",
+		zap.String("sessionID", response.SessionID),
 		zap.String("message", input.Message))
 
 	return c.JSON(http.StatusOK, response)
 }
 
+// ValidateInput POST v1/chat kurallarını uygular; async endpoint de aynı
+// kuralları kullanır. sessionID boşsa yeni session'dır, uuid'i service üretir.
+func ValidateInput(sessionID, message string) error {
+	if sessionID != "" {
+		if _, err := uuid.Parse(sessionID); err != nil {
+			return ErrInvalidSessionID
+		}
+	}
+	if len(message) < 3 || len(message) > 2048 {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
 func (h *handler) ShowHistory(c echo.Context) error {
-	logger.Log.Info("received show history request")
+	log := logger.FromContext(c.Request().Context())
+	log.Info("received show history request")
 	session_id := c.Param("sessionId")
 	if _, err := uuid.Parse(session_id); err != nil {
-		logger.Log.Warn("UUID is not correct format", zap.Error(err))
-		return c.String(http.StatusBadRequest, "uuid is not correct format")
+		log.Warn("UUID is not correct format", zap.Error(err))
+		return ErrInvalidSessionID
 	}
 
-	history, err := h.service.FindHistory(session_id)
+	history, err := h.service.FindHistory(c.Request().Context(), session_id)
 	if err != nil {
-		logger.Log.Error("service error occured", zap.Error(err))
-		return c.String(http.StatusInternalServerError, "session id bulunamadı db de")
+		log.Error("service error occured", zap.Error(err))
+		return withPublicMessage(err, "unable to respond session history")
 	}
-	logger.Log.Info("request sent successfully",
+	log.Info("request sent successfully",
 		zap.String("sessionID", session_id))
 	return c.JSON(http.StatusOK, history)
 
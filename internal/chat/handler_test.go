@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +17,16 @@ import (
 	"go.uber.org/zap"
 )
 
+// handle handler'ı echo'nun yaptığı gibi çalıştırır: dönen hata merkezi
+// HTTPErrorHandler'dan geçer ve recorder'a JSON olarak yazılır.
+func handle(c echo.Context, h echo.HandlerFunc) error {
+	err := h(c)
+	if err != nil {
+		HTTPErrorHandler(err, c)
+	}
+	return err
+}
+
 func TestSend_Success(t *testing.T) {
 	// Setup
 	logger.Log = zap.NewNop()
@@ -39,6 +50,7 @@ func TestSend_Success(t *testing.T) {
 	expectedChat := Chat{
 		SessionID: id,
 		Message:   "sana nasıl yardımcı olabilirim",
+		Attempts:  2,
 	}
 	expectedJSON, err := json.Marshal(expectedChat)
 	if err != nil {
@@ -46,7 +58,7 @@ func TestSend_Success(t *testing.T) {
 	}
 
 	serviceMock.EXPECT().
-		SendMessage(id, msg).
+		SendMessage(gomock.Any(), id, msg).
 		Return(expectedChat, nil).
 		Times(1)
 
@@ -55,10 +67,43 @@ func TestSend_Success(t *testing.T) {
 	//  Assert
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.JSONEq(t, string(expectedJSON), rec.Body.String()) //handler da c.json döndüğümüzden burda bunu kullanıyoruz.Diğer error durumlarında c.string döndüğümüzden error nil döner ve metni bu equal ile karşılaştırabiliriz.
+	assert.JSONEq(t, string(expectedJSON), rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"Attempts":2`)
 
 }
 
+func TestSend_WithoutSessionID_StartsNewSession(t *testing.T) {
+	// Setup
+	logger.Log = zap.NewNop()
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	serviceMock := NewMockService(ctrl)
+	handler := NewHandler(serviceMock)
+
+	chatJSON := `{"Message":"merhaba canım"}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(chatJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	expectedChat := Chat{
+		SessionID: "47b2b877-b53d-4bee-877c-585dda3f9e71",
+		Message:   "sana nasıl yardımcı olabilirim",
+	}
+	// boş sessionID service'e olduğu gibi gider, uuid'i service üretir
+	serviceMock.EXPECT().SendMessage(gomock.Any(), "", "merhaba canım").Return(expectedChat, nil).Times(1)
+
+	// Act
+	err := handler.Send(c)
+
+	//  Assert
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"Message":"sana nasıl yardımcı olabilirim","SessionID":"47b2b877-b53d-4bee-877c-585dda3f9e71"}`, rec.Body.String())
+}
+
 func TestSend_BindError(t *testing.T) {
 	// Setup
 	logger.Log = zap.NewNop()
@@ -78,18 +123,15 @@ func TestSend_BindError(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	// Act
-	handler.Send(c)
+	err := handle(c, handler.Send)
 
 	//  Assert
-
+	assert.ErrorIs(t, err, ErrInvalidRequest)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Equal(t, "bad request", rec.Body.String())
+	assert.JSONEq(t, `{"error":"bad request","code":"invalid_request"}`, rec.Body.String())
 
 }
 
-func TestSend_EmptySessionID_GeneratesUUID(t *testing.T) {
-	//gerek var mı buna bilemedim?
-}
 func TestSend_InvalidSessionID(t *testing.T) {
 	// Setup
 	logger.Log = zap.NewNop()
@@ -108,14 +150,15 @@ func TestSend_InvalidSessionID(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	// Act
-	handler.Send(c)
+	err := handle(c, handler.Send)
 
 	//  Assert
-
+	assert.ErrorIs(t, err, ErrInvalidSessionID)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Equal(t, "uuid is not correct format", rec.Body.String())
+	assert.JSONEq(t, `{"error":"invalid sessionId","code":"invalid_session_id"}`, rec.Body.String())
 }
 
+// 5.3
 func TestSend_MessageTooShort(t *testing.T) {
 	// Setup
 	logger.Log = zap.NewNop()
@@ -126,7 +169,7 @@ func TestSend_MessageTooShort(t *testing.T) {
 	serviceMock := NewMockService(ctrl)
 	handler := NewHandler(serviceMock)
 
-	chatJSON := `{"Message":"Sa" ,"SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`
+	chatJSON := `{"Message":"S" ,"SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`
 
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(chatJSON))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -134,12 +177,12 @@ func TestSend_MessageTooShort(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	// Act
-	handler.Send(c)
+	err := handle(c, handler.Send)
 
 	//  Assert
-
+	assert.ErrorIs(t, err, ErrInvalidMessage)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Equal(t, "message length should be between 3 and 2048", rec.Body.String())
+	assert.JSONEq(t, `{"error":"invalid message","code":"invalid_message"}`, rec.Body.String())
 }
 
 func TestSend_MessageTooLong(t *testing.T) {
@@ -153,7 +196,7 @@ func TestSend_MessageTooLong(t *testing.T) {
 	handler := NewHandler(serviceMock)
 
 	longMessage := strings.Repeat("a", 3000) // 3000 karakterlik "aaaaa..."
-	chatJSON := fmt.Sprintf(`{"Message":"%s","SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`, longMessage)
+	chatJSON := fmt.Sprintf(`{"Message":"%s"}`, longMessage)
 
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(chatJSON))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -161,48 +204,135 @@ func TestSend_MessageTooLong(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	// Act
-	handler.Send(c)
+	err := handle(c, handler.Send)
 
 	//  Assert
+	assert.ErrorIs(t, err, ErrInvalidMessage)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Equal(t, "message length should be between 3 and 2048", rec.Body.String())
+	assert.JSONEq(t, `{"error":"invalid message","code":"invalid_message"}`, rec.Body.String())
 }
 
-func TestSend_ServiceError_SendMessage(t *testing.T) {
+// 5.4
+func TestSend_SessionNotFound(t *testing.T) {
 	// Setup
 	logger.Log = zap.NewNop()
 	e := echo.New()
-	msg := "merhaba canım"
-	id := "811360d0-462f-4fbf-b90b-ccba665986f1"
-	chatJSON := `{"Message":"merhaba canım" ,"SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`
-
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	serviceMock := NewMockService(ctrl)
+	handler := NewHandler(serviceMock)
 
+	chatJSON := `{"Message":"merhaba canım" ,"SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(chatJSON))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	handler := NewHandler(serviceMock)
 
 	serviceMock.EXPECT().
-		SendMessage(id, msg).
-		Return(Chat{}, errors.New("service error")). //bu error ü neden hiçbir yerde çeklemiyoruz onu anlamadım?
+		SendMessage(gomock.Any(), "811360d0-462f-4fbf-b90b-ccba665986f1", "merhaba canım").
+		Return(Chat{}, ErrSessionNotFound).
 		Times(1)
 
-		// Act
-	handler.Send(c)
+	// Act
+	err := handle(c, handler.Send)
+
 	//  Assert
-	// assert.Error(t, err)  bu kontrol neden yok yani. echo nil döndüğünden err yok. ozaman neden return e bir şey yazıyom ?
-	// assert.EqualError(t, err, "database save error")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"sessionId not found","code":"session_not_found"}`, rec.Body.String())
+}
 
-	assert.Equal(t, http.StatusInternalServerError, rec.Code)
-	assert.Equal(t, "service error occured", rec.Body.String())
+// 5.5
+func TestSend_ServiceError_SendMessage(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"upstream", wrap(ErrUpstreamLLM, errors.New("connection refused")), "upstream_llm_error"},
+		{"storage", wrap(ErrStorage, errors.New("db save error")), "storage_error"},
+		{"unknown", errors.New("service error"), "internal_error"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			logger.Log = zap.NewNop()
+			e := echo.New()
+			msg := "merhaba canım"
+			id := "811360d0-462f-4fbf-b90b-ccba665986f1"
+			chatJSON := `{"Message":"merhaba canım" ,"SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			serviceMock := NewMockService(ctrl)
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(chatJSON))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			handler := NewHandler(serviceMock)
+
+			serviceMock.EXPECT().
+				SendMessage(gomock.Any(), id, msg).
+				Return(Chat{}, tc.err).
+				Times(1)
+
+			// Act
+			err := handle(c, handler.Send)
+
+			//  Assert
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, http.StatusInternalServerError, rec.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"error":"unable to perform chat completion","code":%q}`, tc.code), rec.Body.String())
+		})
+	}
+}
 
+func TestSend_ContextErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{"timeout", classify(ErrUpstreamLLM, context.DeadlineExceeded), http.StatusGatewayTimeout,
+			`{"error":"unable to perform chat completion","code":"timeout"}`},
+		{"canceled", classify(ErrUpstreamLLM, context.Canceled), StatusClientClosedRequest,
+			`{"error":"request canceled","code":"request_canceled"}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setup
+			logger.Log = zap.NewNop()
+			e := echo.New()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			serviceMock := NewMockService(ctrl)
+			handler := NewHandler(serviceMock)
+
+			chatJSON := `{"Message":"merhaba canım"}`
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(chatJSON))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// service'e request'in context'i gitmeli
+			serviceMock.EXPECT().SendMessage(req.Context(), "", "merhaba canım").Return(Chat{}, tc.err).Times(1)
+
+			// Act
+			handle(c, handler.Send)
+
+			//  Assert
+			assert.Equal(t, tc.status, rec.Code)
+			assert.JSONEq(t, tc.body, rec.Body.String())
+		})
+	}
 }
 
+// 6.1
 func TestShowHistory_Success(t *testing.T) {
 	//AAA kuralı-> arrange-act-assert
 	//arrange
@@ -243,7 +373,7 @@ func TestShowHistory_Success(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	serviceMock.EXPECT().FindHistory(id).Return(history, nil).Times(1)
+	serviceMock.EXPECT().FindHistory(gomock.Any(), id).Return(history, nil).Times(1)
 	//act
 	err = handler.ShowHistory(c)
 	//assert
@@ -273,13 +403,45 @@ func TestShowHistory_InvalidSessionID(t *testing.T) {
 	c.SetParamValues("bozukid")
 
 	//act
-	handler.ShowHistory(c) //cstring olduğundan error gelmiyor ki
+	err := handle(c, handler.ShowHistory)
 	//assert
+	assert.ErrorIs(t, err, ErrInvalidSessionID)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-	assert.Equal(t, "uuid is not correct format", rec.Body.String())
+	assert.JSONEq(t, `{"error":"invalid sessionId","code":"invalid_session_id"}`, rec.Body.String())
 
 }
 
+// 6.2
+func TestShowHistory_SessionNotFound(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	serviceMock := NewMockService(ctrl)
+	handler := NewHandler(serviceMock)
+
+	c.SetPath("v1/chat/:sessionId")
+	c.SetParamNames("sessionId")
+	c.SetParamValues("811360d0-462f-4fbf-b90b-ccba665986f1")
+
+	serviceMock.EXPECT().FindHistory(gomock.Any(), "811360d0-462f-4fbf-b90b-ccba665986f1").Return(nil, ErrSessionNotFound).Times(1)
+
+	//act
+	err := handle(c, handler.ShowHistory)
+	//assert
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"sessionId not found","code":"session_not_found"}`, rec.Body.String())
+}
+
+// 6.3
 func TestShowHistory_ServiceError_FindHistory(t *testing.T) {
 	//arrange
 	logger.Log = zap.NewNop()
@@ -300,11 +462,29 @@ func TestShowHistory_ServiceError_FindHistory(t *testing.T) {
 	c.SetParamValues("811360d0-462f-4fbf-b90b-ccba665986f1")
 	id := "811360d0-462f-4fbf-b90b-ccba665986f1"
 
-	serviceMock.EXPECT().FindHistory(id).Return(nil, errors.New("service error: find history")).Times(1)
+	serviceMock.EXPECT().FindHistory(gomock.Any(), id).Return(nil, wrap(ErrStorage, errors.New("find history"))).Times(1)
 
 	//act
-	handler.ShowHistory(c) //cstring olduğundan error gelmiyor ki
+	err := handle(c, handler.ShowHistory)
 	//assert
+	assert.ErrorIs(t, err, ErrStorage)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
-	assert.Equal(t, "session id bulunamadı db de", rec.Body.String())
+	assert.JSONEq(t, `{"error":"unable to respond session history","code":"storage_error"}`, rec.Body.String())
+}
+
+func TestHTTPErrorHandler_EchoErrorWithRequestID(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	//act
+	HTTPErrorHandler(echo.ErrNotFound, c)
+
+	//assert
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"Not Found","code":"not_found","requestId":"req-1"}`, rec.Body.String())
 }
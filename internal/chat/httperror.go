@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"myapp/pkg/logger"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+// ErrorResponse 4xx/5xx cevaplarının sabit JSON zarfıdır.
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+var errorStatus = map[*Error]int{
+	ErrInvalidRequest:    http.StatusBadRequest,
+	ErrInvalidMessage:    http.StatusBadRequest,
+	ErrInvalidSessionID:  http.StatusBadRequest,
+	ErrSessionNotFound:   http.StatusNotFound,
+	ErrSessionBusy:       http.StatusConflict,
+	ErrTurnSuperseded:    http.StatusConflict,
+	ErrUpstreamLLM:       http.StatusInternalServerError,
+	ErrStorage:           http.StatusInternalServerError,
+	ErrTimeout:           http.StatusGatewayTimeout,
+	ErrCanceled:          StatusClientClosedRequest,
+	ErrShuttingDown:      http.StatusServiceUnavailable,
+	ErrContentFlagged:    http.StatusUnprocessableEntity,
+	ErrModeration:        http.StatusServiceUnavailable,
+	ErrPromptRejected:    http.StatusUnprocessableEntity,
+	ErrSearchUnavailable: http.StatusNotImplemented,
+}
+
+// ErrorStatus hatanın HTTPErrorHandler'da döneceği status kodudur.
+func ErrorStatus(err error) int {
+	status, _ := errorResponse(err)
+	return status
+}
+
+// StatusClientClosedRequest client bağlantıyı kapattığında kullanılan nginx kodu;
+// cevabı okuyan kimse yok ama access log'da 500'lerden ayrışsın.
+const StatusClientClosedRequest = 499
+
+// HTTPErrorHandler echo'nun merkezi hata handler'ıdır. Domain hatalarını status
+// koduna çevirir ve her hatayı ErrorResponse olarak yazar.
+func HTTPErrorHandler(err error, c echo.Context) {
+	status, body := errorResponse(err)
+	body.RequestID = requestID(c)
+
+	if status >= http.StatusInternalServerError {
+		logger.FromContext(c.Request().Context()).Error("request failed",
+			zap.Int("status", status),
+			zap.String("code", body.Code),
+			zap.Error(err))
+	}
+
+	if c.Response().Committed {
+		return
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, body)
+	}
+	if err != nil {
+		logger.FromContext(c.Request().Context()).Error("failed to write error response", zap.Error(err))
+	}
+}
+
+func errorResponse(err error) (int, ErrorResponse) {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code, ErrorResponse{
+			Error: fmt.Sprint(he.Message),
+			Code:  statusCode(he.Code),
+		}
+	}
+
+	status := http.StatusInternalServerError
+	body := ErrorResponse{
+		Error: "internal server error",
+		Code:  "internal_error",
+	}
+	var de *Error
+	if errors.As(err, &de) {
+		if s, ok := errorStatus[de]; ok {
+			status = s
+		}
+		body.Error = de.Message
+		body.Code = de.Code
+	}
+	// 4xx hataların mesajı zaten client'a yöneliktir, sadece 5xx'ler gizlenir
+	if msg, ok := publicMessage(err); ok && status >= http.StatusInternalServerError {
+		body.Error = msg
+	}
+	return status, body
+}
+
+// statusCode echo'nun kendi hataları (404 route, 405 vb.) için kod üretir: "Not Found" -> "not_found".
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"myapp/pkg/encryption"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+type KeyHandler interface {
+	Rotate(c echo.Context) error
+}
+
+type keyHandler struct {
+	keys encryption.Keyring
+}
+
+// NewKeyHandler tenant data key'lerini yönetmek için admin endpoint'idir.
+func NewKeyHandler(keys encryption.Keyring) KeyHandler {
+	return &keyHandler{
+		keys: keys,
+	}
+}
+
+// Rotate tenant için yeni key versiyonu oluşturur. Yeni mesajlar hemen (diğer
+// replica'larda cache süresi dolunca) yeni key'le yazılır; eski mesajları
+// Reencryptor taşır.
+func (h *keyHandler) Rotate(c echo.Context) error {
+	tenantID := c.Param("tenant")
+	if tenantID == "" {
+		return ErrInvalidRequest
+	}
+	version, err := h.keys.Rotate(c.Request().Context(), tenantID)
+	if err != nil {
+		return withPublicMessage(classify(ErrStorage, err), "unable to rotate key")
+	}
+	return c.JSON(http.StatusOK, echo.Map{"tenantId": tenantID, "version": version})
+}
@@ -0,0 +1,184 @@
+package chat
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionLocker aynı session'a gelen turn'leri sıraya sokar. Lock kilit alınana
+// ya da ctx bitene kadar bekler; dönen unlock mutlaka çağrılmalıdır.
+type SessionLocker interface {
+	Lock(ctx context.Context, sessionID string) (unlock func(), err error)
+}
+
+type localLocker struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	ch   chan struct{} // 1 kapasiteli; dolu olması kilidin tutulduğu anlamına gelir
+	refs int           // bekleyen + tutan; 0 olunca map'ten silinir
+}
+
+// NewLocalLocker tek replica için process içi kilit döner.
+func NewLocalLocker() SessionLocker {
+	return &localLocker{locks: make(map[string]*sessionLock)}
+}
+
+func (l *localLocker) Lock(ctx context.Context, sessionID string) (func(), error) {
+	l.mu.Lock()
+	sl, ok := l.locks[sessionID]
+	if !ok {
+		sl = &sessionLock{ch: make(chan struct{}, 1)}
+		l.locks[sessionID] = sl
+	}
+	sl.refs++
+	l.mu.Unlock()
+
+	select {
+	case sl.ch <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-sl.ch
+				l.release(sessionID, sl)
+			})
+		}, nil
+	case <-ctx.Done():
+		l.release(sessionID, sl)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *localLocker) release(sessionID string, sl *sessionLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sl.refs--
+	if sl.refs == 0 {
+		delete(l.locks, sessionID)
+	}
+}
+
+type mysqlLocker struct {
+	db   *gorm.DB
+	wait time.Duration
+}
+
+// NewMySQLLocker birden fazla replica için MySQL GET_LOCK advisory lock'unu
+// kullanır. wait GET_LOCK'un en fazla bekleme süresidir; dolarsa ErrSessionBusy döner.
+// Kilit bağlantıya bağlı olduğu için unlock'a kadar pool'dan bir bağlantı tutulur.
+func NewMySQLLocker(db *gorm.DB, wait time.Duration) SessionLocker {
+	return &mysqlLocker{db: db, wait: wait}
+}
+
+func (l *mysqlLocker) Lock(ctx context.Context, sessionID string) (func(), error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := lockName(sessionID)
+
+	var got *int
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(l.wait.Seconds())).Scan(&got)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if got == nil || *got != 1 {
+		conn.Close()
+		return nil, ErrSessionBusy
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// request ctx iptal olmuş olabilir; kilidi yine de bırak
+			conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+			conn.Close()
+		})
+	}, nil
+}
+
+// lockName MySQL lock isimleri 64 karakterle sınırlı.
+func lockName(sessionID string) string {
+	sum := sha1.Sum([]byte(sessionID))
+	return fmt.Sprintf("chat_session:%s", hex.EncodeToString(sum[:]))
+}
+
+// postgresLockPoll pg_try_advisory_lock denemeleri arasındaki beklemedir.
+const postgresLockPoll = 50 * time.Millisecond
+
+type postgresLocker struct {
+	db   *gorm.DB
+	wait time.Duration
+}
+
+// NewPostgresLocker birden fazla replica için Postgres session-level advisory
+// lock'unu kullanır. pg_advisory_lock süresiz beklediği için pg_try_advisory_lock
+// wait dolana kadar yoklanır; dolarsa ErrSessionBusy döner. Kilit bağlantıya
+// bağlı olduğu için unlock'a kadar pool'dan bir bağlantı tutulur.
+func NewPostgresLocker(db *gorm.DB, wait time.Duration) SessionLocker {
+	return &postgresLocker{db: db, wait: wait}
+}
+
+func (l *postgresLocker) Lock(ctx context.Context, sessionID string) (func(), error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := lockKey(sessionID)
+
+	deadline := time.Now().Add(l.wait)
+	for {
+		var got bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&got); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if got {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			conn.Close()
+			return nil, ErrSessionBusy
+		}
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, ctx.Err()
+		case <-time.After(postgresLockPoll):
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// request ctx iptal olmuş olabilir; kilidi yine de bırak
+			conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+			conn.Close()
+		})
+	}, nil
+}
+
+// lockKey Postgres advisory lock'ları bigint anahtar alır; session id'nin
+// hash'inden türetilir.
+func lockKey(sessionID string) int64 {
+	sum := sha1.Sum([]byte("chat_session:" + sessionID))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"context"
+	"myapp/pkg/database"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalLocker_Serializes(t *testing.T) {
+	//arrange
+	locker := NewLocalLocker()
+	var inside, maxInside int32
+	var wg sync.WaitGroup
+
+	//act
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := locker.Lock(context.Background(), "s1")
+			assert.NoError(t, err)
+			n := atomic.AddInt32(&inside, 1)
+			for {
+				m := atomic.LoadInt32(&maxInside)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInside, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&inside, -1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	//assert
+	assert.Equal(t, int32(1), maxInside)
+	assert.Empty(t, locker.(*localLocker).locks)
+}
+
+func TestLocalLocker_DifferentSessionsDoNotBlock(t *testing.T) {
+	locker := NewLocalLocker()
+	unlock, err := locker.Lock(context.Background(), "s1")
+	assert.NoError(t, err)
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlock2, err := locker.Lock(ctx, "s2")
+
+	assert.NoError(t, err)
+	unlock2()
+}
+
+func TestLocalLocker_ContextCanceledWhileWaiting(t *testing.T) {
+	//arrange
+	locker := NewLocalLocker()
+	unlock, _ := locker.Lock(context.Background(), "s1")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	//act
+	_, err := locker.Lock(ctx, "s1")
+
+	//assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	unlock()
+	unlock() // ikinci çağrı etkisiz olmalı
+	assert.Empty(t, locker.(*localLocker).locks)
+}
+
+func TestPostgresLocker(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	//arrange
+	db, err := database.Connect(database.Config{Driver: database.Postgres, DSN: dsn})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	first := NewPostgresLocker(db, time.Second)
+	second := NewPostgresLocker(db, 100*time.Millisecond)
+	ctx := context.Background()
+
+	//act
+	unlock, err := first.Lock(ctx, "s1")
+	require.NoError(t, err)
+	_, busyErr := second.Lock(ctx, "s1")
+	other, otherErr := second.Lock(ctx, "s2")
+	unlock()
+	again, againErr := second.Lock(ctx, "s1")
+
+	//assert
+	assert.ErrorIs(t, busyErr, ErrSessionBusy)
+	require.NoError(t, otherErr, "other sessions are not blocked")
+	other()
+	require.NoError(t, againErr, "unlock releases the advisory lock")
+	again()
+}
@@ -10,6 +10,7 @@
 package chat
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -40,16 +41,54 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 }
 
 // GetCompletion mocks base method.
-func (m *MockClient) GetCompletion(message string, messages []ChatMessage) (string, error) {
+func (m *MockClient) GetCompletion(ctx context.Context, message string, messages []ChatMessage) (Completion, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCompletion", message, messages)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GetCompletion", ctx, message, messages)
+	ret0, _ := ret[0].(Completion)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetCompletion indicates an expected call of GetCompletion.
-func (mr *MockClientMockRecorder) GetCompletion(message, messages any) *gomock.Call {
+func (mr *MockClientMockRecorder) GetCompletion(ctx, message, messages any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompletion", reflect.TypeOf((*MockClient)(nil).GetCompletion), message, messages)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompletion", reflect.TypeOf((*MockClient)(nil).GetCompletion), ctx, message, messages)
+}
+
+// MockPinger is a mock of Pinger interface.
+type MockPinger struct {
+	ctrl     *gomock.Controller
+	recorder *MockPingerMockRecorder
+	isgomock struct{}
+}
+
+// MockPingerMockRecorder is the mock recorder for MockPinger.
+type MockPingerMockRecorder struct {
+	mock *MockPinger
+}
+
+// NewMockPinger creates a new mock instance.
+func NewMockPinger(ctrl *gomock.Controller) *MockPinger {
+	mock := &MockPinger{ctrl: ctrl}
+	mock.recorder = &MockPingerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPinger) EXPECT() *MockPingerMockRecorder {
+	return m.recorder
+}
+
+// Ping mocks base method.
+func (m *MockPinger) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockPingerMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockPinger)(nil).Ping), ctx)
 }
@@ -10,6 +10,8 @@
 package chat
 
 import (
+	context "context"
+	events "myapp/internal/events"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -39,31 +41,115 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddEvents mocks base method.
+func (m *MockRepository) AddEvents(ctx context.Context, evs ...events.Event) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx}
+	for _, a := range evs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "AddEvents", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddEvents indicates an expected call of AddEvents.
+func (mr *MockRepositoryMockRecorder) AddEvents(ctx any, evs ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx}, evs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddEvents", reflect.TypeOf((*MockRepository)(nil).AddEvents), varargs...)
+}
+
+// EraseUser mocks base method.
+func (m *MockRepository) EraseUser(ctx context.Context, userID string) (Erasure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EraseUser", ctx, userID)
+	ret0, _ := ret[0].(Erasure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EraseUser indicates an expected call of EraseUser.
+func (mr *MockRepositoryMockRecorder) EraseUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EraseUser", reflect.TypeOf((*MockRepository)(nil).EraseUser), ctx, userID)
+}
+
 // Find mocks base method.
-func (m *MockRepository) Find(sessionID string) ([]ChatMessage, error) {
+func (m *MockRepository) Find(ctx context.Context, sessionID string) ([]ChatMessage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Find", sessionID)
+	ret := m.ctrl.Call(m, "Find", ctx, sessionID)
 	ret0, _ := ret[0].([]ChatMessage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Find indicates an expected call of Find.
-func (mr *MockRepositoryMockRecorder) Find(sessionID any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Find(ctx, sessionID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockRepository)(nil).Find), sessionID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockRepository)(nil).Find), ctx, sessionID)
+}
+
+// Flagged mocks base method.
+func (m *MockRepository) Flagged(ctx context.Context, beforeID, limit int) ([]ChatMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flagged", ctx, beforeID, limit)
+	ret0, _ := ret[0].([]ChatMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Flagged indicates an expected call of Flagged.
+func (mr *MockRepositoryMockRecorder) Flagged(ctx, beforeID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flagged", reflect.TypeOf((*MockRepository)(nil).Flagged), ctx, beforeID, limit)
 }
 
 // Save mocks base method.
-func (m *MockRepository) Save(message *ChatMessage) error {
+func (m *MockRepository) Save(ctx context.Context, message *ChatMessage, evs ...events.Event) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", message)
+	varargs := []any{ctx, message}
+	for _, a := range evs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Save", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockRepositoryMockRecorder) Save(message any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Save(ctx, message any, evs ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, message}, evs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRepository)(nil).Save), varargs...)
+}
+
+// Search mocks base method.
+func (m *MockRepository) Search(ctx context.Context, userID, query string, limit int) ([]ChatMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, userID, query, limit)
+	ret0, _ := ret[0].([]ChatMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockRepositoryMockRecorder) Search(ctx, userID, query, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockRepository)(nil).Search), ctx, userID, query, limit)
+}
+
+// UserMessages mocks base method.
+func (m *MockRepository) UserMessages(ctx context.Context, userID string) ([]ChatMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserMessages", ctx, userID)
+	ret0, _ := ret[0].([]ChatMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserMessages indicates an expected call of UserMessages.
+func (mr *MockRepositoryMockRecorder) UserMessages(ctx, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRepository)(nil).Save), message)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserMessages", reflect.TypeOf((*MockRepository)(nil).UserMessages), ctx, userID)
 }
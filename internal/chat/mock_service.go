@@ -10,6 +10,7 @@
 package chat
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -39,32 +40,61 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// Drain mocks base method.
+func (m *MockService) Drain(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drain", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Drain indicates an expected call of Drain.
+func (mr *MockServiceMockRecorder) Drain(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drain", reflect.TypeOf((*MockService)(nil).Drain), ctx)
+}
+
 // FindHistory mocks base method.
-func (m *MockService) FindHistory(sessionID string) ([]ChatMessage, error) {
+func (m *MockService) FindHistory(ctx context.Context, sessionID string) ([]ChatMessage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindHistory", sessionID)
+	ret := m.ctrl.Call(m, "FindHistory", ctx, sessionID)
 	ret0, _ := ret[0].([]ChatMessage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindHistory indicates an expected call of FindHistory.
-func (mr *MockServiceMockRecorder) FindHistory(sessionID any) *gomock.Call {
+func (mr *MockServiceMockRecorder) FindHistory(ctx, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindHistory", reflect.TypeOf((*MockService)(nil).FindHistory), ctx, sessionID)
+}
+
+// Resume mocks base method.
+func (m *MockService) Resume(ctx context.Context, sessionID string, promptSeq int64) (Chat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resume", ctx, sessionID, promptSeq)
+	ret0, _ := ret[0].(Chat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockServiceMockRecorder) Resume(ctx, sessionID, promptSeq any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindHistory", reflect.TypeOf((*MockService)(nil).FindHistory), sessionID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockService)(nil).Resume), ctx, sessionID, promptSeq)
 }
 
 // SendMessage mocks base method.
-func (m *MockService) SendMessage(sessionID, message string) (Chat, error) {
+func (m *MockService) SendMessage(ctx context.Context, sessionID, message string) (Chat, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendMessage", sessionID, message)
+	ret := m.ctrl.Call(m, "SendMessage", ctx, sessionID, message)
 	ret0, _ := ret[0].(Chat)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SendMessage indicates an expected call of SendMessage.
-func (mr *MockServiceMockRecorder) SendMessage(sessionID, message any) *gomock.Call {
+func (mr *MockServiceMockRecorder) SendMessage(ctx, sessionID, message any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockService)(nil).SendMessage), sessionID, message)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockService)(nil).SendMessage), ctx, sessionID, message)
 }
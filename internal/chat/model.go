@@ -3,18 +3,79 @@ package chat
 type Chat struct { //chatdto
 	Message   string
 	SessionID string
+	// Attempts cevabın kaç LLM çağrısında alındığıdır (retry'lar dahil);
+	// sadece cevapta dolu.
+	Attempts int `json:",omitempty"`
 }
 type MessageKind string
 
 const (
 	UserPrompt MessageKind = "USER_PROMPT"
 	LLMOutput  MessageKind = "LLM_OUTPUT"
+	// Interrupted prompt'u kaydedilmiş ama cevabı shutdown yüzünden alınamamış
+	// turn'ü kapatır; LLM'e giden history'ye eklenmez.
+	Interrupted MessageKind = "INTERRUPTED"
 )
 
 type ChatMessage struct { //direkt chat olmalı adı bence.
 	ID        int
 	Kind      MessageKind
 	Message   string
-	Timestamp int64
-	SessionID string
+	Timestamp int64  `gorm:"index;index:idx_session_timestamp,priority:2"`
+	SessionID string `gorm:"size:64;uniqueIndex:idx_session_seq,priority:1;index:idx_session_timestamp,priority:1"`
+	TenantID  string `gorm:"size:64;not null;default:'';index:idx_tenant_key,priority:1" json:",omitempty"`
+	UserID    string `gorm:"size:255;index" json:",omitempty"`              // turn'ü başlatan kullanıcı; export ve silme buna göre yapılır
+	Persona   string `gorm:"size:64;not null;default:''" json:",omitempty"` // retention kuralları için
+	// KeyVersion Message'ı şifreleyen tenant key'inin versiyonudur; 0 ise
+	// Message düz metindir. Repository dışında her zaman 0'dır.
+	// Kolon sonradan eklendiği için eski satırlar NULL değil 0 almalı ki
+	// Reencryptor onları bulsun.
+	KeyVersion int `gorm:"not null;default:0;index:idx_tenant_key,priority:2" json:"-"`
+	// Seq session içindeki turn sırasıdır (1'den başlar); timestamp saniye
+	// çözünürlüğünde olduğu için history sırası buna göre belirlenir.
+	Seq      int64  `gorm:"uniqueIndex:idx_session_seq,priority:2"`
+	Attempts int    `json:",omitempty"` // LLM_OUTPUT için cevabın kaçıncı denemede alındığı
+	Provider string `json:",omitempty"` // LLM_OUTPUT'u hangi provider'ın verdiği
+	Model    string `json:",omitempty"`
+	Route    string `json:",omitempty"` // modeli seçen routing kuralı
+	// Moderation mesaj moderasyonda işaretlendiyse kategorileridir (virgülle
+	// ayrılmış). İşaretli mesajlar LLM'e giden history'ye eklenmez.
+	Moderation string `gorm:"size:255;index" json:",omitempty"`
+	// guardrail'in prompt için kararı; guardrail kapalıysa boştur. Block
+	// edilen prompt'lar LLM'e giden history'ye eklenmez.
+	GuardAction  GuardAction `gorm:"size:16" json:",omitempty"`
+	GuardScore   float64     `json:",omitempty"`
+	GuardSignals string      `gorm:"size:255" json:",omitempty"` // virgülle ayrılmış kural adları
+}
+
+// SessionCreatedEvent yeni session'ın ilk mesajıyla birlikte yayınlanır.
+// MessageSaved event'inin payload'ı ise ChatMessage'ın kendisidir.
+type SessionCreatedEvent struct {
+	SessionID string `json:"sessionId"`
+	UserID    string `json:"userId,omitempty"`
+	TenantID  string `json:"tenantId,omitempty"`
+}
+
+// Erasure EraseUser'ın sildiği kayıt sayılarıdır.
+type Erasure struct {
+	Sessions int64 `json:"sessions"`
+	Messages int64 `json:"messages"`
+	Events   int64 `json:"events"`
+}
+
+// ContentFlaggedEvent prompt ya da cevap moderasyonda işaretlendiğinde yayınlanır.
+type ContentFlaggedEvent struct {
+	SessionID  string      `json:"sessionId"`
+	Seq        int64       `json:"seq"`
+	Kind       MessageKind `json:"kind"`
+	Categories []string    `json:"categories"`
+	Action     string      `json:"action"`
+}
+
+// CompletionFailedEvent prompt kaydedildikten sonra cevap alınamadığında yayınlanır.
+type CompletionFailedEvent struct {
+	SessionID string `json:"sessionId"`
+	PromptID  int    `json:"promptId"`
+	Seq       int64  `json:"seq"`
+	Code      string `json:"code"`
 }
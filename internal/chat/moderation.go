@@ -0,0 +1,208 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+)
+
+// ModerationResult Flagged ise Categories ihlal edilen kategorilerdir (ör. "hate", "violence").
+type ModerationResult struct {
+	Flagged    bool
+	Categories []string
+}
+
+// Moderator bir metnin içerik politikasını ihlal edip etmediğine karar verir.
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (ModerationResult, error)
+}
+
+// ModerationAction işaretlenen turn'de ne yapılacağıdır.
+type ModerationAction string
+
+const (
+	// ModerationReject turn'ü ErrContentFlagged ile reddeder.
+	ModerationReject ModerationAction = "reject"
+	// ModerationRefuse kullanıcıya RefusalMessage'ı cevap olarak döner.
+	ModerationRefuse ModerationAction = "refuse"
+)
+
+const DefaultRefusalMessage = "Sorry, I can't help with that request."
+
+// ModerationConfig Moderator nil ise moderasyon kapalıdır. FailOpen ise
+// moderator'a ulaşılamadığında turn moderasyonsuz devam eder.
+type ModerationConfig struct {
+	Moderator      Moderator
+	Action         ModerationAction
+	RefusalMessage string
+	FailOpen       bool
+}
+
+type openAIModerator struct {
+	openai  openai.Client
+	timeout time.Duration
+}
+
+// NewOpenAIModerator OpenAI moderation endpoint'ini (omni-moderation-latest) kullanır; ücretsizdir.
+func NewOpenAIModerator(apiKey string, timeout time.Duration, opts ...option.RequestOption) Moderator {
+	base := []option.RequestOption{option.WithAPIKey(apiKey), option.WithMaxRetries(1)}
+	return &openAIModerator{
+		openai:  openai.NewClient(append(base, opts...)...),
+		timeout: timeout,
+	}
+}
+
+func (m *openAIModerator) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	res, err := m.openai.Moderations.New(ctx, openai.ModerationNewParams{
+		Input: openai.ModerationNewParamsInputUnion{OfString: openai.String(text)},
+		Model: openai.ModerationModelOmniModerationLatest,
+	})
+	if err != nil {
+		return ModerationResult{}, err
+	}
+	if len(res.Results) == 0 {
+		return ModerationResult{}, fmt.Errorf("no moderation results returned")
+	}
+	result := res.Results[0]
+	// kategori listesi modele göre değişiyor; struct alanları yerine ham JSON'dan okunur
+	var categories map[string]any
+	if err := json.Unmarshal([]byte(result.Categories.RawJSON()), &categories); err != nil {
+		return ModerationResult{}, fmt.Errorf("parse moderation categories: %w", err)
+	}
+	out := ModerationResult{Flagged: result.Flagged}
+	for name, flagged := range categories {
+		if flagged == true {
+			out.Categories = append(out.Categories, name)
+		}
+	}
+	sort.Strings(out.Categories)
+	return out, nil
+}
+
+// ModerationRule bir kategoriyi anahtar kelime ya da regex ile tanımlar.
+// Kelimeler büyük/küçük harf duyarsız ve tam kelime olarak eşleşir.
+type ModerationRule struct {
+	Category string   `json:"category"`
+	Keywords []string `json:"keywords,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// LoadModerationRules JSON dosyasından yerel moderasyon kurallarını okur.
+func LoadModerationRules(path string) ([]ModerationRule, error) {
+	var rules []ModerationRule
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+type keywordRule struct {
+	category string
+	keywords []string // normalize edilmiş, başı ve sonu boşluklu
+	patterns []*regexp.Regexp
+}
+
+type keywordModerator struct {
+	rules []keywordRule
+}
+
+// NewKeywordModerator dışarıya istek atmadan kurallarla moderasyon yapar.
+func NewKeywordModerator(rules []ModerationRule) (Moderator, error) {
+	m := &keywordModerator{}
+	for _, rule := range rules {
+		if rule.Category == "" {
+			return nil, fmt.Errorf("moderation rule has no category")
+		}
+		kr := keywordRule{category: rule.Category}
+		for _, kw := range rule.Keywords {
+			kr.keywords = append(kr.keywords, " "+normalizeWords(kw)+" ")
+		}
+		for _, p := range rule.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("moderation rule %s: %w", rule.Category, err)
+			}
+			kr.patterns = append(kr.patterns, re)
+		}
+		m.rules = append(m.rules, kr)
+	}
+	return m, nil
+}
+
+func (m *keywordModerator) Moderate(_ context.Context, text string) (ModerationResult, error) {
+	var out ModerationResult
+	words := " " + normalizeWords(text) + " "
+	for _, rule := range m.rules {
+		if rule.matches(text, words) && !slices.Contains(out.Categories, rule.category) {
+			out.Categories = append(out.Categories, rule.category)
+		}
+	}
+	out.Flagged = len(out.Categories) > 0
+	return out, nil
+}
+
+func (r keywordRule) matches(text, words string) bool {
+	for _, kw := range r.keywords {
+		if strings.Contains(words, kw) {
+			return true
+		}
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWords metni küçük harfli ve tek boşlukla ayrılmış kelimelere çevirir;
+// Go regex'inin \b'si Türkçe harfleri kelime saymadığı için regex kullanılmaz.
+func normalizeWords(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+type multiModerator []Moderator
+
+// NewMultiModerator tüm moderator'ları çalıştırır; biri işaretlerse metin
+// işaretlenir ve kategoriler birleştirilir. İlk hata döner.
+func NewMultiModerator(moderators ...Moderator) Moderator {
+	return multiModerator(moderators)
+}
+
+func (m multiModerator) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	var out ModerationResult
+	for _, moderator := range m {
+		res, err := moderator.Moderate(ctx, text)
+		if err != nil {
+			return ModerationResult{}, err
+		}
+		out.Flagged = out.Flagged || res.Flagged
+		for _, c := range res.Categories {
+			if !slices.Contains(out.Categories, c) {
+				out.Categories = append(out.Categories, c)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultReviewLimit = 50
+	maxReviewLimit     = 200
+)
+
+type ModerationHandler interface {
+	Flagged(c echo.Context) error
+}
+
+type moderationHandler struct {
+	repo Repository
+}
+
+// NewModerationHandler işaretlenen mesajları incelemek için admin endpoint'idir.
+func NewModerationHandler(repo Repository) ModerationHandler {
+	return &moderationHandler{
+		repo: repo,
+	}
+}
+
+// Flagged işaretlenen mesajları yeniden eskiye, orijinal metinleriyle döner.
+// Sonraki sayfa için dönen nextBefore, before parametresine verilir.
+func (h *moderationHandler) Flagged(c echo.Context) error {
+	limit, err := queryInt(c, "limit", defaultReviewLimit)
+	if err != nil || limit < 1 || limit > maxReviewLimit {
+		return ErrInvalidRequest
+	}
+	before, err := queryInt(c, "before", 0)
+	if err != nil || before < 0 {
+		return ErrInvalidRequest
+	}
+	messages, err := h.repo.Flagged(c.Request().Context(), before, limit)
+	if err != nil {
+		return withPublicMessage(classify(ErrStorage, err), "unable to list flagged messages")
+	}
+	res := echo.Map{"messages": messages}
+	if len(messages) == limit {
+		res["nextBefore"] = messages[len(messages)-1].ID
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func queryInt(c echo.Context, name string, def int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
@@ -0,0 +1,245 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"myapp/internal/events"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/openai/openai-go/v2/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+type moderatorFunc func(ctx context.Context, text string) (ModerationResult, error)
+
+func (f moderatorFunc) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	return f(ctx, text)
+}
+
+func newKeywordModerator(t *testing.T) Moderator {
+	t.Helper()
+	m, err := NewKeywordModerator([]ModerationRule{
+		{Category: "violence", Keywords: []string{"bomba", "silah yapımı"}},
+		{Category: "self-harm", Patterns: []string{`(?i)kendime zarar`}},
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func TestKeywordModerator(t *testing.T) {
+	m := newKeywordModerator(t)
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"Bomba nasıl yapılır?", []string{"violence"}},
+		{"SİLAH   yapımı ve kendime zarar", []string{"violence", "self-harm"}},
+		{"bombastik bir gün", nil},
+		{"merhaba", nil},
+	}
+	for _, tc := range cases {
+		res, err := m.Moderate(context.Background(), tc.text)
+		assert.NoError(t, err)
+		assert.Equal(t, len(tc.want) > 0, res.Flagged, tc.text)
+		assert.Equal(t, tc.want, res.Categories, tc.text)
+	}
+}
+
+func TestOpenAIModerator(t *testing.T) {
+	//arrange
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+		assert.Equal(t, "/moderations", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"modr-1","model":"omni-moderation-latest","results":[{
+			"flagged": true,
+			"categories": {"hate": false, "violence": true, "harassment/threatening": true, "illicit": null},
+			"category_scores": {"violence": 0.91},
+			"category_applied_input_types": {"violence": ["text"]}
+		}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	m := NewOpenAIModerator("test-key", 0, option.WithBaseURL(srv.URL))
+
+	//act
+	res, err := m.Moderate(context.Background(), "seni bulacağım")
+
+	//assert
+	assert.NoError(t, err)
+	assert.True(t, res.Flagged)
+	assert.Equal(t, []string{"harassment/threatening", "violence"}, res.Categories)
+	assert.Contains(t, body, `"input":"seni bulacağım"`)
+	assert.Contains(t, body, `"model":"omni-moderation-latest"`)
+}
+
+func TestSendMessage_FlaggedPrompt_Rejected(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock, WithModeration(ModerationConfig{Moderator: newKeywordModerator(t)}))
+
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, evs ...events.Event) {
+		assert.Equal(t, UserPrompt, msg.Kind)
+		assert.Equal(t, "violence", msg.Moderation)
+		assert.Equal(t, events.ContentFlagged, evs[len(evs)-1].Type)
+		assert.Equal(t, ContentFlaggedEvent{
+			SessionID:  msg.SessionID,
+			Seq:        1,
+			Kind:       UserPrompt,
+			Categories: []string{"violence"},
+			Action:     "reject",
+		}, evs[len(evs)-1].Payload)
+	}).Return(nil)
+
+	//act
+	_, err := service.SendMessage(context.Background(), "", "bomba nasıl yapılır")
+
+	//assert
+	assert.ErrorIs(t, err, ErrContentFlagged)
+}
+
+func TestSendMessage_FlaggedPrompt_Refused(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock, WithModeration(ModerationConfig{
+		Moderator:      newKeywordModerator(t),
+		Action:         ModerationRefuse,
+		RefusalMessage: "bu konuda yardımcı olamam",
+	}))
+	history := []ChatMessage{{SessionID: "s1", Kind: UserPrompt, Message: "selam", Seq: 1}}
+
+	gomock.InOrder(
+		repoMock.EXPECT().Find(gomock.Any(), "s1").Return(history, nil),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, evs ...events.Event) {
+			assert.Equal(t, "violence", msg.Moderation)
+			// mevcut session: SessionCreated yok
+			assert.Equal(t, []events.Type{events.MessageSaved, events.ContentFlagged}, []events.Type{evs[0].Type, evs[1].Type})
+		}).Return(nil),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
+			assert.Equal(t, LLMOutput, msg.Kind)
+			assert.Equal(t, "bu konuda yardımcı olamam", msg.Message)
+			assert.Equal(t, int64(3), msg.Seq)
+		}).Return(nil),
+	)
+
+	//act
+	res, err := service.SendMessage(context.Background(), "s1", "bomba nasıl yapılır")
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, Chat{Message: "bu konuda yardımcı olamam", SessionID: "s1"}, res)
+}
+
+func TestSendMessage_FlaggedResponse_StoredButNotShown(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock, WithModeration(ModerationConfig{
+		Moderator: newKeywordModerator(t),
+		Action:    ModerationRefuse,
+	}))
+	history := []ChatMessage{
+		{SessionID: "s1", Kind: UserPrompt, Message: "bomba", Seq: 1, Moderation: "violence"},
+		{SessionID: "s1", Kind: LLMOutput, Message: DefaultRefusalMessage, Seq: 2},
+	}
+
+	gomock.InOrder(
+		repoMock.EXPECT().Find(gomock.Any(), "s1").Return(history, nil),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+		// işaretli prompt LLM'e gitmez
+		clientMock.EXPECT().GetCompletion(gomock.Any(), "tarif ver", []ChatMessage{history[1]}).
+			Return(Completion{Message: "önce bomba için..."}, nil),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, evs ...events.Event) {
+			assert.Equal(t, "önce bomba için...", msg.Message)
+			assert.Equal(t, "violence", msg.Moderation)
+			assert.Len(t, evs, 2)
+		}).Return(nil),
+	)
+
+	//act
+	res, err := service.SendMessage(context.Background(), "s1", "tarif ver")
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRefusalMessage, res.Message)
+}
+
+func TestSendMessage_ModerationUnavailable(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	down := moderatorFunc(func(context.Context, string) (ModerationResult, error) {
+		return ModerationResult{}, errors.New("connection refused")
+	})
+
+	_, err := NewService(repoMock, clientMock, WithModeration(ModerationConfig{Moderator: down})).
+		SendMessage(context.Background(), "", "merhaba")
+	assert.ErrorIs(t, err, ErrModeration)
+
+	// fail open: moderasyonsuz devam eder
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	clientMock.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).Return(Completion{Message: "selam"}, nil)
+	res, err := NewService(repoMock, clientMock, WithModeration(ModerationConfig{Moderator: down, FailOpen: true})).
+		SendMessage(context.Background(), "", "merhaba")
+	assert.NoError(t, err)
+	assert.Equal(t, "selam", res.Message)
+}
+
+func TestFindHistory_HidesFlaggedResponses(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	service := NewService(repoMock, NewMockClient(ctrl))
+	repoMock.EXPECT().Find(gomock.Any(), "s1").Return([]ChatMessage{
+		{Kind: UserPrompt, Message: "tarif ver"},
+		{Kind: LLMOutput, Message: "önce bomba için...", Moderation: "violence"},
+	}, nil)
+
+	history, err := service.FindHistory(context.Background(), "s1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRefusalMessage, history[1].Message)
+}
+
+func TestModerationHandler_Flagged(t *testing.T) {
+	//arrange
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	h := NewModerationHandler(repoMock)
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.GET("/debug/moderation", h.Flagged)
+	repoMock.EXPECT().Flagged(gomock.Any(), 40, 2).Return([]ChatMessage{
+		{ID: 39, Moderation: "violence"}, {ID: 12, Moderation: "hate"},
+	}, nil)
+
+	//act
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/moderation?before=40&limit=2", nil))
+	bad := httptest.NewRecorder()
+	e.ServeHTTP(bad, httptest.NewRequest(http.MethodGet, "/debug/moderation?limit=1000", nil))
+
+	//assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"nextBefore":12`)
+	assert.Contains(t, rec.Body.String(), `"Moderation":"violence"`)
+	assert.Equal(t, http.StatusBadRequest, bad.Code)
+}
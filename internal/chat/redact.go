@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"context"
+	"myapp/pkg/redact"
+)
+
+type redactingClient struct {
+	next     Client
+	redactor redact.Redactor
+}
+
+// NewRedactingClient prompt'taki ve history'deki PII'ı provider'a gitmeden
+// önce [EMAIL_1] gibi placeholder'larla maskeler, cevaptaki placeholder'ları
+// geri açar. Kaydedilen mesajlar maskelenmez; sadece LLM'e gidenler.
+func NewRedactingClient(next Client, redactor redact.Redactor) Client {
+	return &redactingClient{
+		next:     next,
+		redactor: redactor,
+	}
+}
+
+func (c *redactingClient) GetCompletion(ctx context.Context, message string, messages []ChatMessage) (Completion, error) {
+	// aynı değer history'de ve yeni mesajda aynı placeholder'ı alsın ki
+	// model konuşmayı takip edebilsin
+	vault := redact.NewVault()
+	masked := make([]ChatMessage, len(messages))
+	for i, msg := range messages {
+		msg.Message = c.redactor.Mask(msg.Message, vault)
+		masked[i] = msg
+	}
+	completion, err := c.next.GetCompletion(ctx, c.redactor.Mask(message, vault), masked)
+	if err != nil {
+		return completion, err
+	}
+	completion.Message = vault.Restore(completion.Message)
+	return completion, nil
+}
+
+type redactingModerator struct {
+	next     Moderator
+	redactor redact.Redactor
+}
+
+// NewRedactingModerator metni dış bir moderation servisine gitmeden önce
+// maskeler. Placeholder'lar içeriğin kategorisini değiştirmez.
+func NewRedactingModerator(next Moderator, redactor redact.Redactor) Moderator {
+	return &redactingModerator{
+		next:     next,
+		redactor: redactor,
+	}
+}
+
+func (m *redactingModerator) Moderate(ctx context.Context, text string) (ModerationResult, error) {
+	return m.next.Moderate(ctx, m.redactor.Mask(text, redact.NewVault()))
+}
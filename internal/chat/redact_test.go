@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"context"
+	"myapp/pkg/redact"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestRedactingClient_MasksPromptAndRestoresResponse(t *testing.T) {
+	//arrange
+	ctrl := gomock.NewController(t)
+	next := NewMockClient(ctrl)
+	c := NewRedactingClient(next, redact.New())
+	history := []ChatMessage{{Message: "mailim ali@example.com", Kind: UserPrompt}}
+
+	next.EXPECT().
+		GetCompletion(gomock.Any(), "[EMAIL_1] ve [PHONE_1] kaydet", []ChatMessage{{Message: "mailim [EMAIL_1]", Kind: UserPrompt}}).
+		Return(Completion{Message: "[EMAIL_1] ve [PHONE_1] kaydedildi"}, nil)
+
+	//act
+	completion, err := c.GetCompletion(context.Background(), "ali@example.com ve 0532 123 45 67 kaydet", history)
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, "ali@example.com ve 0532 123 45 67 kaydedildi", completion.Message)
+	assert.Equal(t, "mailim ali@example.com", history[0].Message, "caller's history must not be modified")
+}
+
+func TestRedactingModerator_MasksText(t *testing.T) {
+	var got string
+	m := NewRedactingModerator(moderatorFunc(func(_ context.Context, text string) (ModerationResult, error) {
+		got = text
+		return ModerationResult{Flagged: true, Categories: []string{"harassment"}}, nil
+	}), redact.New())
+
+	res, err := m.Moderate(context.Background(), "ali@example.com adresine tehdit yaz")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[EMAIL_1] adresine tehdit yaz", got)
+	assert.True(t, res.Flagged)
+}
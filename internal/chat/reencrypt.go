@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"context"
+	"myapp/pkg/encryption"
+	"myapp/pkg/logger"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ReencryptConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	// KeyMaxAge sıfırdan büyükse güncel key'i bundan eski tenant'lar rotate edilir.
+	KeyMaxAge time.Duration
+}
+
+// Reencryptor şifresiz eski satırları ve eski key versiyonuyla şifrelenmiş
+// satırları tenant'ın güncel key'ine taşır. Master key değiştiyse önce data
+// key'leri yeniden sarar. Birden fazla replica'da çalışması güvenlidir:
+// satırlar sadece okunduğu versiyondayken güncellenir.
+type Reencryptor struct {
+	db     *gorm.DB
+	keys   encryption.Keyring
+	cfg    ReencryptConfig
+	tables []encryption.Table
+	wg     sync.WaitGroup
+}
+
+// NewReencryptor chat_messages'ı ve tables'ı (ör. chat_jobs,
+// idempotency_records) aynı turda günceller.
+func NewReencryptor(db *gorm.DB, keys encryption.Keyring, cfg ReencryptConfig, tables ...encryption.Table) *Reencryptor {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Reencryptor{
+		db:     db,
+		keys:   keys,
+		cfg:    cfg,
+		tables: tables,
+	}
+}
+
+// Start ctx iptal edilene kadar her Interval'da RunOnce çalıştırır.
+func (r *Reencryptor) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			if n, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				logger.Log.Error("re-encryption failed", zap.Error(err))
+			} else if n > 0 {
+				logger.Log.Info("messages re-encrypted", zap.Int("count", n))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.cfg.Interval):
+			}
+		}
+	}()
+}
+
+func (r *Reencryptor) Wait() {
+	r.wg.Wait()
+}
+
+// RunOnce güncel key'le şifrelenmemiş tüm satırları işler ve kaç satır
+// güncellendiğini döner.
+func (r *Reencryptor) RunOnce(ctx context.Context) (int, error) {
+	if n, err := r.keys.Rewrap(ctx); err != nil {
+		return 0, err
+	} else if n > 0 {
+		logger.Log.Info("data keys rewrapped", zap.Int("count", n))
+	}
+	if r.cfg.KeyMaxAge > 0 {
+		rotated, err := r.keys.RotateOlderThan(ctx, r.cfg.KeyMaxAge)
+		if err != nil {
+			return 0, err
+		}
+		if len(rotated) > 0 {
+			logger.Log.Info("data keys rotated", zap.Strings("tenants", rotated))
+		}
+	}
+
+	tenants, err := r.tenants(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, tenantID := range tenants {
+		n, err := r.tenant(ctx, tenantID)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// tenantTables tenant'ın diğer tablolardaki satırlarını key'e taşır.
+func (r *Reencryptor) tenantTables(ctx context.Context, tenantID string, version int, key []byte) (int, error) {
+	total := 0
+	for _, t := range r.tables {
+		n, err := t.Reencrypt(ctx, tenantID, version, key, r.cfg.BatchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// tenants key'i olan tenant'lar ile şifresiz satırı olan tenant'ların birleşimidir.
+func (r *Reencryptor) tenants(ctx context.Context) ([]string, error) {
+	versions, err := r.keys.Versions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var plain []string
+	err = r.db.WithContext(ctx).Model(&ChatMessage{}).
+		Where("key_version = 0").Distinct().Pluck("tenant_id", &plain).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range r.tables {
+		more, err := t.PlainTenants(ctx)
+		if err != nil {
+			return nil, err
+		}
+		plain = append(plain, more...)
+	}
+	tenants := make([]string, 0, len(versions)+len(plain))
+	for tenantID := range versions {
+		tenants = append(tenants, tenantID)
+	}
+	seen := make(map[string]bool, len(plain))
+	for _, tenantID := range plain {
+		if _, ok := versions[tenantID]; !ok && !seen[tenantID] {
+			seen[tenantID] = true
+			tenants = append(tenants, tenantID)
+		}
+	}
+	return tenants, nil
+}
+
+func (r *Reencryptor) tenant(ctx context.Context, tenantID string) (int, error) {
+	version, key, err := r.keys.Current(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	total, err := r.tenantTables(ctx, tenantID, version, key)
+	if err != nil {
+		return total, err
+	}
+	lastID := 0
+	for {
+		var rows []ChatMessage
+		err := r.db.WithContext(ctx).
+			Where("tenant_id = ? AND key_version <> ? AND id > ?", tenantID, version, lastID).
+			Order("id").Limit(r.cfg.BatchSize).Find(&rows).Error
+		if err != nil {
+			return total, err
+		}
+		for i := range rows {
+			lastID = rows[i].ID
+			n, err := r.row(ctx, &rows[i], version, key)
+			if err != nil {
+				return total, err
+			}
+			total += n
+		}
+		if len(rows) < r.cfg.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (r *Reencryptor) row(ctx context.Context, msg *ChatMessage, version int, key []byte) (int, error) {
+	old := msg.KeyVersion
+	if err := openMessage(ctx, r.keys, msg); err != nil {
+		return 0, err
+	}
+	sealed, err := sealWith(msg, version, key)
+	if err != nil {
+		return 0, err
+	}
+	// satır bu arada başka bir replica tarafından güncellendiyse dokunulmaz
+	res := r.db.WithContext(ctx).Model(&ChatMessage{}).
+		Where("id = ? AND key_version = ?", msg.ID, old).
+		Updates(map[string]any{"message": sealed.Message, "key_version": version})
+	return int(res.RowsAffected), res.Error
+}
@@ -1,40 +1,217 @@
 package chat
 
 import (
+	"context"
+	"myapp/internal/events"
+	"myapp/pkg/encryption"
 	"myapp/pkg/logger"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// Repository Find'da mesajları Seq sırasıyla döner; Seq'i olmayan eski kayıtlar
+// (Seq 0) başa, kendi aralarında ID sırasıyla gelir.
 type Repository interface {
-	Save(message *ChatMessage) error
-	Find(sessionID string) ([]ChatMessage, error)
+	// Save mesajı ve verilen event'leri outbox'a aynı transaction'da yazar;
+	// mesaj kaydedildiyse event'ler de kaydedilmiştir.
+	Save(ctx context.Context, message *ChatMessage, evs ...events.Event) error
+	Find(ctx context.Context, sessionID string) ([]ChatMessage, error)
+	// AddEvents mesaja bağlı olmayan event'leri (ör. CompletionFailed) outbox'a yazar.
+	AddEvents(ctx context.Context, evs ...events.Event) error
+	// Flagged moderasyonda işaretlenen mesajları yeniden eskiye döner;
+	// beforeID sıfırdan büyükse o ID'den eskiler gelir (sayfalama).
+	Flagged(ctx context.Context, beforeID int, limit int) ([]ChatMessage, error)
+	// UserMessages kullanıcının başlattığı turn'lerin mesajlarını (prompt'lar ve
+	// cevapları) session ve Seq sırasıyla döner. Session'ların sahibi olmadığı
+	// için aynı session'daki başka kullanıcıların turn'leri dahil edilmez.
+	UserMessages(ctx context.Context, userID string) ([]ChatMessage, error)
+	// EraseUser UserMessages'ın döndüğü mesajları ve bu turn'lerin outbox
+	// event'lerini tek transaction'da kalıcı olarak siler.
+	EraseUser(ctx context.Context, userID string) (Erasure, error)
+	// Search kullanıcının turn'lerinde query'yi içeren en fazla limit
+	// mesajı en alakalıdan başlayarak döner: postgres'te tsvector, mysql'de
+	// FULLTEXT index'i, SQLite'ta LIKE kullanılır. WithEncryption ile
+	// ErrSearchUnavailable döner.
+	Search(ctx context.Context, userID, query string, limit int) ([]ChatMessage, error)
 }
 type repository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	timeout time.Duration
+	keys    encryption.Keyring
 }
 
-func NewRepository(db *gorm.DB) Repository {
-	return &repository{
-		db: db,
+// RepositoryOption NewRepository'nin opsiyonel ayarlarıdır.
+type RepositoryOption func(*repository)
+
+// WithEncryption Message alanını tenant'ın data key'iyle AES-GCM kullanarak
+// şifreler. Okurken şifresiz eski satırlar olduğu gibi döner; onları
+// Reencryptor şifreler.
+func WithEncryption(keys encryption.Keyring) RepositoryOption {
+	return func(r *repository) {
+		r.keys = keys
+	}
+}
+
+// NewRepository timeout sıfırdan büyükse her sorguya ayrıca deadline koyar;
+// request context'i iptal edilirse sorgu yine de iptal olur.
+func NewRepository(db *gorm.DB, timeout time.Duration, opts ...RepositoryOption) Repository {
+	r := &repository{
+		db:      db,
+		timeout: timeout,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
-func (r *repository) Save(message *ChatMessage) error {
+func (r *repository) Save(ctx context.Context, message *ChatMessage, evs ...events.Event) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	return r.db.Create(message).Error
+	row := message
+	if r.keys != nil {
+		// çağıranın mesajı düz metin kalır; sadece DB'ye giden kopya şifrelenir
+		sealed, err := sealMessage(ctx, r.keys, message)
+		if err != nil {
+			return err
+		}
+		row = sealed
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(row).Error; err != nil {
+			return err
+		}
+		message.ID = row.ID
+		if r.keys != nil {
+			evs = withoutContent(evs, message)
+		}
+		// payload mesajın kendisiyse DB'nin verdiği ID'yle yazılsın diye Create'ten sonra
+		return createEvents(tx, evs, message.UserID)
+	})
 }
 
-func (r *repository) Find(sessionID string) ([]ChatMessage, error) {
+// withoutContent mesajın kendisini taşıyan event'lerde Message'ı boşaltır;
+// şifreleme açıkken metin outbox'a ve oradan sink'lere düz metin gitmesin.
+func withoutContent(evs []events.Event, message *ChatMessage) []events.Event {
+	out := make([]events.Event, len(evs))
+	for i, ev := range evs {
+		if m, ok := ev.Payload.(*ChatMessage); ok && m == message {
+			stripped := *message
+			stripped.Message = ""
+			ev.Payload = &stripped
+		}
+		out[i] = ev
+	}
+	return out
+}
+
+func (r *repository) AddEvents(ctx context.Context, evs ...events.Event) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return createEvents(r.db.WithContext(ctx), evs, "")
+}
+
+func createEvents(db *gorm.DB, evs []events.Event, userID string) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	records, err := events.NewRecords(evs)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].UserID = userID
+	}
+	return db.Create(&records).Error
+}
+
+func (r *repository) Find(ctx context.Context, sessionID string) ([]ChatMessage, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	var messages []ChatMessage
-	result := r.db.Where("session_id = ?", sessionID).Find(&messages).Order("timestamp desc")
+	result := r.db.WithContext(ctx).Where("session_id = ?", sessionID).Order("seq asc, id asc").Find(&messages)
 
 	if result.Error != nil {
-		logger.Log.Error("database find error", zap.Error(result.Error))
+		logger.FromContext(ctx).Error("database find error", zap.Error(result.Error))
 		return []ChatMessage{}, result.Error
-	} else {
-		return messages, nil
 	}
+	return messages, r.open(ctx, messages)
+}
+
+func (r *repository) open(ctx context.Context, messages []ChatMessage) error {
+	if r.keys == nil {
+		return nil
+	}
+	for i := range messages {
+		if err := openMessage(ctx, r.keys, &messages[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repository) Flagged(ctx context.Context, beforeID int, limit int) ([]ChatMessage, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	q := r.db.WithContext(ctx).Where("moderation <> ''")
+	if beforeID > 0 {
+		q = q.Where("id < ?", beforeID)
+	}
+	var messages []ChatMessage
+	if err := q.Order("id desc").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, r.open(ctx, messages)
+}
 
+func (r *repository) UserMessages(ctx context.Context, userID string) ([]ChatMessage, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var messages []ChatMessage
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("session_id, seq, id").Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, r.open(ctx, messages)
+}
+
+func (r *repository) EraseUser(ctx context.Context, userID string) (Erasure, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var erasure Erasure
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// cevaplar da prompt'un UserID'sini taşır; başka kullanıcıların aynı
+		// session'daki turn'lerine dokunulmaz
+		err := tx.Model(&ChatMessage{}).Where("user_id = ?", userID).
+			Distinct("session_id").Count(&erasure.Sessions).Error
+		if err != nil {
+			return err
+		}
+		res := tx.Where("user_id = ?", userID).Delete(&ChatMessage{})
+		if res.Error != nil {
+			return res.Error
+		}
+		erasure.Messages = res.RowsAffected
+		// yayınlanmış olsa da payload'larda mesaj metni var
+		res = tx.Where("user_id = ?", userID).Delete(&events.Record{})
+		erasure.Events = res.RowsAffected
+		return res.Error
+	})
+	return erasure, err
+}
+
+func (r *repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.timeout)
 }
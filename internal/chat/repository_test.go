@@ -0,0 +1,289 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"myapp/internal/events"
+	"myapp/internal/migrations"
+	"myapp/pkg/database"
+	"myapp/pkg/migrate"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// testDialects repository conformance testlerinin çalıştığı veritabanlarıdır.
+// SQLite her zaman in-memory çalışır; MySQL ve Postgres sadece DSN verildiyse:
+//
+//	TEST_MYSQL_DSN="chat:pw@tcp(localhost:3306)/chat_test?parseTime=true"
+//	TEST_POSTGRES_DSN="postgres://chat:pw@localhost:5432/chat_test"
+//
+// Test veritabanlarındaki tablolar her testte silinip yeniden oluşturulur.
+var testDialects = []struct {
+	driver string
+	dsnEnv string
+}{
+	{database.SQLite, ""},
+	{database.MySQL, "TEST_MYSQL_DSN"},
+	{database.Postgres, "TEST_POSTGRES_DSN"},
+}
+
+// forEachDialect testi her veritabanında boş tablolarla ayrı bir subtest olarak çalıştırır.
+func forEachDialect(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	for _, d := range testDialects {
+		t.Run(d.driver, func(t *testing.T) {
+			dsn := ":memory:"
+			if d.dsnEnv != "" {
+				dsn = os.Getenv(d.dsnEnv)
+				if dsn == "" {
+					t.Skipf("%s is not set", d.dsnEnv)
+				}
+			}
+			test(t, newTestDB(t, d.driver, dsn))
+		})
+	}
+}
+
+func newTestDB(t *testing.T, driver, dsn string) *gorm.DB {
+	t.Helper()
+	db, err := database.Connect(database.Config{Driver: driver, DSN: dsn})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.Migrator().DropTable(&ChatMessage{}, &events.Record{}, &migrate.Record{}))
+	m, err := migrations.New(db, 0)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func TestRepository_SaveAndFind(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		//arrange
+		ctx := context.Background()
+		repo := NewRepository(db, time.Second)
+		// Seq sırası ID sırasından farklı olsa da Find Seq'e göre döner
+		second := &ChatMessage{SessionID: "s1", Kind: LLMOutput, Message: "selam", Seq: 2}
+		first := &ChatMessage{SessionID: "s1", Kind: UserPrompt, Message: "merhaba", Seq: 1}
+
+		//act
+		require.NoError(t, repo.Save(ctx, second))
+		require.NoError(t, repo.Save(ctx, first, events.New(events.MessageSaved, "s1", first)))
+		require.NoError(t, repo.Save(ctx, &ChatMessage{SessionID: "s2", Message: "başka", Seq: 1}))
+		messages, err := repo.Find(ctx, "s1")
+
+		//assert
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		assert.Equal(t, "merhaba", messages[0].Message)
+		assert.Equal(t, "selam", messages[1].Message)
+		assert.NotZero(t, first.ID)
+
+		var records []events.Record
+		require.NoError(t, db.Find(&records).Error)
+		require.Len(t, records, 1)
+		var payload ChatMessage
+		require.NoError(t, json.Unmarshal(records[0].Payload, &payload))
+		assert.Equal(t, first.ID, payload.ID, "payload is written with the assigned id")
+	})
+}
+
+func TestRepository_SaveRollsBackOnEventFailure(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewRepository(db, time.Second)
+		ev := events.New(events.MessageSaved, "s1", nil)
+
+		// aynı event id iki kez yazılamaz; ikinci mesaj da kaydedilmemeli
+		require.NoError(t, repo.Save(ctx, &ChatMessage{SessionID: "s1", Seq: 1}, ev))
+		err := repo.Save(ctx, &ChatMessage{SessionID: "s1", Seq: 2}, ev)
+
+		assert.Error(t, err)
+		messages, _ := repo.Find(ctx, "s1")
+		assert.Len(t, messages, 1)
+	})
+}
+
+func TestRepository_DuplicateSeq(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewRepository(db, time.Second)
+		require.NoError(t, repo.Save(ctx, &ChatMessage{SessionID: "s1", Seq: 1}))
+
+		err := repo.Save(ctx, &ChatMessage{SessionID: "s1", Seq: 1})
+
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	})
+}
+
+func TestRepository_Flagged(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		repo := NewRepository(db, time.Second)
+		for i, moderation := range []string{"violence", "", "self-harm", "illicit"} {
+			require.NoError(t, repo.Save(ctx, &ChatMessage{SessionID: "s1", Seq: int64(i + 1), Moderation: moderation}))
+		}
+
+		page, err := repo.Flagged(ctx, 0, 2)
+		require.NoError(t, err)
+		require.Len(t, page, 2)
+		assert.Equal(t, "illicit", page[0].Moderation)
+		assert.Equal(t, "self-harm", page[1].Moderation)
+
+		page, err = repo.Flagged(ctx, page[1].ID, 2)
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, "violence", page[0].Moderation)
+	})
+}
+
+func TestRepository_UserMessagesAndErase(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		//arrange
+		ctx := context.Background()
+		repo := NewRepository(db, time.Second)
+		save := func(session, user string, seq int64) {
+			msg := &ChatMessage{SessionID: session, UserID: user, Message: session, Seq: seq}
+			require.NoError(t, repo.Save(ctx, msg, events.New(events.MessageSaved, session, msg)))
+		}
+		save("s1", "u1", 1)
+		save("s1", "u1", 2) // cevap prompt'un UserID'sini taşır
+		save("s1", "u2", 3) // aynı session'da başka kullanıcının turn'ü
+		save("s2", "u1", 1)
+		save("s3", "u2", 1)
+
+		//act
+		exported, err := repo.UserMessages(ctx, "u1")
+		require.NoError(t, err)
+		erasure, err := repo.EraseUser(ctx, "u1")
+
+		//assert
+		require.NoError(t, err)
+		assert.Len(t, exported, 3)
+		assert.Equal(t, Erasure{Sessions: 2, Messages: 3, Events: 3}, erasure)
+		left, _ := repo.Find(ctx, "s1")
+		require.Len(t, left, 1, "other users' turns survive")
+		assert.Equal(t, "u2", left[0].UserID)
+		left, _ = repo.Find(ctx, "s3")
+		assert.Len(t, left, 1)
+		var records int64
+		db.Model(&events.Record{}).Count(&records)
+		assert.Equal(t, int64(2), records)
+
+		erasure, err = repo.EraseUser(ctx, "u1")
+		require.NoError(t, err)
+		assert.Equal(t, Erasure{}, erasure, "erasure is idempotent")
+	})
+}
+
+func TestRepository_Encryption(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		//arrange
+		ctx := context.Background()
+		keys := testKeyring(t)
+		repo := NewRepository(db, time.Second, WithEncryption(keys))
+		msg := &ChatMessage{SessionID: "s1", TenantID: "acme", Message: "gizli", Seq: 1}
+
+		//act
+		require.NoError(t, repo.Save(ctx, msg, events.New(events.MessageSaved, "s1", msg)))
+		messages, err := repo.Find(ctx, "s1")
+
+		//assert
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "gizli", messages[0].Message)
+		assert.Equal(t, "gizli", msg.Message, "caller's message stays plaintext")
+
+		var row ChatMessage
+		require.NoError(t, db.First(&row, msg.ID).Error)
+		assert.NotEqual(t, "gizli", row.Message)
+		assert.Equal(t, 1, row.KeyVersion)
+
+		var record events.Record
+		require.NoError(t, db.First(&record).Error)
+		assert.NotContains(t, string(record.Payload), "gizli", "message text must not reach the outbox")
+		var payload ChatMessage
+		require.NoError(t, json.Unmarshal(record.Payload, &payload))
+		assert.Equal(t, msg.ID, payload.ID)
+	})
+}
+
+func TestReencryptor_EncryptsLegacyAndRotatedRows(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		//arrange
+		ctx := context.Background()
+		keys := testKeyring(t)
+		plain := NewRepository(db, time.Second)
+		encrypted := NewRepository(db, time.Second, WithEncryption(keys))
+		require.NoError(t, plain.Save(ctx, &ChatMessage{SessionID: "s1", TenantID: "acme", Message: "eski", Seq: 1}))
+		require.NoError(t, encrypted.Save(ctx, &ChatMessage{SessionID: "s1", TenantID: "acme", Message: "yeni", Seq: 2}))
+		r := NewReencryptor(db, keys, ReencryptConfig{BatchSize: 1})
+
+		//act
+		n, err := r.RunOnce(ctx)
+
+		//assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assertKeyVersions(t, db, 1, 1)
+
+		_, err = keys.Rotate(ctx, "acme")
+		require.NoError(t, err)
+		n, err = r.RunOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assertKeyVersions(t, db, 2, 2)
+
+		messages, err := encrypted.Find(ctx, "s1")
+		require.NoError(t, err)
+		assert.Equal(t, "eski", messages[0].Message)
+		assert.Equal(t, "yeni", messages[1].Message)
+	})
+}
+
+type fakeTable struct {
+	plain    []string
+	versions map[string]int
+}
+
+func (f *fakeTable) PlainTenants(context.Context) ([]string, error) {
+	return f.plain, nil
+}
+
+func (f *fakeTable) Reencrypt(_ context.Context, tenantID string, version int, _ []byte, _ int) (int, error) {
+	f.versions[tenantID] = version
+	return 1, nil
+}
+
+func TestReencryptor_CoversExtraTables(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	db := newTestDB(t, database.SQLite, ":memory:")
+	keys := testKeyring(t)
+	// chat_messages'ta satırı olmayan tenant da taranır
+	table := &fakeTable{plain: []string{"acme"}, versions: map[string]int{}}
+	r := NewReencryptor(db, keys, ReencryptConfig{BatchSize: 10}, table)
+
+	//act
+	n, err := r.RunOnce(ctx)
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, map[string]int{"acme": 1}, table.versions)
+}
+
+func assertKeyVersions(t *testing.T, db *gorm.DB, want ...int) {
+	t.Helper()
+	var versions []int
+	require.NoError(t, db.Model(&ChatMessage{}).Order("id").Pluck("key_version", &versions).Error)
+	assert.Equal(t, want, versions)
+}
@@ -0,0 +1,200 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"myapp/pkg/logger"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+	"go.uber.org/zap"
+)
+
+// RetryPolicy geçici LLM hataları (429, 5xx, ağ hataları) için tekrar deneme ayarlarıdır.
+type RetryPolicy struct {
+	MaxAttempts int           // ilk deneme dahil
+	BaseDelay   time.Duration // ilk bekleme, her denemede ikiye katlanır
+	MaxDelay    time.Duration // tek bekleme için üst sınır
+	Budget      time.Duration // tüm denemeler için toplam süre; 0 ise sınırsız
+}
+
+// Split policy'yi failover zincirindeki n provider arasında paylaştırır: her
+// provider bütçenin ve denemelerin n'de birini alır (en az bir deneme). Yoksa
+// birincil provider bütün bütçeyi harcar, yedeklere süre kalmaz.
+func (p RetryPolicy) Split(n int) RetryPolicy {
+	if n <= 1 {
+		return p
+	}
+	p.MaxAttempts = max(1, (p.MaxAttempts+n-1)/n)
+	p.Budget /= time.Duration(n)
+	return p
+}
+
+type retryingClient struct {
+	next   Client
+	policy RetryPolicy
+	// jitter testlerde beklemeyi deterministik yapmak için değiştirilir
+	jitter func(time.Duration) time.Duration
+}
+
+// NewRetryingClient next'i exponential backoff + full jitter ile tekrar deneyen
+// bir Client ile sarar. Retry-After header'ı varsa ona uyulur.
+func NewRetryingClient(next Client, policy RetryPolicy) Client {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &retryingClient{
+		next:   next,
+		policy: policy,
+		jitter: fullJitter,
+	}
+}
+
+func (c *retryingClient) GetCompletion(ctx context.Context, message string, messages []ChatMessage) (Completion, error) {
+	log := logger.FromContext(ctx)
+	var deadline time.Time
+	// denemeler bütçeyi aşmasın diye deadline'lı context ile yapılır; asılı
+	// kalan bir deneme de bütçe dolunca kesilir
+	attemptCtx := ctx
+	if c.policy.Budget > 0 {
+		deadline = time.Now().Add(c.policy.Budget)
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		completion, err := c.next.GetCompletion(attemptCtx, message, messages)
+		if err == nil {
+			completion.Attempts = attempt
+			if attempt > 1 {
+				log.Info("LLM call succeeded after retry", zap.Int("attempts", attempt))
+			}
+			return completion, nil
+		}
+
+		retryable, retryAfter := classifyLLMError(ctx, err)
+		if !retryable {
+			return Completion{}, attemptsError(attempt, err)
+		}
+		if attempt >= c.policy.MaxAttempts {
+			log.Warn("LLM retries exhausted", zap.Int("attempts", attempt), zap.Error(err))
+			return Completion{}, attemptsError(attempt, err)
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			log.Warn("LLM retry budget exhausted", zap.Int("attempts", attempt), zap.Error(err))
+			return Completion{}, attemptsError(attempt, err)
+		}
+
+		log.Warn("LLM call failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", wait),
+			zap.Error(err))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Completion{}, attemptsError(attempt, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *retryingClient) backoff(attempt int) time.Duration {
+	d := c.policy.BaseDelay << (attempt - 1)
+	if d <= 0 || (c.policy.MaxDelay > 0 && d > c.policy.MaxDelay) {
+		d = c.policy.MaxDelay
+	}
+	return c.jitter(d)
+}
+
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// RetryError son hatayı ve kaç deneme yapıldığını taşır.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+func attemptsError(attempts int, err error) error {
+	return &RetryError{Attempts: attempts, Err: err}
+}
+
+// classifyLLMError hatanın tekrar denenip denenmeyeceğini ve sunucunun
+// istediği bekleme süresini döner.
+func classifyLLMError(ctx context.Context, err error) (bool, time.Duration) {
+	// çağıranın context'i bittiyse tekrar denemenin anlamı yok; ama tek
+	// denemenin kendi timeout'u dolduysa bu geçici bir hatadır
+	if ctx.Err() != nil {
+		return false, 0
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true, 0
+	}
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode == http.StatusConflict,
+			apiErr.StatusCode >= http.StatusInternalServerError:
+			return true, retryAfter(apiErr.Response)
+		}
+		return false, 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true, 0
+	}
+	return false, 0
+}
+
+// retryAfter OpenAI'ın retry-after-ms ve standart Retry-After (saniye ya da
+// HTTP tarihi) header'larını okur.
+func retryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(res.Header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
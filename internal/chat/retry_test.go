@@ -0,0 +1,210 @@
+package chat
+
+import (
+	"context"
+	"io"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2/option"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+// scriptedResponse sahte OpenAI sunucusunun sıradaki cevabıdır.
+type scriptedResponse struct {
+	status int
+	header map[string]string
+}
+
+// newScriptedClient verilen sırayla cevap veren bir sunucuya bağlı, retry'lı
+// bir client döner. Script bitince sunucu hep başarılı cevap verir.
+func newScriptedClient(t *testing.T, policy RetryPolicy, script ...scriptedResponse) (Client, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		n := int(atomic.AddInt32(&hits, 1))
+		w.Header().Set("Content-Type", "application/json")
+		if n <= len(script) {
+			step := script[n-1]
+			for k, v := range step.header {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(step.status)
+			_, _ = io.WriteString(w, `{"error":{"message":"scripted failure","type":"server_error"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, completionJSON)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewRetryingClient(NewClient("test-key", 0, option.WithBaseURL(srv.URL)), policy).(*retryingClient)
+	c.jitter = func(d time.Duration) time.Duration { return d }
+	return c, &hits
+}
+
+func TestRetry_TransientErrorsThenSuccess(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	c, hits := newScriptedClient(t,
+		RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+		scriptedResponse{status: http.StatusServiceUnavailable},
+		scriptedResponse{status: http.StatusInternalServerError},
+	)
+
+	//act
+	completion, err := c.GetCompletion(context.Background(), "merhaba", nil)
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, 3, completion.Attempts)
+	assert.Equal(t, "merhaba, size nasıl yardımcı olabilirim?", completion.Message)
+	assert.Equal(t, int32(3), atomic.LoadInt32(hits))
+}
+
+func TestRetry_ClientErrorIsNotRetried(t *testing.T) {
+	logger.Log = zap.NewNop()
+	c, hits := newScriptedClient(t,
+		RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond},
+		scriptedResponse{status: http.StatusBadRequest},
+	)
+
+	_, err := c.GetCompletion(context.Background(), "merhaba", nil)
+
+	var retryErr *RetryError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, 1, retryErr.Attempts)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+}
+
+func TestRetry_HonorsRetryAfter(t *testing.T) {
+	logger.Log = zap.NewNop()
+	c, _ := newScriptedClient(t,
+		RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+		scriptedResponse{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After-Ms": "150"}},
+	)
+
+	start := time.Now()
+	completion, err := c.GetCompletion(context.Background(), "merhaba", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, completion.Attempts)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestRetry_StopsAtMaxAttempts(t *testing.T) {
+	logger.Log = zap.NewNop()
+	c, hits := newScriptedClient(t,
+		RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		scriptedResponse{status: http.StatusBadGateway},
+		scriptedResponse{status: http.StatusBadGateway},
+		scriptedResponse{status: http.StatusBadGateway},
+		scriptedResponse{status: http.StatusBadGateway},
+	)
+
+	_, err := c.GetCompletion(context.Background(), "merhaba", nil)
+
+	var retryErr *RetryError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, 3, retryErr.Attempts)
+	assert.Equal(t, int32(3), atomic.LoadInt32(hits))
+}
+
+func TestRetryPolicy_Split(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, Budget: 30 * time.Second}
+
+	assert.Equal(t, policy, policy.Split(1))
+	assert.Equal(t, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Budget: 15 * time.Second}, policy.Split(2))
+	assert.Equal(t, 1, RetryPolicy{MaxAttempts: 1}.Split(3).MaxAttempts)
+}
+
+func TestRetry_SplitPolicyLeavesAttemptsForFallback(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond}.Split(2)
+	down := scriptedResponse{status: http.StatusServiceUnavailable}
+	primary, primaryHits := newScriptedClient(t, policy, down, down, down, down)
+	fallback, fallbackHits := newScriptedClient(t, policy)
+	f := NewFailoverClient(newTestProvider("primary", primary), newTestProvider("fallback", fallback))
+
+	//act
+	completion, err := f.GetCompletion(context.Background(), "merhaba", nil)
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, completion.Attempts)
+	assert.Equal(t, int32(2), atomic.LoadInt32(primaryHits), "primary gets half of the attempts")
+	assert.Equal(t, int32(1), atomic.LoadInt32(fallbackHits))
+}
+
+func TestRetry_StopsWhenBudgetExhausted(t *testing.T) {
+	logger.Log = zap.NewNop()
+	// sunucu 10sn beklememizi istiyor ama bütçe 100ms
+	c, hits := newScriptedClient(t,
+		RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, Budget: 100 * time.Millisecond},
+		scriptedResponse{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "10"}},
+	)
+
+	start := time.Now()
+	_, err := c.GetCompletion(context.Background(), "merhaba", nil)
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+}
+
+func TestRetry_BudgetBoundsHungAttempt(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	next := NewMockClient(ctrl)
+	next.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ string, _ []ChatMessage) (Completion, error) {
+			<-ctx.Done()
+			return Completion{}, ctx.Err()
+		})
+	c := NewRetryingClient(next, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Budget: 50 * time.Millisecond})
+
+	//act
+	start := time.Now()
+	_, err := c.GetCompletion(context.Background(), "merhaba", nil)
+
+	//assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRetry_ContextCanceledDuringBackoff(t *testing.T) {
+	logger.Log = zap.NewNop()
+	c, hits := newScriptedClient(t,
+		RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second},
+		scriptedResponse{status: http.StatusServiceUnavailable},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.GetCompletion(ctx, "merhaba", nil)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+}
+
+func TestRetryAfter_ParsesSecondsAndDates(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	res.Header.Set("Retry-After", "2")
+	assert.Equal(t, 2*time.Second, retryAfter(res))
+
+	res.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, time.Minute, retryAfter(res), float64(2*time.Second))
+
+	res.Header.Set("Retry-After", "bozuk")
+	assert.Equal(t, time.Duration(0), retryAfter(res))
+}
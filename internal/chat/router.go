@@ -0,0 +1,309 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// RouterConfig model routing kurallarının JSON karşılığıdır. Kurallar sırayla
+// denenir, ilk eşleşen kazanır; hiçbiri eşleşmezse DefaultModel kullanılır.
+type RouterConfig struct {
+	DefaultModel string            `json:"defaultModel"`
+	Rules        []RouteRule       `json:"rules"`
+	Classifier   *ClassifierConfig `json:"classifier,omitempty"`
+}
+
+type RouteRule struct {
+	Name  string         `json:"name"`
+	Model string         `json:"model"`
+	When  RouteCondition `json:"when"`
+}
+
+// RouteCondition'daki boş alanlar her şeyle eşleşir; dolu alanların hepsi sağlanmalıdır.
+type RouteCondition struct {
+	MinLength int      `json:"minLength,omitempty"` // karakter
+	MaxLength int      `json:"maxLength,omitempty"`
+	Languages []string `json:"languages,omitempty"` // "tr", "en", "unknown"
+	HasCode   *bool    `json:"hasCode,omitempty"`
+	Personas  []string `json:"personas,omitempty"`
+	Tiers     []string `json:"tiers,omitempty"`
+	Classes   []string `json:"classes,omitempty"` // classifier'ın döndüğü etiket
+}
+
+// ClassifierConfig ucuz bir modelle prompt'u etiketleyen classifier ayarıdır.
+type ClassifierConfig struct {
+	Model  string   `json:"model"`
+	Labels []string `json:"labels"`
+}
+
+// LoadRouterConfig JSON dosyasından routing kurallarını okur.
+func LoadRouterConfig(path string) (RouterConfig, error) {
+	var cfg RouterConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, rule := range cfg.Rules {
+		if rule.Model == "" {
+			return cfg, fmt.Errorf("rule %d (%s) has no model", i, rule.Name)
+		}
+		if len(rule.When.Classes) > 0 && cfg.Classifier == nil {
+			return cfg, fmt.Errorf("rule %s uses classes but no classifier is configured", rule.Name)
+		}
+	}
+	return cfg, nil
+}
+
+// Classifier prompt'a RouterConfig.Classifier.Labels'tan bir etiket verir.
+type Classifier interface {
+	Classify(ctx context.Context, message string) (string, error)
+}
+
+// PromptFeatures routing'in baktığı, prompt'tan ve kimlikten çıkarılan sinyallerdir.
+type PromptFeatures struct {
+	Length   int
+	Language string
+	HasCode  bool
+	Persona  string
+	Tier     string
+	Class    string
+}
+
+// RouteDecision hangi kuralın hangi modeli seçtiğidir.
+type RouteDecision struct {
+	Rule  string
+	Model string
+}
+
+type router struct {
+	next       Client
+	cfg        RouterConfig
+	classifier Classifier
+}
+
+// NewRouter her çağrıda kurallara göre model seçip next'e context üzerinden
+// iletir. classifier nil olabilir; sadece Classes kullanan ve diğer koşulları
+// tutan bir kural varsa çağrılır.
+func NewRouter(next Client, cfg RouterConfig, classifier Classifier) Client {
+	// classifier etiketleri küçük harfle döner
+	rules := make([]RouteRule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		rules[i] = rule
+		rules[i].When.Classes = lowerAll(rule.When.Classes)
+	}
+	cfg.Rules = rules
+	return &router{
+		next:       next,
+		cfg:        cfg,
+		classifier: classifier,
+	}
+}
+
+func (r *router) GetCompletion(ctx context.Context, message string, messages []ChatMessage) (Completion, error) {
+	decision, features := r.route(ctx, message)
+	logger.FromContext(ctx).Info("model routed",
+		zap.String("rule", decision.Rule),
+		zap.String("model", decision.Model),
+		zap.Int("length", features.Length),
+		zap.String("language", features.Language),
+		zap.Bool("hasCode", features.HasCode),
+		zap.String("tier", features.Tier),
+		zap.String("persona", features.Persona),
+		zap.String("class", features.Class))
+
+	if decision.Model != "" {
+		ctx = WithModel(ctx, decision.Model)
+	}
+	completion, err := r.next.GetCompletion(ctx, message, messages)
+	if err != nil {
+		return completion, err
+	}
+	completion.Route = decision.Rule
+	return completion, nil
+}
+
+func (r *router) route(ctx context.Context, message string) (RouteDecision, PromptFeatures) {
+	id := identity.FromContext(ctx)
+	features := PromptFeatures{
+		Length:   utf8.RuneCountInString(message),
+		Language: DetectLanguage(message),
+		HasCode:  HasCode(message),
+		Persona:  id.Persona,
+		Tier:     id.Tier,
+	}
+	classified := false
+
+	for _, rule := range r.cfg.Rules {
+		// classifier bir LLM çağrısıdır; kuralın ucuz koşulları tutmuyorsa sorulmaz
+		if !rule.When.matchesSignals(features) {
+			continue
+		}
+		if len(rule.When.Classes) > 0 && !classified {
+			features.Class = r.classify(ctx, message)
+			classified = true
+		}
+		if rule.When.matches(features) {
+			return RouteDecision{Rule: rule.Name, Model: rule.Model}, features
+		}
+	}
+	return RouteDecision{Rule: "default", Model: r.cfg.DefaultModel}, features
+}
+
+func (r *router) classify(ctx context.Context, message string) string {
+	if r.classifier == nil {
+		return ""
+	}
+	label, err := r.classifier.Classify(ctx, message)
+	if err != nil {
+		// classifier opsiyonel bir sinyal, hata routing'i durdurmasın
+		logger.FromContext(ctx).Warn("prompt classification failed", zap.Error(err))
+		return ""
+	}
+	return label
+}
+
+func (w RouteCondition) matches(f PromptFeatures) bool {
+	if !w.matchesSignals(f) {
+		return false
+	}
+	return len(w.Classes) == 0 || slices.Contains(w.Classes, f.Class)
+}
+
+// matchesSignals Classes dışındaki, classifier gerektirmeyen koşullara bakar.
+func (w RouteCondition) matchesSignals(f PromptFeatures) bool {
+	if w.MinLength > 0 && f.Length < w.MinLength {
+		return false
+	}
+	if w.MaxLength > 0 && f.Length > w.MaxLength {
+		return false
+	}
+	if len(w.Languages) > 0 && !slices.Contains(w.Languages, f.Language) {
+		return false
+	}
+	if w.HasCode != nil && *w.HasCode != f.HasCode {
+		return false
+	}
+	if len(w.Personas) > 0 && !slices.Contains(w.Personas, f.Persona) {
+		return false
+	}
+	if len(w.Tiers) > 0 && !slices.Contains(w.Tiers, f.Tier) {
+		return false
+	}
+	return true
+}
+
+func lowerAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
+
+type modelKey struct{}
+
+// WithModel provider client'ın varsayılan modelini bu çağrı için değiştirir.
+// Boş model override'ı kaldırır.
+func WithModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelKey{}, model)
+}
+
+// ModelFromContext WithModel ile verilen override'ı döner; yoksa boş.
+func ModelFromContext(ctx context.Context) string {
+	model, _ := ctx.Value(modelKey{}).(string)
+	return model
+}
+
+var turkishWords = map[string]bool{
+	"ve": true, "bir": true, "bu": true, "ne": true, "nasıl": true, "için": true,
+	"mi": true, "mı": true, "ile": true, "de": true, "da": true, "ben": true,
+	"sen": true, "merhaba": true, "neden": true, "nedir": true, "var": true,
+}
+
+var englishWords = map[string]bool{
+	"the": true, "and": true, "is": true, "what": true, "how": true, "you": true,
+	"to": true, "of": true, "in": true, "for": true, "why": true, "can": true,
+	"hello": true, "please": true, "this": true, "are": true,
+}
+
+// DetectLanguage Türkçe/İngilizce ayrımı için basit bir sezgidir: Türkçeye
+// özgü harfler ve sık kullanılan kelimeler sayılır.
+func DetectLanguage(text string) string {
+	lower := strings.ToLower(text)
+	tr, en := 0, 0
+	for _, r := range lower {
+		if strings.ContainsRune("çğıöşü", r) {
+			tr++
+		}
+	}
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if turkishWords[word] {
+			tr++
+		}
+		if englishWords[word] {
+			en++
+		}
+	}
+	switch {
+	case tr == 0 && en == 0:
+		return "unknown"
+	case tr >= en:
+		return "tr"
+	default:
+		return "en"
+	}
+}
+
+var codePattern = regexp.MustCompile("(?m)```|^\\s*(func|def|class|import|package|public|private|#include|SELECT|const|let|var)\\s|[;{}]\\s*$|=>|:=")
+
+// HasCode prompt'ta kod parçası olup olmadığını tahmin eder.
+func HasCode(text string) bool {
+	return codePattern.MatchString(text)
+}
+
+type llmClassifier struct {
+	client Client
+	labels []string
+}
+
+// NewLLMClassifier prompt'u ucuz bir modele etiketleten Classifier döner.
+// client'ın modeli classifier modeli olmalıdır (ör. gpt-4o-mini).
+func NewLLMClassifier(client Client, labels []string) Classifier {
+	return &llmClassifier{
+		client: client,
+		labels: lowerAll(labels),
+	}
+}
+
+func (c *llmClassifier) Classify(ctx context.Context, message string) (string, error) {
+	prompt := fmt.Sprintf("Classify the user message below into exactly one of these labels: %s. "+
+		"Answer with the label only.\n\nMessage:\n%s", strings.Join(c.labels, ", "), message)
+	completion, err := c.client.GetCompletion(ctx, prompt, nil)
+	if err != nil {
+		return "", err
+	}
+	label := strings.ToLower(strings.Trim(strings.TrimSpace(completion.Message), ".\"'"))
+	if !slices.Contains(c.labels, label) {
+		return "", fmt.Errorf("classifier returned unknown label %q", label)
+	}
+	return label, nil
+}
@@ -0,0 +1,200 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+type fakeClassifier struct {
+	label string
+	err   error
+	calls int
+}
+
+func (f *fakeClassifier) Classify(context.Context, string) (string, error) {
+	f.calls++
+	return f.label, f.err
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+var testRoutes = RouterConfig{
+	DefaultModel: "gpt-4o",
+	Rules: []RouteRule{
+		{Name: "enterprise", Model: "gpt-4o", When: RouteCondition{Tiers: []string{"enterprise"}}},
+		{Name: "code", Model: "code-model", When: RouteCondition{HasCode: boolPtr(true)}},
+		{Name: "tutor", Model: "tutor-model", When: RouteCondition{Personas: []string{"tutor"}}},
+		{Name: "long", Model: "long-model", When: RouteCondition{MinLength: 500}},
+		{Name: "small-talk", Model: "gpt-4o-mini", When: RouteCondition{Classes: []string{"small_talk"}}},
+		{Name: "english", Model: "en-model", When: RouteCondition{Languages: []string{"en"}}},
+	},
+}
+
+func TestRouter_PicksModelByRule(t *testing.T) {
+	cases := []struct {
+		name    string
+		id      identity.Identity
+		message string
+		label   string
+		rule    string
+		model   string
+	}{
+		{"tier", identity.Identity{Tier: "enterprise"}, "func main() {}", "", "enterprise", "gpt-4o"},
+		{"code", identity.Identity{}, "bu neden çalışmıyor?\n```go\nx := 1\n```", "", "code", "code-model"},
+		{"persona", identity.Identity{Persona: "tutor"}, "türev nedir", "", "tutor", "tutor-model"},
+		{"length", identity.Identity{}, string(make([]rune, 600)), "", "long", "long-model"},
+		{"classifier", identity.Identity{}, "naber nasılsın", "small_talk", "small-talk", "gpt-4o-mini"},
+		{"language", identity.Identity{}, "what is the capital of France?", "question", "english", "en-model"},
+		{"default", identity.Identity{}, "İstanbul'un nüfusu nedir?", "question", "default", "gpt-4o"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			//arrange
+			logger.Log = zap.NewNop()
+			ctrl := gomock.NewController(t)
+			next := NewMockClient(ctrl)
+			r := NewRouter(next, testRoutes, &fakeClassifier{label: tc.label})
+			ctx := identity.WithContext(context.Background(), tc.id)
+
+			next.EXPECT().GetCompletion(gomock.Any(), tc.message, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, _ string, _ []ChatMessage) (Completion, error) {
+					// seçilen model client'a context ile ulaşmalı
+					assert.Equal(t, tc.model, ModelFromContext(ctx))
+					return Completion{Message: "ok", Model: tc.model}, nil
+				}).Times(1)
+
+			//act
+			completion, err := r.GetCompletion(ctx, tc.message, nil)
+
+			//assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.rule, completion.Route)
+		})
+	}
+}
+
+func TestRouter_ClassifierOnlyCalledWhenNeeded(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	next := NewMockClient(ctrl)
+	classifier := &fakeClassifier{label: "small_talk"}
+	r := NewRouter(next, testRoutes, classifier)
+	next.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).Return(Completion{}, nil).Times(1)
+
+	// kod kuralı classifier kuralından önce eşleşir
+	_, err := r.GetCompletion(context.Background(), "```\nfoo\n```", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, classifier.calls)
+}
+
+func TestRouter_ClassifierErrorFallsThrough(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	next := NewMockClient(ctrl)
+	r := NewRouter(next, testRoutes, &fakeClassifier{err: errors.New("timeout")})
+	next.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).Return(Completion{}, nil).Times(1)
+
+	completion, err := r.GetCompletion(context.Background(), "naber nasılsın", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "default", completion.Route)
+}
+
+func TestRouter_ClassifierSkippedWhenOtherConditionsFail(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	next := NewMockClient(ctrl)
+	classifier := &fakeClassifier{label: "billing"}
+	routes := RouterConfig{
+		DefaultModel: "gpt-4o",
+		Rules: []RouteRule{
+			{Name: "enterprise-billing", Model: "billing-model", When: RouteCondition{Tiers: []string{"enterprise"}, Classes: []string{"Billing"}}},
+		},
+	}
+	r := NewRouter(next, routes, classifier)
+	next.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).Return(Completion{}, nil).Times(2)
+
+	//act
+	free, err := r.GetCompletion(identity.WithContext(context.Background(), identity.Identity{Tier: "free"}), "faturam nerede", nil)
+	assert.NoError(t, err)
+	callsForFree := classifier.calls
+	enterprise, err := r.GetCompletion(identity.WithContext(context.Background(), identity.Identity{Tier: "enterprise"}), "faturam nerede", nil)
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, "default", free.Route)
+	assert.Equal(t, 0, callsForFree, "tier does not match, no classifier call")
+	assert.Equal(t, "enterprise-billing", enterprise.Route, "configured classes are matched case-insensitively")
+	assert.Equal(t, 1, classifier.calls)
+}
+
+func TestLLMClassifier_ParsesLabel(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	c := NewLLMClassifier(client, []string{"Small_Talk", "task"})
+
+	client.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Nil()).Return(Completion{Message: " Small_Talk.\n"}, nil)
+	client.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Nil()).Return(Completion{Message: "weather"}, nil)
+
+	label, err := c.Classify(context.Background(), "selam")
+	assert.NoError(t, err)
+	assert.Equal(t, "small_talk", label)
+
+	_, err = c.Classify(context.Background(), "selam")
+	assert.Error(t, err)
+}
+
+func TestLoadRouterConfig(t *testing.T) {
+	cfg, err := LoadRouterConfig("../../config/model_routes.example.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o", cfg.DefaultModel)
+	assert.NotEmpty(t, cfg.Rules)
+
+	bad := filepath.Join(t.TempDir(), "routes.json")
+	os.WriteFile(bad, []byte(`{"rules":[{"name":"x","model":"m","when":{"classes":["a"]}}]}`), 0o600)
+	_, err = LoadRouterConfig(bad)
+	assert.Error(t, err)
+}
+
+func TestDetectLanguageAndCode(t *testing.T) {
+	assert.Equal(t, "tr", DetectLanguage("Merhaba, bu kod neden çalışmıyor?"))
+	assert.Equal(t, "en", DetectLanguage("Hello, how are you?"))
+	assert.Equal(t, "unknown", DetectLanguage("12345"))
+
+	assert.True(t, HasCode("def foo():\n    return 1"))
+	assert.True(t, HasCode("x := compute()"))
+	assert.False(t, HasCode("İstanbul'da hava nasıl?"))
+}
+
+func TestGetCompletion_UsesModelOverride(t *testing.T) {
+	logger.Log = zap.NewNop()
+	var body struct {
+		Model string `json:"model"`
+	}
+	c := newTestClient(t, 0, func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, completionJSON)
+	})
+
+	_, err := c.GetCompletion(WithModel(context.Background(), "gpt-4o-mini"), "merhaba", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", body.Model)
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"context"
+	"myapp/pkg/database"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchConfig postgres'in metin arama konfigürasyonudur; search_vector
+// kolonu internal/migrations'da aynı konfigürasyonla üretilir.
+const searchConfig = "simple"
+
+func (r *repository) Search(ctx context.Context, userID, query string, limit int) ([]ChatMessage, error) {
+	// şifreli mesajlar veritabanında aranamaz
+	if r.keys != nil {
+		return nil, ErrSearchUnavailable
+	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var messages []ChatMessage
+	err := r.searchQuery(r.db.WithContext(ctx), userID, query).Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// searchQuery kullanıcının turn'lerinde query'yi içeren mesajları en
+// alakalıdan başlayarak seçer; eşit alakada yeni mesaj önce gelir.
+func (r *repository) searchQuery(db *gorm.DB, userID, query string) *gorm.DB {
+	q := db.Model(&ChatMessage{}).Where("user_id = ?", userID)
+	switch db.Dialector.Name() {
+	case database.Postgres:
+		tsquery := "plainto_tsquery('" + searchConfig + "', ?)"
+		return q.Where("search_vector @@ "+tsquery, query).
+			Order(byRank("ts_rank(search_vector, "+tsquery+")", query))
+	case database.MySQL:
+		match := "MATCH (message) AGAINST (? IN NATURAL LANGUAGE MODE)"
+		return q.Where(match, query).Order(byRank(match, query))
+	default:
+		return q.Where(`message LIKE ? ESCAPE '\'`, "%"+escapeLike(query)+"%").Order("id desc")
+	}
+}
+
+// byRank Order'a verilen ifade sıralamasıdır; gorm ifadeyi kolonlarla
+// birleştiremediği için id de ifadenin içindedir.
+func byRank(rank string, vars ...any) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{SQL: rank + " DESC, id DESC", Vars: vars}}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"errors"
+	"myapp/pkg/identity"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQueryLen  = 256
+)
+
+type SearchHandler interface {
+	Search(c echo.Context) error
+}
+
+type searchHandler struct {
+	repo Repository
+}
+
+// NewSearchHandler kullanıcının kendi mesajlarında arama yapar; kimlik
+// gateway'in X-User-ID header'ından alınır.
+func NewSearchHandler(repo Repository) SearchHandler {
+	return &searchHandler{
+		repo: repo,
+	}
+}
+
+// Search ?q= ile verilen metni içeren mesajları en alakalıdan başlayarak döner.
+func (h *searchHandler) Search(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := identity.FromContext(ctx)
+	if id.UserID == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "X-User-ID header is required")
+	}
+	query := strings.TrimSpace(c.QueryParam("q"))
+	if query == "" || len(query) > maxSearchQueryLen {
+		return ErrInvalidRequest
+	}
+	limit, err := queryInt(c, "limit", defaultSearchLimit)
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		return ErrInvalidRequest
+	}
+	messages, err := h.repo.Search(ctx, id.UserID, query, limit)
+	if errors.Is(err, ErrSearchUnavailable) {
+		return err
+	}
+	if err != nil {
+		return withPublicMessage(classify(ErrStorage, err), "unable to search messages")
+	}
+	return c.JSON(http.StatusOK, echo.Map{"messages": messages})
+}
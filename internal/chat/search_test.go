@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"context"
+	"myapp/pkg/database"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestRepository_Search(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		//arrange
+		ctx := context.Background()
+		repo := NewRepository(db, time.Second)
+		var seq int64
+		save := func(session, user, message string) {
+			seq++
+			require.NoError(t, repo.Save(ctx, &ChatMessage{SessionID: session, UserID: user, Message: message, Seq: seq}))
+		}
+		save("s1", "u1", "kargo nerede kaldı")
+		save("s1", "u1", "kargo yarın teslim edilecek") // cevap prompt'un UserID'sini taşır
+		save("s1", "u2", "kargo bende de gecikti")      // aynı session'da başka kullanıcı
+		save("s2", "u1", "fatura adresi yanlış")
+		save("s3", "u2", "kargo kayboldu")
+
+		//act
+		found, err := repo.Search(ctx, "u1", "kargo", 10)
+		require.NoError(t, err)
+		limited, err := repo.Search(ctx, "u1", "kargo", 1)
+		require.NoError(t, err)
+		none, err := repo.Search(ctx, "u1", "iade", 10)
+		require.NoError(t, err)
+
+		//assert
+		var texts []string
+		for _, m := range found {
+			texts = append(texts, m.Message)
+		}
+		assert.ElementsMatch(t, []string{"kargo nerede kaldı", "kargo yarın teslim edilecek"}, texts,
+			"other users' messages are not searched")
+		assert.Len(t, limited, 1)
+		assert.Empty(t, none)
+	})
+}
+
+func TestRepository_SearchEscapesLikeWildcards(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(newTestDB(t, database.SQLite, ":memory:"), time.Second)
+	require.NoError(t, repo.Save(ctx, &ChatMessage{SessionID: "s1", UserID: "u1", Message: "indirim 100 TL"}))
+
+	found, err := repo.Search(ctx, "u1", "%", 10)
+
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestRepository_SearchUnavailableWithEncryption(t *testing.T) {
+	repo := NewRepository(newTestDB(t, database.SQLite, ":memory:"), time.Second, WithEncryption(testKeyring(t)))
+
+	_, err := repo.Search(context.Background(), "u1", "kargo", 10)
+
+	assert.ErrorIs(t, err, ErrSearchUnavailable)
+}
+
+func TestSearchQuery_DialectSQL(t *testing.T) {
+	cases := map[string]struct {
+		dialector gorm.Dialector
+		want      []string
+	}{
+		"postgres": {
+			postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}),
+			[]string{
+				"search_vector @@ plainto_tsquery('simple', 'kargo')",
+				"ORDER BY ts_rank(search_vector, plainto_tsquery('simple', 'kargo')) DESC, id DESC",
+			},
+		},
+		"mysql": {
+			mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/db", SkipInitializeWithVersion: true}),
+			[]string{
+				"MATCH (message) AGAINST ('kargo' IN NATURAL LANGUAGE MODE)",
+				"ORDER BY MATCH (message) AGAINST ('kargo' IN NATURAL LANGUAGE MODE) DESC, id DESC",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			db, err := gorm.Open(tc.dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+			require.NoError(t, err)
+			repo := NewRepository(db, 0).(*repository)
+
+			var messages []ChatMessage
+			stmt := repo.searchQuery(db, "u1", "kargo").Find(&messages).Statement
+			sql := stmt.Dialector.Explain(stmt.SQL.String(), stmt.Vars...)
+
+			for _, want := range tc.want {
+				assert.Contains(t, sql, want)
+			}
+			assert.Contains(t, sql, "user_id = 'u1'")
+		})
+	}
+}
+
+func TestSearchHandler_Search(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(identity.Middleware())
+	e.GET("/v1/me/search", NewSearchHandler(repoMock).Search)
+	repoMock.EXPECT().Search(gomock.Any(), "u1", "kargo", 5).Return([]ChatMessage{{ID: 3, Message: "kargo nerede"}}, nil)
+	request := func(target, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if userID != "" {
+			req.Header.Set(identity.HeaderUserID, userID)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	//act
+	rec := request("/v1/me/search?q=+kargo+&limit=5", "u1")
+	anonymous := request("/v1/me/search?q=kargo", "")
+	empty := request("/v1/me/search?q=+", "u1")
+	tooMany := request("/v1/me/search?q=kargo&limit=1000", "u1")
+
+	//assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Message":"kargo nerede"`)
+	assert.Equal(t, http.StatusUnauthorized, anonymous.Code)
+	assert.Equal(t, http.StatusBadRequest, empty.Code)
+	assert.Equal(t, http.StatusBadRequest, tooMany.Code)
+}
+
+func TestSearchHandler_Unavailable(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(identity.Middleware())
+	e.GET("/v1/me/search", NewSearchHandler(repoMock).Search)
+	repoMock.EXPECT().Search(gomock.Any(), "u1", "kargo", defaultSearchLimit).Return(nil, ErrSearchUnavailable)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/me/search?q=kargo", nil)
+	req.Header.Set(identity.HeaderUserID, "u1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"search_unavailable"`)
+}
@@ -1,88 +1,562 @@
 package chat
 
 import (
+	"context"
+	"errors"
+	"myapp/internal/events"
+	"myapp/pkg/identity"
 	"myapp/pkg/logger"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type Service interface {
-	SendMessage(sessionID string, message string) (Chat, error)
-	FindHistory(sessionID string) ([]ChatMessage, error)
+	// SendMessage sessionID boşsa yeni bir session başlatır, doluysa session'ın
+	// var olmasını bekler; yoksa ErrSessionNotFound döner.
+	// ctx iptal edilirse (client bağlantıyı kapattı, deadline doldu) DB ve LLM çağrıları da iptal olur.
+	SendMessage(ctx context.Context, sessionID string, message string) (Chat, error)
+	// Resume shutdown'da yarıda kalan turn'ü (bkz. InterruptedTurnError) prompt'u
+	// tekrar kaydetmeden tamamlar. Turn bu arada cevaplanmışsa o cevap döner.
+	Resume(ctx context.Context, sessionID string, promptSeq int64) (Chat, error)
+	FindHistory(ctx context.Context, sessionID string) ([]ChatMessage, error)
+	// Drain yeni turn'leri ErrShuttingDown ile reddeder ve süren turn'lerin
+	// bitmesini bekler. ctx dolarsa kalan turn'ler iptal edilir, prompt'ları
+	// Interrupted mesajıyla kapatılır ve ctx'in hatası döner.
+	Drain(ctx context.Context) error
 }
 
 type service struct {
 	repo   Repository
 	client Client
+	locker SessionLocker
+
+	moderation ModerationConfig
+	guardrail  Guardrail
+
+	mu       sync.Mutex
+	draining bool
+	active   sync.WaitGroup
+	// abortCtx Drain'in süresi dolunca iptal edilir; süren turn'lerin context'i buna bağlıdır
+	abortCtx context.Context
+	abort    context.CancelFunc
+}
+
+// Option NewService'in opsiyonel bağımlılıklarını ayarlar.
+type Option func(*service)
+
+// WithLocker session kilidini değiştirir; birden fazla replica varsa
+// NewMySQLLocker gibi paylaşımlı bir kilit verilmelidir.
+func WithLocker(l SessionLocker) Option {
+	return func(s *service) {
+		s.locker = l
+	}
+}
+
+// WithModeration prompt'u LLM'e gitmeden, cevabı kaydedilmeden önce denetler.
+func WithModeration(cfg ModerationConfig) Option {
+	return func(s *service) {
+		if cfg.Action == "" {
+			cfg.Action = ModerationReject
+		}
+		if cfg.RefusalMessage == "" {
+			cfg.RefusalMessage = DefaultRefusalMessage
+		}
+		s.moderation = cfg
+	}
+}
+
+// WithGuardrail prompt'u LLM'e gitmeden önce injection/jailbreak için denetler.
+func WithGuardrail(g Guardrail) Option {
+	return func(s *service) {
+		s.guardrail = g
+	}
+}
+
+type promptSavedKey struct{}
+
+// PromptSavedFunc turn'ün prompt'u kaydedildikten hemen sonra, LLM'e
+// gitmeden önce çağrılır. Hata dönerse turn burada biter.
+type PromptSavedFunc func(ctx context.Context, sessionID string, seq int64) error
+
+// WithPromptSaved fn'i ctx'e koyar. Async job'lar prompt'un yerini bununla
+// saklar; process turn ortasında ölürse job tekrar SendMessage yerine Resume ile
+// devam eder ve prompt ikinci kez eklenmez.
+func WithPromptSaved(ctx context.Context, fn PromptSavedFunc) context.Context {
+	return context.WithValue(ctx, promptSavedKey{}, fn)
+}
+
+func promptSaved(ctx context.Context, msg ChatMessage) error {
+	fn, _ := ctx.Value(promptSavedKey{}).(PromptSavedFunc)
+	if fn == nil {
+		return nil
+	}
+	if err := fn(ctx, msg.SessionID, msg.Seq); err != nil {
+		logger.FromContext(ctx).Error("prompt saved hook failed", zap.Error(err))
+		return classify(ErrStorage, err)
+	}
+	return nil
 }
 
-func NewService(repo Repository, llmClient Client) Service {
-	return &service{
+// NewService varsayılan olarak process içi session kilidi kullanır.
+func NewService(repo Repository, llmClient Client, opts ...Option) Service {
+	s := &service{
 		repo:   repo,
 		client: llmClient,
+		locker: NewLocalLocker(),
 	}
+	s.abortCtx, s.abort = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// run turn'ü Drain'in beklediği turn'lere ekler ve ctx'i, Drain'in süresi
+// dolunca ErrShuttingDown sebebiyle iptal olacak şekilde bağlar.
+func (s *service) run(ctx context.Context, turn func(ctx context.Context) (Chat, error)) (Chat, error) {
+	if !s.begin() {
+		logger.FromContext(ctx).Warn("rejected turn during shutdown")
+		return Chat{}, ErrShuttingDown
+	}
+	defer s.active.Done()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(s.abortCtx, func() { cancel(ErrShuttingDown) })
+	defer stop()
+	return turn(ctx)
 }
-func (s *service) SendMessage(sessionID string, message string) (Chat, error) {
-	logger.Log.Info("Sending message",
+
+// lock session kilidini alır; aynı session'a eş zamanlı gelen turn'ler sırayla
+// işlenir. Kilit cevap kaydedilene kadar tutulur ki sonraki turn bu cevabı da görsün.
+func (s *service) lock(ctx context.Context, sessionID string) (func(), error) {
+	unlock, err := s.locker.Lock(ctx, sessionID)
+	if err == nil {
+		return unlock, nil
+	}
+	logger.FromContext(ctx).Warn("session lock failed", zap.String("sessionID", sessionID), zap.Error(err))
+	if errors.Is(err, ErrSessionBusy) {
+		return nil, err
+	}
+	if errors.Is(context.Cause(ctx), ErrShuttingDown) {
+		return nil, ErrShuttingDown
+	}
+	return nil, classify(ErrStorage, err)
+}
+
+func (s *service) SendMessage(ctx context.Context, sessionID string, message string) (Chat, error) {
+	return s.run(ctx, func(ctx context.Context) (Chat, error) {
+		return s.sendMessage(ctx, sessionID, message)
+	})
+}
+
+func (s *service) sendMessage(ctx context.Context, sessionID string, message string) (Chat, error) {
+	log := logger.FromContext(ctx)
+	log.Info("Sending message",
 		zap.String("sessionID", sessionID),
 		zap.String("message", message))
 
+	var messages []ChatMessage
+	var seq int64
+	newSession := sessionID == ""
+	if newSession {
+		sessionID = uuid.New().String()
+	} else {
+		unlock, err := s.lock(ctx, sessionID)
+		if err != nil {
+			return Chat{}, err
+		}
+		defer unlock()
+
+		history, err := s.repo.Find(ctx, sessionID)
+		if err != nil {
+			log.Error("load to history failed", zap.Error(err))
+			return Chat{}, classify(ErrStorage, err)
+		}
+		if len(history) == 0 {
+			log.Warn("session not found", zap.String("sessionID", sessionID))
+			return Chat{}, ErrSessionNotFound
+		}
+		messages = allowed(history)
+		seq = lastSeq(history)
+	}
+
+	id := identity.FromContext(ctx)
 	msg := ChatMessage{
 		Message:   message,
 		SessionID: sessionID,
+		TenantID:  id.TenantID,
+		UserID:    id.UserID,
+		Persona:   id.Persona,
 		Kind:      UserPrompt,
 		Timestamp: time.Now().Unix(),
+		Seq:       seq + 1,
 	}
-	err := s.repo.Save(&msg)
+	evs := []events.Event{events.New(events.MessageSaved, sessionID, &msg)}
+	if newSession {
+		evs = append([]events.Event{events.New(events.SessionCreated, sessionID, SessionCreatedEvent{
+			SessionID: sessionID,
+			UserID:    id.UserID,
+			TenantID:  id.TenantID,
+		})}, evs...)
+	}
+	s.guard(ctx, &msg)
+	verdict, err := s.moderate(ctx, msg.Message)
 	if err != nil {
-		logger.Log.Error("user message failed to saved", zap.Error(err))
 		return Chat{}, err
 	}
-	messages, err := s.repo.Find(sessionID)
+	if verdict.Flagged {
+		evs = append(evs, s.flag(&msg, verdict))
+	}
+	err = s.repo.Save(ctx, &msg, evs...)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// kilit replica'lar arasında paylaşılmıyorsa aynı Seq'i başka bir turn
+		// almış olabilir; unique index bunu yakalar, client tekrar dener
+		log.Warn("concurrent turn took the same seq", zap.String("sessionID", sessionID))
+		return Chat{}, wrap(ErrSessionBusy, err)
+	}
 	if err != nil {
-		logger.Log.Error("load to history failed", zap.Error(err))
+		log.Error("user message failed to saved", zap.Error(err))
+		return Chat{}, classify(ErrStorage, err)
+	}
+	if err := promptSaved(ctx, msg); err != nil {
 		return Chat{}, err
 	}
+	if msg.GuardAction == GuardBlock {
+		// kayıt inceleme için tutulur; turn LLM'e gitmeden kapanır
+		return Chat{}, ErrPromptRejected
+	}
+	if verdict.Flagged {
+		log.Warn("prompt flagged by moderation", zap.Strings("categories", verdict.Categories))
+		return s.flaggedPrompt(ctx, msg)
+	}
+	return s.complete(ctx, msg, messages, seq+2)
+}
 
-	response, err := s.client.GetCompletion(message, messages)
-	// param := s.client.BuildParams(msg.Message)
-	// for _, msg := range messages {
-	// 	param.Messages = append(param.Messages, openai.UserMessage(msg.Message))
-	// }
-	// completion, err = s.client.CreateCompletion(param)
-
+// complete kaydedilmiş prompt'un LLM cevabını alır, denetler ve seq ile
+// kaydeder. messages prompt'tan önceki, LLM'e gidecek history'dir.
+func (s *service) complete(ctx context.Context, msg ChatMessage, messages []ChatMessage, seq int64) (Chat, error) {
+	log := logger.FromContext(ctx)
+	sessionID := msg.SessionID
+	completion, err := s.client.GetCompletion(ctx, msg.Message, messages)
+	if errors.Is(context.Cause(ctx), ErrShuttingDown) {
+		log.Warn("turn interrupted by shutdown", zap.String("sessionID", sessionID))
+		s.interrupted(ctx, msg, seq)
+		return Chat{}, wrap(ErrShuttingDown, &InterruptedTurnError{SessionID: sessionID, PromptSeq: msg.Seq, Err: context.Cause(ctx)})
+	}
 	if err != nil {
-		logger.Log.Error("get completion fail", zap.Error(err))
+		log.Error("get completion fail", zap.Error(err))
+		err = classify(ErrUpstreamLLM, err)
+		s.completionFailed(ctx, msg, err)
 		return Chat{}, err
 	}
 	openaiMsg := ChatMessage{
-		Message:   response,
+		Message:   completion.Message,
 		SessionID: sessionID,
+		TenantID:  msg.TenantID,
+		UserID:    msg.UserID,
+		Persona:   msg.Persona,
 		Kind:      LLMOutput,
 		Timestamp: time.Now().Unix(),
+		Seq:       seq,
+		Attempts:  completion.Attempts,
+		Provider:  completion.Provider,
+		Model:     completion.Model,
+		Route:     completion.Route,
 	}
-	err = s.repo.Save(&openaiMsg)
+	verdict, err := s.moderate(ctx, completion.Message)
 	if err != nil {
-		logger.Log.Error("llm response failed to save", zap.Error(err))
+		s.completionFailed(ctx, msg, err)
 		return Chat{}, err
 	}
+	evs := []events.Event{events.New(events.MessageSaved, sessionID, &openaiMsg)}
+	if verdict.Flagged {
+		// işaretli cevap inceleme için saklanır ama kullanıcıya gösterilmez
+		evs = append(evs, s.flag(&openaiMsg, verdict))
+	}
+	// cevap alındı (ücreti ödendi); client gitmiş ya da shutdown başlamış olsa da kaydedilir
+	err = s.repo.Save(context.WithoutCancel(ctx), &openaiMsg, evs...)
+	if err != nil {
+		log.Error("llm response failed to save", zap.Error(err))
+		return Chat{}, classify(ErrStorage, err)
+	}
+	if verdict.Flagged {
+		log.Warn("response flagged by moderation", zap.Strings("categories", verdict.Categories))
+		if s.moderation.Action == ModerationReject {
+			return Chat{}, ErrContentFlagged
+		}
+		return Chat{Message: s.moderation.RefusalMessage, SessionID: sessionID, Attempts: completion.Attempts}, nil
+	}
 
-	logger.Log.Info("message sended")
+	log.Info("message sended",
+		zap.Int("attempts", completion.Attempts),
+		zap.String("provider", completion.Provider),
+		zap.String("model", completion.Model))
 	return Chat{
 		Message:   openaiMsg.Message,
 		SessionID: openaiMsg.SessionID,
+		Attempts:  openaiMsg.Attempts,
 	}, nil
 }
 
-func (s *service) FindHistory(sessionID string) ([]ChatMessage, error) {
-	logger.Log.Info("Finding history",
+// guard guardrail kararını mesaja yazar; strip'te mesaj temizlenmiş metinle
+// kaydedilir ki sonraki turn'lerin history'sinde de injection olmasın.
+func (s *service) guard(ctx context.Context, msg *ChatMessage) {
+	if s.guardrail == nil {
+		return
+	}
+	decision := s.guardrail.Inspect(ctx, SourceUser, msg.Message)
+	msg.GuardAction = decision.Action
+	msg.GuardScore = decision.Score
+	msg.GuardSignals = strings.Join(decision.Signals, ",")
+	if decision.Action == GuardAllow {
+		return
+	}
+	logger.FromContext(ctx).Warn("guardrail triggered",
+		zap.String("action", string(decision.Action)),
+		zap.Float64("score", decision.Score),
+		zap.Strings("signals", decision.Signals))
+	if decision.Action == GuardStrip {
+		msg.Message = decision.Text
+	}
+}
+
+// moderate moderasyon kapalıysa ya da FailOpen iken moderator'a
+// ulaşılamazsa boş sonuç döner.
+func (s *service) moderate(ctx context.Context, text string) (ModerationResult, error) {
+	if s.moderation.Moderator == nil {
+		return ModerationResult{}, nil
+	}
+	res, err := s.moderation.Moderator.Moderate(ctx, text)
+	if err != nil {
+		log := logger.FromContext(ctx)
+		if s.moderation.FailOpen {
+			log.Warn("moderation failed, continuing unmoderated", zap.Error(err))
+			return ModerationResult{}, nil
+		}
+		log.Error("moderation failed", zap.Error(err))
+		return ModerationResult{}, classify(ErrModeration, err)
+	}
+	return res, nil
+}
+
+// flag mesajı kategorileriyle işaretler ve ContentFlagged event'ini döner.
+func (s *service) flag(msg *ChatMessage, verdict ModerationResult) events.Event {
+	msg.Moderation = strings.Join(verdict.Categories, ",")
+	if msg.Moderation == "" {
+		msg.Moderation = "flagged"
+	}
+	return events.New(events.ContentFlagged, msg.SessionID, ContentFlaggedEvent{
+		SessionID:  msg.SessionID,
+		Seq:        msg.Seq,
+		Kind:       msg.Kind,
+		Categories: verdict.Categories,
+		Action:     string(s.moderation.Action),
+	})
+}
+
+// flaggedPrompt işaretlenen prompt'un turn'ünü kapatır: reject'te hata döner,
+// refuse'da ret mesajı cevap olarak kaydedilir.
+func (s *service) flaggedPrompt(ctx context.Context, prompt ChatMessage) (Chat, error) {
+	if s.moderation.Action == ModerationReject {
+		return Chat{}, ErrContentFlagged
+	}
+	refusal := ChatMessage{
+		Message:   s.moderation.RefusalMessage,
+		SessionID: prompt.SessionID,
+		TenantID:  prompt.TenantID,
+		UserID:    prompt.UserID,
+		Persona:   prompt.Persona,
+		Kind:      LLMOutput,
+		Timestamp: time.Now().Unix(),
+		Seq:       prompt.Seq + 1,
+	}
+	err := s.repo.Save(context.WithoutCancel(ctx), &refusal, events.New(events.MessageSaved, refusal.SessionID, &refusal))
+	if err != nil {
+		logger.FromContext(ctx).Error("refusal failed to save", zap.Error(err))
+		return Chat{}, classify(ErrStorage, err)
+	}
+	return Chat{Message: refusal.Message, SessionID: refusal.SessionID}, nil
+}
+
+// allowed moderasyonda işaretlenen ve guardrail'in block ettiği mesajları
+// LLM'e gidecek history'den çıkarır.
+func allowed(history []ChatMessage) []ChatMessage {
+	out := make([]ChatMessage, 0, len(history))
+	for _, m := range history {
+		if m.Moderation == "" && m.GuardAction != GuardBlock {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (s *service) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return false
+	}
+	s.active.Add(1)
+	return true
+}
+
+func (s *service) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	// süre doldu: LLM çağrıları iptal edilir, turn'ler Interrupted yazıp çıkar
+	s.abort()
+	<-done
+	return ctx.Err()
+}
+
+// interrupted cevabı gelmeyen prompt'un arkasına, cevabın alacağı seq ile
+// Interrupted mesajı yazar ki history'de sessizce cevapsız kalmasın.
+func (s *service) interrupted(ctx context.Context, prompt ChatMessage, seq int64) {
+	marker := ChatMessage{
+		Message:   "turn interrupted by server shutdown",
+		SessionID: prompt.SessionID,
+		TenantID:  prompt.TenantID,
+		UserID:    prompt.UserID,
+		Persona:   prompt.Persona,
+		Kind:      Interrupted,
+		Timestamp: time.Now().Unix(),
+		Seq:       seq,
+	}
+	ctx = context.WithoutCancel(ctx)
+	s.completionFailed(ctx, prompt, ErrShuttingDown)
+	if err := s.repo.Save(ctx, &marker, events.New(events.MessageSaved, marker.SessionID, &marker)); err != nil {
+		logger.FromContext(ctx).Error("failed to mark interrupted turn", zap.Error(err))
+	}
+}
+
+// completionFailed CompletionFailed event'ini yazar. Client bağlantıyı kapatmış
+// olsa da event kaybolmasın diye ctx'in iptali dikkate alınmaz.
+func (s *service) completionFailed(ctx context.Context, prompt ChatMessage, err error) {
+	code := ErrUpstreamLLM.Code
+	var de *Error
+	if errors.As(err, &de) {
+		code = de.Code
+	}
+	ev := events.New(events.CompletionFailed, prompt.SessionID, CompletionFailedEvent{
+		SessionID: prompt.SessionID,
+		PromptID:  prompt.ID,
+		Seq:       prompt.Seq,
+		Code:      code,
+	})
+	if err := s.repo.AddEvents(context.WithoutCancel(ctx), ev); err != nil {
+		logger.FromContext(ctx).Error("failed to record completion failure", zap.Error(err))
+	}
+}
+
+func (s *service) refusalMessage() string {
+	if s.moderation.RefusalMessage != "" {
+		return s.moderation.RefusalMessage
+	}
+	return DefaultRefusalMessage
+}
+
+func (s *service) Resume(ctx context.Context, sessionID string, promptSeq int64) (Chat, error) {
+	return s.run(ctx, func(ctx context.Context) (Chat, error) {
+		log := logger.FromContext(ctx)
+		log.Info("Resuming turn", zap.String("sessionID", sessionID), zap.Int64("seq", promptSeq))
+
+		unlock, err := s.lock(ctx, sessionID)
+		if err != nil {
+			return Chat{}, err
+		}
+		defer unlock()
+
+		history, err := s.repo.Find(ctx, sessionID)
+		if err != nil {
+			log.Error("load to history failed", zap.Error(err))
+			return Chat{}, classify(ErrStorage, err)
+		}
+		prompt := -1
+		for i, m := range history {
+			if m.Seq == promptSeq && m.Kind == UserPrompt {
+				prompt = i
+				break
+			}
+		}
+		if prompt < 0 {
+			log.Warn("interrupted prompt not found", zap.String("sessionID", sessionID))
+			return Chat{}, ErrSessionNotFound
+		}
+		// prompt'tan sonra Interrupted'dan başka ilk mesaj cevapsa turn bitmiştir;
+		// başka bir turn'ün prompt'uysa cevap onun arkasına eklenmez
+		for _, m := range history[prompt+1:] {
+			if m.Kind == Interrupted {
+				continue
+			}
+			if m.Kind == LLMOutput {
+				if m.Moderation != "" {
+					m.Message = s.refusalMessage()
+				}
+				return Chat{Message: m.Message, SessionID: sessionID, Attempts: m.Attempts}, nil
+			}
+			log.Warn("interrupted turn superseded by a newer turn", zap.String("sessionID", sessionID))
+			return Chat{}, ErrTurnSuperseded
+		}
+		msg := history[prompt]
+		if msg.GuardAction == GuardBlock {
+			return Chat{}, ErrPromptRejected
+		}
+		if msg.Moderation != "" {
+			return s.flaggedPrompt(ctx, msg)
+		}
+		// sonrasında sadece bu turn'ün Interrupted işaretleri var; cevap onlardan hemen sonra gelir
+		return s.complete(ctx, msg, allowed(history[:prompt]), lastSeq(history)+1)
+	})
+}
+
+func (s *service) FindHistory(ctx context.Context, sessionID string) ([]ChatMessage, error) {
+	log := logger.FromContext(ctx)
+	log.Info("Finding history",
 		zap.String("sessionID", sessionID))
-	messages, err := s.repo.Find(sessionID)
+	messages, err := s.repo.Find(ctx, sessionID)
 	if err != nil {
-		logger.Log.Error("failed to load history", zap.Error(err))
-		return nil, err
+		log.Error("failed to load history", zap.Error(err))
+		return nil, classify(ErrStorage, err)
+	}
+	if len(messages) == 0 {
+		log.Warn("session not found", zap.String("sessionID", sessionID))
+		return nil, ErrSessionNotFound
+	}
+	// işaretli cevaplar inceleme için saklanır ama kullanıcıya gösterilmez
+	for i, m := range messages {
+		if m.Kind == LLMOutput && m.Moderation != "" {
+			messages[i].Message = s.refusalMessage()
+		}
 	}
-	logger.Log.Info("history loaded")
+	log.Info("history loaded")
 	return messages, nil
 }
+
+// lastSeq history'deki en büyük Seq'i döner; Seq'siz eski kayıtlarda da
+// yeni turn'ler sona eklenir.
+func lastSeq(history []ChatMessage) int64 {
+	var last int64
+	for _, m := range history {
+		if m.Seq > last {
+			last = m.Seq
+		}
+	}
+	return last
+}
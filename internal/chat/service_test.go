@@ -1,17 +1,23 @@
 package chat
 
 import (
+	"context"
 	"errors"
+	"myapp/internal/events"
 	"myapp/pkg/logger"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-func TestSendMessage_NoHistory_Success(t *testing.T) {
+func TestSendMessage_NewSession_Success(t *testing.T) {
 	//arrange
 	logger.Log = zap.NewNop()
 	ctrl := gomock.NewController(t)
@@ -21,35 +27,38 @@ func TestSendMessage_NoHistory_Success(t *testing.T) {
 
 	message := "merhaba"
 	openaiMsg := "merhaba, size nasıl yardımcı olabilirim?"
-	sessionId := "sess123"
-
-	response := Chat{
-		Message:   openaiMsg,
-		SessionID: sessionId,
-	}
-	history := []ChatMessage{}
+	var savedSessionID string
 
 	gomock.InOrder(
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, evs ...events.Event) {
 			assert.Equal(t, message, msg.Message)
-			assert.Equal(t, sessionId, msg.SessionID)
+			assert.Equal(t, UserPrompt, msg.Kind)
+			savedSessionID = msg.SessionID
+			// yeni session: SessionCreated ve prompt'un MessageSaved'i aynı Save'de
+			assert.Len(t, evs, 2)
+			assert.Equal(t, events.SessionCreated, evs[0].Type)
+			assert.Equal(t, events.MessageSaved, evs[1].Type)
+			assert.Same(t, msg, evs[1].Payload)
 		}).Return(nil).Times(1),
-		repoMock.EXPECT().Find(sessionId).Return(history, nil).Times(1),
-		clientMock.EXPECT().GetCompletion(message, history).Return(openaiMsg, nil).Times(1),
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
+		clientMock.EXPECT().GetCompletion(gomock.Any(), message, gomock.Len(0)).Return(Completion{Message: openaiMsg, Attempts: 2}, nil).Times(1),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
 			assert.Equal(t, openaiMsg, msg.Message)
-			assert.Equal(t, sessionId, msg.SessionID)
+			assert.Equal(t, LLMOutput, msg.Kind)
+			assert.Equal(t, savedSessionID, msg.SessionID)
 		}).Return(nil).Times(1),
 	)
 
 	//act
-	result, err := service.SendMessage(sessionId, message)
+	result, err := service.SendMessage(context.Background(), "", message)
 	//assert
-	assert.Equal(t, response, result)
 	assert.Nil(t, err)
+	assert.Equal(t, openaiMsg, result.Message)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, savedSessionID, result.SessionID)
+	_, parseErr := uuid.Parse(result.SessionID)
+	assert.NoError(t, parseErr)
 }
 func TestSendMessage_WithHistory_Success(t *testing.T) {
-	//gerek var mı buna anlamadım ya
 	//arrange
 	logger.Log = zap.NewNop()
 	ctrl := gomock.NewController(t)
@@ -64,6 +73,7 @@ func TestSendMessage_WithHistory_Success(t *testing.T) {
 	response := Chat{
 		Message:   openaiMsg,
 		SessionID: sessionId,
+		Attempts:  2,
 	}
 	history := []ChatMessage{
 		{
@@ -83,27 +93,45 @@ func TestSendMessage_WithHistory_Success(t *testing.T) {
 	}
 
 	gomock.InOrder(
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
+		repoMock.EXPECT().Find(gomock.Any(), gomock.Any()).Do(func(_ context.Context, id string) {
+			assert.Equal(t, sessionId, id)
+		}).Return(history, nil).Times(1),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
 			assert.Equal(t, message, msg.Message)
 			assert.Equal(t, sessionId, msg.SessionID)
 		}).Return(nil).Times(1),
-		repoMock.EXPECT().Find(gomock.Any()).Do(func(id string) {
-			assert.Equal(t, sessionId, id)
-		}).Return(history, nil).Times(1),
-		clientMock.EXPECT().GetCompletion(message, history).Return(openaiMsg, nil).Times(1),
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
+		clientMock.EXPECT().GetCompletion(gomock.Any(), message, history).Return(Completion{Message: openaiMsg, Attempts: 2}, nil).Times(1),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
 			assert.Equal(t, openaiMsg, msg.Message)
 			assert.Equal(t, sessionId, msg.SessionID)
+			assert.Equal(t, 2, msg.Attempts)
 		}).Return(nil).Times(1),
 	)
 
 	//act
-	result, err := service.SendMessage(sessionId, message)
+	result, err := service.SendMessage(context.Background(), sessionId, message)
 	//assert
 	assert.Equal(t, response, result)
 	assert.Nil(t, err)
 }
 
+func TestSendMessage_SessionNotFound(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return([]ChatMessage{}, nil).Times(1)
+
+	//act
+	result, err := service.SendMessage(context.Background(), "sess123", "merhaba")
+	//assert
+	assert.Equal(t, Chat{}, result)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
 func TestSendMessage_SaveUserMessageFails(t *testing.T) {
 	//arrange
 	logger.Log = zap.NewNop()
@@ -115,18 +143,22 @@ func TestSendMessage_SaveUserMessageFails(t *testing.T) {
 	message := "merhaba"
 	sessionId := "sess123"
 	response := Chat{}
+	dbErr := errors.New("database save error")
 
-	repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
-		assert.Equal(t, message, msg.Message)
-		assert.Equal(t, sessionId, msg.SessionID)
-	}).Return(errors.New("database save error")).Times(1)
+	gomock.InOrder(
+		repoMock.EXPECT().Find(gomock.Any(), sessionId).Return([]ChatMessage{{ID: 1}}, nil).Times(1),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
+			assert.Equal(t, message, msg.Message)
+			assert.Equal(t, sessionId, msg.SessionID)
+		}).Return(dbErr).Times(1),
+	)
 
 	//act
-	result, err := service.SendMessage(sessionId, message)
+	result, err := service.SendMessage(context.Background(), sessionId, message)
 	//assert
 	assert.Equal(t, response, result)
-	assert.Error(t, err)
-	assert.EqualError(t, err, "database save error")
+	assert.ErrorIs(t, err, ErrStorage)
+	assert.ErrorIs(t, err, dbErr)
 }
 
 func TestSendMessage_FindHistoryFails(t *testing.T) {
@@ -141,19 +173,14 @@ func TestSendMessage_FindHistoryFails(t *testing.T) {
 	sessionId := "sess123"
 	response := Chat{}
 
-	gomock.InOrder(
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
-			assert.Equal(t, message, msg.Message)
-			assert.Equal(t, sessionId, msg.SessionID)
-		}).Return(nil).Times(1),
-		repoMock.EXPECT().Find(sessionId).Return(nil, gorm.ErrRecordNotFound).Times(1),
-	)
+	repoMock.EXPECT().Find(gomock.Any(), sessionId).Return(nil, gorm.ErrInvalidDB).Times(1)
 
 	//act
-	result, err := service.SendMessage(sessionId, message) //nasıl oldu kafam gitti
+	result, err := service.SendMessage(context.Background(), sessionId, message)
 	//assert
 	assert.Equal(t, response, result)
-	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, err, ErrStorage)
+	assert.ErrorIs(t, err, gorm.ErrInvalidDB)
 }
 
 func TestSendMessage_GetCompletionFails(t *testing.T) {
@@ -166,23 +193,29 @@ func TestSendMessage_GetCompletionFails(t *testing.T) {
 	message := "merhaba"
 	sessionId := "sess123"
 	response := Chat{}
-	history := []ChatMessage{}
+	history := []ChatMessage{{ID: 1, Kind: UserPrompt, Message: "selam", SessionID: sessionId}}
+	llmErr := errors.New("llm error")
 
 	gomock.InOrder(
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
+		repoMock.EXPECT().Find(gomock.Any(), sessionId).Return(history, nil).Times(1),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
 			assert.Equal(t, message, msg.Message)
 			assert.Equal(t, sessionId, msg.SessionID)
 		}).Return(nil).Times(1),
-		repoMock.EXPECT().Find(sessionId).Return(history, nil).Times(1),
-		clientMock.EXPECT().GetCompletion(message, history).Return("", errors.New("llm error")).Times(1),
+		clientMock.EXPECT().GetCompletion(gomock.Any(), message, history).Return(Completion{}, llmErr).Times(1),
+		repoMock.EXPECT().AddEvents(gomock.Any(), gomock.Any()).Do(func(_ context.Context, evs ...events.Event) {
+			assert.Len(t, evs, 1)
+			assert.Equal(t, events.CompletionFailed, evs[0].Type)
+			assert.Equal(t, "upstream_llm_error", evs[0].Payload.(CompletionFailedEvent).Code)
+		}).Return(nil).Times(1),
 	)
 
 	//act
-	result, err := service.SendMessage(sessionId, message) //nasıl oldu kafam gitti
+	result, err := service.SendMessage(context.Background(), sessionId, message)
 	//assert
 	assert.Equal(t, response, result)
-	assert.Error(t, err)
-	assert.EqualError(t, err, "llm error")
+	assert.ErrorIs(t, err, ErrUpstreamLLM)
+	assert.ErrorIs(t, err, llmErr)
 }
 
 func TestSendMessage_SaveLLMMessageFails(t *testing.T) {
@@ -198,27 +231,28 @@ func TestSendMessage_SaveLLMMessageFails(t *testing.T) {
 	sessionId := "sess123"
 
 	response := Chat{}
-	history := []ChatMessage{}
+	history := []ChatMessage{{ID: 1, Kind: UserPrompt, Message: "selam", SessionID: sessionId}}
+	dbErr := errors.New("db save response error")
 
 	gomock.InOrder(
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
+		repoMock.EXPECT().Find(gomock.Any(), sessionId).Return(history, nil).Times(1),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
 			assert.Equal(t, message, msg.Message)
 			assert.Equal(t, sessionId, msg.SessionID)
 		}).Return(nil).Times(1),
-		repoMock.EXPECT().Find(sessionId).Return(history, nil).Times(1),
-		clientMock.EXPECT().GetCompletion(message, history).Return(openaiMsg, nil).Times(1),
-		repoMock.EXPECT().Save(gomock.Any()).Do(func(msg *ChatMessage) {
+		clientMock.EXPECT().GetCompletion(gomock.Any(), message, history).Return(Completion{Message: openaiMsg, Attempts: 1}, nil).Times(1),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
 			assert.Equal(t, openaiMsg, msg.Message)
 			assert.Equal(t, sessionId, msg.SessionID)
-		}).Return(errors.New("db save response error")).Times(1),
+		}).Return(dbErr).Times(1),
 	)
 
 	//act
-	result, err := service.SendMessage(sessionId, message) //nasıl oldu kafam gitti
+	result, err := service.SendMessage(context.Background(), sessionId, message)
 	//assert
 	assert.Equal(t, response, result)
-	assert.Error(t, err)
-	assert.EqualError(t, err, "db save response error")
+	assert.ErrorIs(t, err, ErrStorage)
+	assert.ErrorIs(t, err, dbErr)
 }
 func TestFindHistory_Success(t *testing.T) {
 	//arrange
@@ -231,9 +265,9 @@ func TestFindHistory_Success(t *testing.T) {
 		{ID: 1, Kind: UserPrompt, Message: "merhaba", Timestamp: 111, SessionID: "session1"},
 	}
 
-	repoMock.EXPECT().Find("sess1").Return(history, nil).Times(1)
+	repoMock.EXPECT().Find(gomock.Any(), "sess1").Return(history, nil).Times(1)
 	//act
-	result, err := service.FindHistory("sess1")
+	result, err := service.FindHistory(context.Background(), "sess1")
 	//assert
 	assert.Equal(t, len(history), len(result))
 	assert.Nil(t, err)
@@ -246,10 +280,422 @@ func TestFindHistory_NotFound(t *testing.T) {
 	repoMock := NewMockRepository(ctrl)
 	service := NewService(repoMock, nil)
 
-	repoMock.EXPECT().Find("sess1").Return(nil, gorm.ErrRecordNotFound)
+	repoMock.EXPECT().Find(gomock.Any(), "sess1").Return([]ChatMessage{}, nil)
+
+	result, err := service.FindHistory(context.Background(), "sess1")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestFindHistory_StorageError(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	service := NewService(repoMock, nil)
+
+	repoMock.EXPECT().Find(gomock.Any(), "sess1").Return(nil, gorm.ErrInvalidDB)
 
-	result, err := service.FindHistory("sess1")
+	result, err := service.FindHistory(context.Background(), "sess1")
 
 	assert.Nil(t, result)
-	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, err, ErrStorage)
+	assert.ErrorIs(t, err, gorm.ErrInvalidDB)
+}
+
+func TestSendMessage_ContextCanceledDuringCompletion(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	type key struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "req"))
+	defer cancel()
+	sessionId := "sess123"
+	history := []ChatMessage{{ID: 1, Kind: UserPrompt, Message: "selam", SessionID: sessionId}}
+
+	// aynı request context'i tüm katmanlara ulaşmalı
+	sameCtx := gomock.Cond(func(c context.Context) bool { return c.Value(key{}) == "req" })
+	gomock.InOrder(
+		repoMock.EXPECT().Find(sameCtx, sessionId).Return(history, nil).Times(1),
+		repoMock.EXPECT().Save(sameCtx, gomock.Any(), gomock.Any()).Return(nil).Times(1),
+		clientMock.EXPECT().GetCompletion(sameCtx, "merhaba", history).
+			DoAndReturn(func(ctx context.Context, _ string, _ []ChatMessage) (Completion, error) {
+				cancel() // client bağlantıyı kapattı
+				<-ctx.Done()
+				return Completion{}, ctx.Err()
+			}).Times(1),
+		// client gitse de event yazılmalı
+		repoMock.EXPECT().AddEvents(gomock.Cond(func(c context.Context) bool { return c.Err() == nil }), gomock.Any()).
+			Do(func(_ context.Context, evs ...events.Event) {
+				assert.Equal(t, "request_canceled", evs[0].Payload.(CompletionFailedEvent).Code)
+			}).Return(nil).Times(1),
+	)
+	// LLM cevabı kaydedilmemeli: ikinci Save beklenmiyor
+
+	//act
+	result, err := service.SendMessage(ctx, sessionId, "merhaba")
+	//assert
+	assert.Equal(t, Chat{}, result)
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSendMessage_DeadlineExceeded(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").
+		DoAndReturn(func(ctx context.Context, _ string) ([]ChatMessage, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}).Times(1)
+
+	//act
+	_, err := service.SendMessage(ctx, "sess123", "merhaba")
+	//assert
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSendMessage_AssignsSeqAfterHistory(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	history := []ChatMessage{
+		{ID: 1, Kind: UserPrompt, Message: "selam", SessionID: "sess123", Seq: 3},
+		{ID: 2, Kind: LLMOutput, Message: "selam!", SessionID: "sess123", Seq: 4},
+	}
+	var seqs []int64
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return(history, nil)
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
+		seqs = append(seqs, msg.Seq)
+	}).Return(nil).Times(2)
+	clientMock.EXPECT().GetCompletion(gomock.Any(), "naber", history).Return(Completion{Message: "iyi"}, nil)
+
+	//act
+	_, err := service.SendMessage(context.Background(), "sess123", "naber")
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{5, 6}, seqs)
+}
+
+func TestSendMessage_ConcurrentTurnsAreSerialized(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	var mu sync.Mutex
+	stored := []ChatMessage{{ID: 1, Kind: UserPrompt, SessionID: "sess123", Seq: 1}, {ID: 2, Kind: LLMOutput, SessionID: "sess123", Seq: 2}}
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").DoAndReturn(func(context.Context, string) ([]ChatMessage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]ChatMessage(nil), stored...), nil
+	}).AnyTimes()
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msg *ChatMessage, _ ...events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		msg.ID = len(stored) + 1
+		stored = append(stored, *msg)
+		return nil
+	}).AnyTimes()
+	clientMock.EXPECT().GetCompletion(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, message string, history []ChatMessage) (Completion, error) {
+			// her turn'ün gördüğü history cevaplanmamış prompt içermemeli
+			assert.Equal(t, LLMOutput, history[len(history)-1].Kind)
+			time.Sleep(2 * time.Millisecond)
+			return Completion{Message: "cevap " + message}, nil
+		}).AnyTimes()
+
+	//act
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.SendMessage(context.Background(), "sess123", "soru")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	//assert
+	assert.Len(t, stored, 12)
+	for i, m := range stored {
+		assert.Equal(t, int64(i+1), m.Seq)
+		if i%2 == 0 {
+			assert.Equal(t, UserPrompt, m.Kind)
+		} else {
+			assert.Equal(t, LLMOutput, m.Kind)
+		}
+	}
+}
+
+type busyLocker struct{}
+
+func (busyLocker) Lock(context.Context, string) (func(), error) {
+	return nil, ErrSessionBusy
+}
+
+func TestSendMessage_SessionBusy(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	service := NewService(NewMockRepository(ctrl), NewMockClient(ctrl), WithLocker(busyLocker{}))
+
+	_, err := service.SendMessage(context.Background(), "sess123", "naber")
+
+	assert.ErrorIs(t, err, ErrSessionBusy)
+}
+
+func TestSendMessage_DuplicateSeqIsSessionBusy(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	service := NewService(repoMock, NewMockClient(ctrl))
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return([]ChatMessage{{ID: 1, Kind: UserPrompt, SessionID: "sess123", Seq: 1}}, nil)
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(gorm.ErrDuplicatedKey)
+
+	_, err := service.SendMessage(context.Background(), "sess123", "naber")
+
+	assert.ErrorIs(t, err, ErrSessionBusy)
+}
+
+func TestDrain_RejectsNewTurns(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	service := NewService(NewMockRepository(ctrl), NewMockClient(ctrl))
+
+	err := service.Drain(context.Background())
+	_, sendErr := service.SendMessage(context.Background(), "", "merhaba")
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, sendErr, ErrShuttingDown)
+}
+
+func TestDrain_WaitsForActiveTurn(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	clientMock.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).DoAndReturn(
+		func(context.Context, string, []ChatMessage) (Completion, error) {
+			close(started)
+			<-release
+			return Completion{Message: "selam"}, nil
+		})
+	result := make(chan error, 1)
+	go func() {
+		_, err := service.SendMessage(context.Background(), "", "merhaba")
+		result <- err
+	}()
+	<-started
+
+	//act
+	drained := make(chan error, 1)
+	go func() { drained <- service.Drain(context.Background()) }()
+	select {
+	case <-drained:
+		t.Fatal("drain returned before the turn finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	//assert
+	assert.NoError(t, <-drained)
+	assert.NoError(t, <-result)
+}
+
+func TestDrain_DeadlineInterruptsTurn(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	history := []ChatMessage{{ID: 1, Kind: UserPrompt, SessionID: "sess123", Seq: 1}, {ID: 2, Kind: LLMOutput, SessionID: "sess123", Seq: 2}}
+	started := make(chan struct{})
+	var marker ChatMessage
+	gomock.InOrder(
+		repoMock.EXPECT().Find(gomock.Any(), "sess123").Return(history, nil),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+		clientMock.EXPECT().GetCompletion(gomock.Any(), "merhaba", history).DoAndReturn(
+			func(ctx context.Context, _ string, _ []ChatMessage) (Completion, error) {
+				close(started)
+				<-ctx.Done()
+				return Completion{}, ctx.Err()
+			}),
+		repoMock.EXPECT().AddEvents(gomock.Any(), gomock.Any()).Do(func(_ context.Context, evs ...events.Event) {
+			assert.Equal(t, "shutting_down", evs[0].Payload.(CompletionFailedEvent).Code)
+		}).Return(nil),
+		repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(ctx context.Context, msg *ChatMessage, _ ...events.Event) {
+			assert.NoError(t, ctx.Err())
+			marker = *msg
+		}).Return(nil),
+	)
+	result := make(chan error, 1)
+	go func() {
+		_, err := service.SendMessage(context.Background(), "sess123", "merhaba")
+		result <- err
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	//act
+	err := service.Drain(ctx)
+
+	//assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	sendErr := <-result
+	assert.ErrorIs(t, sendErr, ErrShuttingDown)
+	var turn *InterruptedTurnError
+	require.ErrorAs(t, sendErr, &turn)
+	assert.Equal(t, InterruptedTurnError{SessionID: "sess123", PromptSeq: 3, Err: turn.Err}, *turn)
+	assert.Equal(t, Interrupted, marker.Kind)
+	assert.Equal(t, int64(4), marker.Seq)
+}
+
+func TestResume_CompletesInterruptedTurn(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	before := []ChatMessage{{ID: 1, Kind: UserPrompt, SessionID: "sess123", Seq: 1}, {ID: 2, Kind: LLMOutput, SessionID: "sess123", Seq: 2}}
+	history := append(append([]ChatMessage(nil), before...),
+		ChatMessage{ID: 3, Kind: UserPrompt, SessionID: "sess123", Seq: 3, Message: "merhaba"},
+		ChatMessage{ID: 4, Kind: Interrupted, SessionID: "sess123", Seq: 4},
+	)
+	var saved ChatMessage
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return(history, nil)
+	clientMock.EXPECT().GetCompletion(gomock.Any(), "merhaba", before).Return(Completion{Message: "selam"}, nil)
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ context.Context, msg *ChatMessage, _ ...events.Event) {
+		saved = *msg
+	}).Return(nil)
+
+	//act
+	res, err := service.Resume(context.Background(), "sess123", 3)
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, Chat{Message: "selam", SessionID: "sess123"}, res)
+	assert.Equal(t, LLMOutput, saved.Kind)
+	assert.Equal(t, int64(5), saved.Seq)
+}
+
+func TestResume_AlreadyAnswered(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	service := NewService(repoMock, NewMockClient(ctrl))
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return([]ChatMessage{
+		{ID: 1, Kind: UserPrompt, SessionID: "sess123", Seq: 1},
+		{ID: 2, Kind: Interrupted, SessionID: "sess123", Seq: 2},
+		{ID: 3, Kind: LLMOutput, SessionID: "sess123", Seq: 3, Message: "cevap"},
+	}, nil)
+
+	res, err := service.Resume(context.Background(), "sess123", 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "cevap", res.Message)
+}
+
+func TestResume_SupersededByNewerTurn(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	service := NewService(repoMock, NewMockClient(ctrl))
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return([]ChatMessage{
+		{ID: 1, Kind: UserPrompt, SessionID: "sess123", Seq: 1},
+		{ID: 2, Kind: UserPrompt, SessionID: "sess123", Seq: 2},
+		{ID: 3, Kind: LLMOutput, SessionID: "sess123", Seq: 3},
+	}, nil)
+
+	_, err := service.Resume(context.Background(), "sess123", 1)
+
+	assert.ErrorIs(t, err, ErrTurnSuperseded, "the answer is not appended after a newer turn")
+}
+
+func TestSendMessage_PromptSavedHookRunsBeforeLLM(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	clientMock := NewMockClient(ctrl)
+	service := NewService(repoMock, clientMock)
+
+	var hookSession string
+	var hookSeq int64
+	ctx := WithPromptSaved(context.Background(), func(_ context.Context, sessionID string, seq int64) error {
+		hookSession, hookSeq = sessionID, seq
+		return nil
+	})
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return([]ChatMessage{{ID: 1, Kind: UserPrompt, SessionID: "sess123", Seq: 1}}, nil)
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	clientMock.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).DoAndReturn(
+		func(context.Context, string, []ChatMessage) (Completion, error) {
+			assert.Equal(t, "sess123", hookSession, "hook runs before the LLM call")
+			assert.Equal(t, int64(2), hookSeq)
+			return Completion{Message: "selam"}, nil
+		})
+
+	//act
+	_, err := service.SendMessage(ctx, "sess123", "merhaba")
+
+	//assert
+	require.NoError(t, err)
+}
+
+func TestSendMessage_PromptSavedHookFailureStopsTurn(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	service := NewService(repoMock, NewMockClient(ctrl))
+	ctx := WithPromptSaved(context.Background(), func(context.Context, string, int64) error {
+		return errors.New("db down")
+	})
+	repoMock.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	_, err := service.SendMessage(ctx, "", "merhaba")
+
+	assert.ErrorIs(t, err, ErrStorage)
+}
+
+func TestResume_PromptNotFound(t *testing.T) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	repoMock := NewMockRepository(ctrl)
+	service := NewService(repoMock, NewMockClient(ctrl))
+	repoMock.EXPECT().Find(gomock.Any(), "sess123").Return(nil, nil)
+
+	_, err := service.Resume(context.Background(), "sess123", 1)
+
+	assert.ErrorIs(t, err, ErrSessionNotFound)
 }
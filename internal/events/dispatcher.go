@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"myapp/pkg/logger"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type DispatcherConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// PublishTimeout bir event'in tüm sink'lere gönderilmesi için süre; aynı
+	// zamanda claim lease'idir.
+	PublishTimeout time.Duration
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// Dispatcher outbox'taki kayıtları sırayla sink'lere yayınlar. Bir sink hata
+// verirse kayıt geri çekilir ve artan beklemeyle tekrar denenir; o sırada
+// sonraki kayıtlar yayınlanmaya devam eder.
+type Dispatcher struct {
+	outbox Outbox
+	sinks  []Sink
+	cfg    DispatcherConfig
+	now    func() time.Time
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(outbox Outbox, cfg DispatcherConfig, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		outbox: outbox,
+		sinks:  sinks,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Start ctx iptal edilene kadar outbox'ı poll eder; Wait elindeki batch'in bitmesini bekler.
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			n, err := d.dispatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Log.Error("outbox dispatch failed", zap.Error(err))
+			}
+			if n == d.cfg.BatchSize && ctx.Err() == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(d.cfg.PollInterval):
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// dispatch bir batch yayınlar ve alınan kayıt sayısını döner.
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	records, err := d.outbox.Claim(ctx, d.now(), d.cfg.PublishTimeout, d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	// claim edilen kayıtlar shutdown'da da işaretlensin
+	ctx = context.WithoutCancel(ctx)
+	for _, r := range records {
+		log := logger.Log.With(zap.String("eventID", r.EventID), zap.String("type", string(r.Type)))
+		if err := d.publish(ctx, r.Envelope()); err != nil {
+			next := d.now().Add(d.backoff(r.Attempts))
+			log.Warn("event publish failed", zap.Int("attempts", r.Attempts), zap.Time("next", next), zap.Error(err))
+			if err := d.outbox.MarkFailed(ctx, r.ID, next, err.Error()); err != nil {
+				return len(records), err
+			}
+			continue
+		}
+		if err := d.outbox.MarkPublished(ctx, r.ID, d.now()); err != nil {
+			return len(records), err
+		}
+		log.Debug("event published")
+	}
+	return len(records), nil
+}
+
+func (d *Dispatcher) publish(ctx context.Context, env Envelope) error {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.PublishTimeout)
+	defer cancel()
+	for _, s := range d.sinks {
+		if err := s.Publish(ctx, env); err != nil {
+			return fmt.Errorf("%s: %w", s.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.RetryBaseDelay
+	for i := 1; i < attempts && delay < d.cfg.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.RetryMaxDelay)
+}
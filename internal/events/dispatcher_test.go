@@ -0,0 +1,219 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"myapp/pkg/logger"
+	"myapp/pkg/webhook"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryOutbox Outbox'ın test için bellekteki karşılığıdır.
+type memoryOutbox struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (o *memoryOutbox) add(t *testing.T, evs ...Event) {
+	records, err := NewRecords(evs)
+	assert.NoError(t, err)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, r := range records {
+		r.ID = uint64(len(o.records) + 1)
+		o.records = append(o.records, r)
+	}
+}
+
+func (o *memoryOutbox) Claim(_ context.Context, now time.Time, lease time.Duration, limit int) ([]Record, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var claimed []Record
+	for i := range o.records {
+		r := &o.records[i]
+		if r.PublishedAt == nil && !r.NextAttemptAt.After(now) && len(claimed) < limit {
+			r.Attempts++
+			r.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, *r)
+		}
+	}
+	return claimed, nil
+}
+
+func (o *memoryOutbox) MarkPublished(_ context.Context, id uint64, at time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.records[id-1].PublishedAt = &at
+	return nil
+}
+
+func (o *memoryOutbox) MarkFailed(_ context.Context, id uint64, next time.Time, reason string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.records[id-1].NextAttemptAt = next
+	o.records[id-1].LastError = reason
+	return nil
+}
+
+func (o *memoryOutbox) Backlog(context.Context) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var n int64
+	for _, r := range o.records {
+		if r.PublishedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+var testDispatcherConfig = DispatcherConfig{
+	PollInterval:   10 * time.Millisecond,
+	BatchSize:      10,
+	PublishTimeout: time.Second,
+	RetryBaseDelay: time.Second,
+	RetryMaxDelay:  time.Minute,
+}
+
+func TestDispatcher_PublishesInOrderToBus(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	outbox := &memoryOutbox{}
+	outbox.add(t,
+		New(SessionCreated, "s1", map[string]string{"sessionId": "s1"}),
+		New(MessageSaved, "s1", map[string]string{"message": "merhaba"}),
+	)
+	bus := NewBus()
+	var all, saved []Type
+	bus.Subscribe("", func(_ context.Context, env Envelope) error { all = append(all, env.Type); return nil })
+	bus.Subscribe(MessageSaved, func(_ context.Context, env Envelope) error {
+		saved = append(saved, env.Type)
+		assert.JSONEq(t, `{"message":"merhaba"}`, string(env.Payload))
+		return nil
+	})
+	d := NewDispatcher(outbox, testDispatcherConfig, bus)
+
+	//act
+	n, err := d.dispatch(context.Background())
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []Type{SessionCreated, MessageSaved}, all)
+	assert.Equal(t, []Type{MessageSaved}, saved)
+	for _, r := range outbox.records {
+		assert.NotNil(t, r.PublishedAt)
+	}
+}
+
+func TestDispatcher_RetriesFailedEvent(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	outbox := &memoryOutbox{}
+	outbox.add(t, New(MessageSaved, "s1", nil))
+	bus := NewBus()
+	fail := true
+	bus.Subscribe("", func(context.Context, Envelope) error {
+		if fail {
+			return errors.New("index down")
+		}
+		return nil
+	})
+	d := NewDispatcher(outbox, testDispatcherConfig, bus)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	//act
+	_, err := d.dispatch(context.Background())
+
+	//assert
+	assert.NoError(t, err)
+	r := outbox.records[0]
+	assert.Nil(t, r.PublishedAt)
+	assert.Equal(t, now.Add(time.Second), r.NextAttemptAt)
+	assert.Contains(t, r.LastError, "bus: index down")
+
+	// zamanı gelmeden tekrar denenmez
+	n, _ := d.dispatch(context.Background())
+	assert.Equal(t, 0, n)
+
+	now = now.Add(time.Second)
+	fail = false
+	n, _ = d.dispatch(context.Background())
+	assert.Equal(t, 1, n)
+	assert.NotNil(t, outbox.records[0].PublishedAt)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(&memoryOutbox{}, testDispatcherConfig)
+
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 4*time.Second, d.backoff(3))
+	assert.Equal(t, time.Minute, d.backoff(20))
+}
+
+func TestDispatcher_StartAndStop(t *testing.T) {
+	logger.Log = zap.NewNop()
+	outbox := &memoryOutbox{}
+	outbox.add(t, New(MessageSaved, "s1", nil))
+	got := make(chan Envelope, 1)
+	bus := NewBus()
+	bus.Subscribe(MessageSaved, func(_ context.Context, env Envelope) error { got <- env; return nil })
+	d := NewDispatcher(outbox, testDispatcherConfig, bus)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d.Start(ctx)
+	select {
+	case env := <-got:
+		assert.Equal(t, "s1", env.SessionID)
+	case <-time.After(time.Second):
+		t.Fatal("event not dispatched")
+	}
+	cancel()
+	d.Wait()
+}
+
+func TestHTTPSink(t *testing.T) {
+	//arrange
+	var got Envelope
+	var ok bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		ok = webhook.Verify("s3cret", ts, body, r.Header.Get(webhook.HeaderSignature))
+		json.Unmarshal(body, &got)
+		assert.Equal(t, string(MessageSaved), r.Header.Get(HeaderEventType))
+	}))
+	defer srv.Close()
+	env := Envelope{ID: "e1", Type: MessageSaved, SessionID: "s1", Payload: json.RawMessage(`{"id":1}`)}
+
+	//act
+	err := NewHTTPSink(srv.URL, "s3cret", time.Second).Publish(context.Background(), env)
+
+	//assert
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "e1", got.ID)
+	assert.JSONEq(t, `{"id":1}`, string(got.Payload))
+}
+
+func TestHTTPSink_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	err := NewHTTPSink(srv.URL, "", time.Second).Publish(context.Background(), Envelope{ID: "e1"})
+
+	assert.Error(t, err)
+}
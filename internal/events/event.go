@@ -0,0 +1,99 @@
+package events
+
+import (
+	"encoding/json"
+	"myapp/pkg/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	SessionCreated   Type = "SessionCreated"
+	MessageSaved     Type = "MessageSaved"
+	CompletionFailed Type = "CompletionFailed"
+	ContentFlagged   Type = "ContentFlagged"
+)
+
+// Event service'in ürettiği domain event'idir. Payload outbox'a yazılırken
+// JSON'a çevrilir; pointer verilirse o anki (ör. DB'nin atadığı ID'li) değer yazılır.
+type Event struct {
+	ID         string
+	Type       Type
+	SessionID  string
+	OccurredAt time.Time
+	Payload    any
+}
+
+func New(t Type, sessionID string, payload any) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       t,
+		SessionID:  sessionID,
+		OccurredAt: time.Now(),
+		Payload:    payload,
+	}
+}
+
+// Envelope sink'lere giden, tüketicilerin gördüğü event biçimidir. Teslimat en
+// az bir kezdir; tüketiciler ID ile tekrarları ayıklamalıdır.
+type Envelope struct {
+	ID         string          `json:"id"`
+	Type       Type            `json:"type"`
+	SessionID  string          `json:"sessionId"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Record outbox tablosundaki satırdır; event'i üreten kayıtla aynı transaction'da yazılır.
+type Record struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"` // yayın sırası
+	EventID   string `gorm:"size:36;uniqueIndex"`
+	Type      Type   `gorm:"size:64"`
+	SessionID string `gorm:"size:64"`
+	// UserID event'i doğuran turn'ün kullanıcısıdır; kullanıcı silinirken
+	// payload'ında onun içeriği olan kayıtlar buna göre bulunur. Yayınlanmaz.
+	UserID        string        `gorm:"size:255;index"`
+	Payload       database.JSON // postgres'te JSONB
+	OccurredAt    time.Time
+	PublishedAt   *time.Time `gorm:"index:idx_outbox_pending,priority:1"`
+	NextAttemptAt time.Time  `gorm:"index:idx_outbox_pending,priority:2"`
+	Attempts      int
+	LastError     string
+}
+
+func (Record) TableName() string {
+	return "outbox_events"
+}
+
+// NewRecords event'leri outbox satırlarına çevirir.
+func NewRecords(evs []Event) ([]Record, error) {
+	records := make([]Record, 0, len(evs))
+	for _, ev := range evs {
+		payload, err := json.Marshal(ev.Payload)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, Record{
+			EventID:       ev.ID,
+			Type:          ev.Type,
+			SessionID:     ev.SessionID,
+			Payload:       payload,
+			OccurredAt:    ev.OccurredAt,
+			NextAttemptAt: ev.OccurredAt,
+		})
+	}
+	return records, nil
+}
+
+func (r Record) Envelope() Envelope {
+	return Envelope{
+		ID:         r.EventID,
+		Type:       r.Type,
+		SessionID:  r.SessionID,
+		OccurredAt: r.OccurredAt,
+		Payload:    json.RawMessage(r.Payload),
+	}
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"myapp/pkg/webhook"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEventID   = "X-Event-ID"
+	HeaderEventType = "X-Event-Type"
+)
+
+type httpSink struct {
+	url    string
+	secret string
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewHTTPSink envelope'u JSON olarak url'e POST eder; secret verilirse istek
+// job webhook'larıyla aynı şekilde imzalanır. 2xx dışı cevaplar hatadır.
+func NewHTTPSink(url, secret string, timeout time.Duration) Sink {
+	return &httpSink{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+	}
+}
+
+func (s *httpSink) Name() string {
+	return "http"
+}
+
+func (s *httpSink) Publish(ctx context.Context, env Envelope) error {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, env.ID)
+	req.Header.Set(HeaderEventType, string(env.Type))
+	if s.secret != "" {
+		ts := s.now().Unix()
+		req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(ts, 10))
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(s.secret, ts, body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("event webhook responded with %d", res.StatusCode)
+	}
+	return nil
+}
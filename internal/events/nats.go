@@ -0,0 +1,43 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+)
+
+type natsSink struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSSink event'leri "<prefix>.<Type>" subject'ine yayınlar (ör.
+// chat.events.MessageSaved). Nats-Msg-Id header'ı event ID'sidir; JetStream
+// stream'i varsa tekrar gelen event'leri kendisi ayıklar.
+func NewNATSSink(conn *nats.Conn, prefix string) Sink {
+	return &natsSink{conn: conn, prefix: prefix}
+}
+
+func (s *natsSink) Name() string {
+	return "nats"
+}
+
+func (s *natsSink) Publish(ctx context.Context, env Envelope) error {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(s.prefix + "." + string(env.Type))
+	msg.Data = body
+	msg.Header.Set(nats.MsgIdHdr, env.ID)
+	if err := s.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// flush sunucunun mesajı aldığını garanti eder; yoksa outbox kaydı
+	// mesaj buffer'da beklerken yayınlandı sayılabilir
+	if _, ok := ctx.Deadline(); !ok {
+		return s.conn.Flush()
+	}
+	return s.conn.FlushWithContext(ctx)
+}
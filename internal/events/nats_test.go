@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runNATS testler için process içinde, rastgele portta bir NATS sunucusu açar.
+func runNATS(t *testing.T) *server.Server {
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	require.NoError(t, err)
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestNATSSink(t *testing.T) {
+	//arrange
+	srv := runNATS(t)
+	nc, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+	sub, err := nc.SubscribeSync("chat.events.>")
+	require.NoError(t, err)
+	env := Envelope{ID: "e1", Type: CompletionFailed, SessionID: "s1", Payload: json.RawMessage(`{"code":"timeout"}`)}
+
+	//act
+	err = NewNATSSink(nc, "chat.events").Publish(context.Background(), env)
+
+	//assert
+	require.NoError(t, err)
+	msg, err := sub.NextMsg(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "chat.events.CompletionFailed", msg.Subject)
+	assert.Equal(t, "e1", msg.Header.Get(nats.MsgIdHdr))
+	var got Envelope
+	require.NoError(t, json.Unmarshal(msg.Data, &got))
+	assert.Equal(t, "s1", got.SessionID)
+	assert.JSONEq(t, `{"code":"timeout"}`, string(got.Payload))
+}
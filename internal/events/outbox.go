@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Outbox dispatcher'ın yayınlanmamış kayıtları okuduğu depodur.
+type Outbox interface {
+	// Claim zamanı gelmiş en fazla limit kaydı ID sırasıyla lease süresince
+	// bu dispatcher'a ayırır; başka replica aynı kaydı o sürede almaz.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Record, error)
+	MarkPublished(ctx context.Context, id uint64, at time.Time) error
+	MarkFailed(ctx context.Context, id uint64, next time.Time, reason string) error
+	// Backlog henüz yayınlanmamış kayıt sayısıdır.
+	Backlog(ctx context.Context) (int64, error)
+}
+
+type gormOutbox struct {
+	db *gorm.DB
+}
+
+func NewGormOutbox(db *gorm.DB) Outbox {
+	return &gormOutbox{db: db}
+}
+
+func (o *gormOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Record, error) {
+	db := o.db.WithContext(ctx)
+	var candidates []Record
+	err := db.Where("published_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").Limit(limit).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := candidates[:0]
+	for _, r := range candidates {
+		// attempts her claim'de arttığı için aynı kaydı iki dispatcher alamaz
+		res := db.Model(&Record{}).
+			Where("id = ? AND attempts = ? AND published_at IS NULL", r.ID, r.Attempts).
+			Updates(map[string]any{
+				"attempts":        r.Attempts + 1,
+				"next_attempt_at": now.Add(lease),
+			})
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			r.Attempts++
+			claimed = append(claimed, r)
+		}
+	}
+	return claimed, nil
+}
+
+func (o *gormOutbox) MarkPublished(ctx context.Context, id uint64, at time.Time) error {
+	return o.db.WithContext(ctx).Model(&Record{}).Where("id = ?", id).
+		Updates(map[string]any{"published_at": at, "last_error": ""}).Error
+}
+
+func (o *gormOutbox) MarkFailed(ctx context.Context, id uint64, next time.Time, reason string) error {
+	return o.db.WithContext(ctx).Model(&Record{}).Where("id = ?", id).
+		Updates(map[string]any{"next_attempt_at": next, "last_error": reason}).Error
+}
+
+func (o *gormOutbox) Backlog(ctx context.Context) (int64, error) {
+	var n int64
+	err := o.db.WithContext(ctx).Model(&Record{}).Where("published_at IS NULL").Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Sink outbox'tan çıkan event'leri bir hedefe yayınlar. Hata dönerse event
+// daha sonra tekrar denenir; tüm sink'lere yeniden gönderilir.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, env Envelope) error
+}
+
+// Handler Bus'a abone olan process içi tüketicidir.
+type Handler func(ctx context.Context, env Envelope) error
+
+// Bus process içi sink'tir; abonelere event'i senkron olarak dağıtır.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[Type][]Handler)}
+}
+
+// Subscribe t boşsa handler tüm event tiplerini alır.
+func (b *Bus) Subscribe(t Type, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[t] = append(b.handlers[t], h)
+}
+
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+func (b *Bus) Publish(ctx context.Context, env Envelope) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[env.Type]...), b.handlers[""]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, h := range handlers {
+		if err := h(ctx, env); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"myapp/pkg/migrate"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Checker tek bir bağımlılığı kontrol eder; nil dönmesi kullanılabilir olduğunu gösterir.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type funcChecker struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func CheckFunc(name string, fn func(ctx context.Context) error) Checker {
+	return &funcChecker{name: name, fn: fn}
+}
+
+func (c *funcChecker) Name() string {
+	return c.name
+}
+
+func (c *funcChecker) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// Database connection pool'dan bir bağlantıyla ping atar.
+func Database(db *gorm.DB) Checker {
+	return CheckFunc("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// Migrations bekleyen migration varsa ya da uygulanmış biri sonradan
+// değiştirildiyse hata döner.
+func Migrations(m *migrate.Migrator) Checker {
+	return CheckFunc("migrations", func(ctx context.Context) error {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, next: %s", len(pending), pending[0])
+		}
+		return nil
+	})
+}
+
+type cachedChecker struct {
+	next Checker
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// Cached sonucu (hata dahil) ttl boyunca saklar. Readiness sık çağrılır; LLM
+// provider'larına her seferinde istek atılmasın diye kullanılır.
+func Cached(next Checker, ttl time.Duration) Checker {
+	return &cachedChecker{next: next, ttl: ttl, now: time.Now}
+}
+
+func (c *cachedChecker) Name() string {
+	return c.next.Name()
+}
+
+func (c *cachedChecker) Check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checkedAt.IsZero() && c.now().Sub(c.checkedAt) < c.ttl {
+		return c.err
+	}
+	c.err = c.next.Check(ctx)
+	c.checkedAt = c.now()
+	return c.err
+}
+
+// AnyOf en az bir alt kontrol başarılıysa başarılıdır; failover zincirindeki
+// provider'lardan biri ayaktaysa LLM kullanılabilir sayılır.
+func AnyOf(name string, checks ...Checker) Checker {
+	return &anyOf{name: name, checks: checks}
+}
+
+type anyOf struct {
+	name   string
+	checks []Checker
+}
+
+func (a *anyOf) Name() string {
+	return a.name
+}
+
+func (a *anyOf) Check(ctx context.Context) error {
+	return a.check(ctx, nil)
+}
+
+// check alt sonuçları report'a da yazar.
+func (a *anyOf) check(ctx context.Context, report func(Result)) error {
+	var failed []string
+	for _, c := range a.checks {
+		res := run(ctx, c)
+		if report != nil {
+			report(res)
+		}
+		if res.Status != StatusOK {
+			failed = append(failed, c.Name())
+		}
+	}
+	if len(failed) == len(a.checks) {
+		return fmt.Errorf("all unavailable: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
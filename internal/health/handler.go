@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Result bir bağımlılığın kontrol sonucudur.
+type Result struct {
+	Name      string   `json:"name"`
+	Status    string   `json:"status"`
+	Error     string   `json:"error,omitempty"`
+	LatencyMs int64    `json:"latencyMs"`
+	Details   []Result `json:"details,omitempty"`
+}
+
+// Report /readyz cevabıdır.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type Handler interface {
+	Healthz(c echo.Context) error
+	Readyz(c echo.Context) error
+	// ShuttingDown çağrıldıktan sonra /readyz 503 döner ki load balancer yeni
+	// istek göndermeyi bıraksın.
+	ShuttingDown()
+}
+
+type handler struct {
+	checks   []Checker
+	timeout  time.Duration
+	stopping atomic.Bool
+}
+
+// NewHandler timeout tüm kontrollerin toplam süresidir; kontroller paralel çalışır.
+func NewHandler(timeout time.Duration, checks ...Checker) Handler {
+	return &handler{checks: checks, timeout: timeout}
+}
+
+// Healthz process ayaktaysa 200 döner; bağımlılıklara bakmaz.
+func (h *handler) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"status": StatusOK})
+}
+
+func (h *handler) Readyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), h.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(h.checks))}
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	if h.stopping.Load() {
+		report.Status = StatusFail
+		report.Checks = append(report.Checks, Result{Name: "shutdown", Status: StatusFail, Error: "shutting down"})
+	}
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, report)
+}
+
+func (h *handler) ShuttingDown() {
+	h.stopping.Store(true)
+}
+
+func run(ctx context.Context, c Checker) Result {
+	start := time.Now()
+	res := Result{Name: c.Name(), Status: StatusOK}
+	var err error
+	if group, ok := c.(*anyOf); ok {
+		err = group.check(ctx, func(r Result) { res.Details = append(res.Details, r) })
+	} else {
+		err = c.Check(ctx)
+	}
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"myapp/pkg/database"
+	"myapp/pkg/migrate"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(name string) Checker {
+	return CheckFunc(name, func(context.Context) error { return nil })
+}
+
+func failing(name string) Checker {
+	return CheckFunc(name, func(context.Context) error { return errors.New(name + " down") })
+}
+
+func readyz(t *testing.T, h Handler) (int, Report) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), rec)
+	assert.NoError(t, h.Readyz(c))
+	var report Report
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, report
+}
+
+func TestReadyz_AllHealthy(t *testing.T) {
+	code, report := readyz(t, NewHandler(time.Second, ok("database"), ok("migrations")))
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, "database", report.Checks[0].Name)
+}
+
+func TestReadyz_DependencyDown(t *testing.T) {
+	//arrange
+	h := NewHandler(time.Second, failing("database"), ok("migrations"))
+
+	//act
+	code, report := readyz(t, h)
+
+	//assert
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusFail, report.Checks[0].Status)
+	assert.Equal(t, "database down", report.Checks[0].Error)
+	assert.Equal(t, StatusOK, report.Checks[1].Status)
+}
+
+func TestReadyz_AnyProviderIsEnough(t *testing.T) {
+	llm := AnyOf("llm", failing("llm:openai"), ok("llm:local"))
+
+	code, report := readyz(t, NewHandler(time.Second, llm))
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, report.Checks[0].Details, 2)
+	assert.Equal(t, StatusFail, report.Checks[0].Details[0].Status)
+	assert.Equal(t, StatusOK, report.Checks[0].Details[1].Status)
+
+	code, report = readyz(t, NewHandler(time.Second, AnyOf("llm", failing("llm:openai"))))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, report.Checks[0].Error, "llm:openai")
+}
+
+func TestReadyz_ShuttingDown(t *testing.T) {
+	h := NewHandler(time.Second, ok("database"))
+	h.ShuttingDown()
+
+	code, report := readyz(t, h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutdown", report.Checks[1].Name)
+}
+
+func TestReadyz_Timeout(t *testing.T) {
+	slow := CheckFunc("llm", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, report := readyz(t, NewHandler(10*time.Millisecond, slow))
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestCached(t *testing.T) {
+	//arrange
+	calls := 0
+	probe := CheckFunc("llm:openai", func(context.Context) error {
+		calls++
+		return errors.New("unreachable")
+	})
+	c := Cached(probe, time.Minute).(*cachedChecker)
+	now := time.Unix(1756212819, 0)
+	c.now = func() time.Time { return now }
+
+	//act
+	err1 := c.Check(context.Background())
+	err2 := c.Check(context.Background())
+	now = now.Add(time.Minute)
+	c.Check(context.Background())
+
+	//assert
+	assert.Error(t, err1)
+	assert.Equal(t, err1, err2)
+	assert.Equal(t, 2, calls)
+}
+
+func TestHealthz(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/healthz", nil), rec)
+
+	err := NewHandler(time.Second, failing("database")).Healthz(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestMigrations_Pending(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	db, err := database.Connect(database.Config{Driver: database.SQLite})
+	require.NoError(t, err)
+	m := migrate.New(db, []migrate.Migration{{Version: 1, Name: "notes", Up: "CREATE TABLE notes (id integer);", Checksum: "c1"}}, 0)
+	check := Migrations(m)
+
+	//act
+	before := check.Check(ctx)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	after := check.Check(ctx)
+
+	//assert
+	assert.ErrorContains(t, before, "1 pending migrations, next: 0001_notes")
+	assert.NoError(t, after)
+}
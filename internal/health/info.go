@@ -0,0 +1,58 @@
+package health
+
+import (
+	"database/sql"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/labstack/echo"
+	"gorm.io/gorm"
+)
+
+// Version build sırasında verilir: go build -ldflags "-X myapp/internal/health.Version=v1.2.3"
+var Version = "dev"
+
+var startedAt = time.Now()
+
+// Info GET /debug/info cevabıdır.
+type Info struct {
+	Version    string      `json:"version"`
+	Revision   string      `json:"revision,omitempty"`
+	BuildTime  string      `json:"buildTime,omitempty"`
+	GoVersion  string      `json:"goVersion"`
+	StartedAt  time.Time   `json:"startedAt"`
+	Uptime     string      `json:"uptime"`
+	Goroutines int         `json:"goroutines"`
+	Config     any         `json:"config"`
+	DBPool     sql.DBStats `json:"dbPool"`
+}
+
+// NewInfoHandler config'in secret'ları temizlenmiş halini almalıdır.
+func NewInfoHandler(config any, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		info := Info{
+			Version:    Version,
+			GoVersion:  runtime.Version(),
+			StartedAt:  startedAt,
+			Uptime:     time.Since(startedAt).Round(time.Second).String(),
+			Goroutines: runtime.NumGoroutine(),
+			Config:     config,
+		}
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					info.Revision = s.Value
+				case "vcs.time":
+					info.BuildTime = s.Value
+				}
+			}
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			info.DBPool = sqlDB.Stats()
+		}
+		return c.JSON(http.StatusOK, info)
+	}
+}
@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"myapp/pkg/encryption"
+
+	"gorm.io/gorm"
+)
+
+// jobAAD ciphertext'i tenant'a ve job'a bağlar; başka bir job'ın satırına
+// kopyalanan ciphertext açılmaz.
+func jobAAD(job *Job) []byte {
+	return []byte(job.TenantID + "/" + job.ID)
+}
+
+// sealJob prompt'u ve cevabı şifrelenmiş bir kopya döner; job değişmez.
+func sealJob(ctx context.Context, keys encryption.Keyring, job *Job) (*Job, error) {
+	version, key, err := keys.Current(ctx, job.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return sealWith(job, version, key)
+}
+
+func sealWith(job *Job, version int, key []byte) (*Job, error) {
+	message, err := encryption.Seal(key, []byte(job.Message), jobAAD(job))
+	if err != nil {
+		return nil, err
+	}
+	result, err := encryption.Seal(key, []byte(job.Result), jobAAD(job))
+	if err != nil {
+		return nil, err
+	}
+	row := *job
+	row.Message = message
+	row.Result = result
+	row.KeyVersion = version
+	return &row, nil
+}
+
+// openJob KeyVersion sıfırsa (şifrelenmemiş eski satır) job'ı olduğu gibi bırakır.
+func openJob(ctx context.Context, keys encryption.Keyring, job *Job) error {
+	if job.KeyVersion == 0 {
+		return nil
+	}
+	key, err := keys.Key(ctx, job.TenantID, job.KeyVersion)
+	if err != nil {
+		return err
+	}
+	message, err := encryption.Open(key, job.Message, jobAAD(job))
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.ID, err)
+	}
+	result, err := encryption.Open(key, job.Result, jobAAD(job))
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.ID, err)
+	}
+	job.Message = string(message)
+	job.Result = string(result)
+	job.KeyVersion = 0
+	return nil
+}
+
+type sealedTable struct {
+	db   *gorm.DB
+	keys encryption.Keyring
+}
+
+// NewSealedTable re-encryption job'ının chat_jobs'u da tenant'ın güncel key'ine
+// taşıması içindir.
+func NewSealedTable(db *gorm.DB, keys encryption.Keyring) encryption.Table {
+	return &sealedTable{db: db, keys: keys}
+}
+
+func (t *sealedTable) PlainTenants(ctx context.Context) ([]string, error) {
+	var tenants []string
+	err := t.db.WithContext(ctx).Model(&Job{}).
+		Where("key_version = 0").Distinct().Pluck("tenant_id", &tenants).Error
+	return tenants, err
+}
+
+func (t *sealedTable) Reencrypt(ctx context.Context, tenantID string, version int, key []byte, batchSize int) (int, error) {
+	total, lastID := 0, ""
+	for {
+		var rows []Job
+		err := t.db.WithContext(ctx).
+			Where("tenant_id = ? AND key_version <> ? AND id > ?", tenantID, version, lastID).
+			Order("id").Limit(batchSize).Find(&rows).Error
+		if err != nil {
+			return total, err
+		}
+		for i := range rows {
+			lastID = rows[i].ID
+			old := rows[i]
+			if err := openJob(ctx, t.keys, &rows[i]); err != nil {
+				return total, err
+			}
+			sealed, err := sealWith(&rows[i], version, key)
+			if err != nil {
+				return total, err
+			}
+			// worker bu arada job'ı yazdıysa ciphertext değişmiştir; satıra dokunulmaz
+			res := t.db.WithContext(ctx).Model(&Job{}).
+				Where("id = ? AND key_version = ? AND message = ? AND result = ?", old.ID, old.KeyVersion, old.Message, old.Result).
+				UpdateColumns(map[string]any{"message": sealed.Message, "result": sealed.Result, "key_version": version})
+			if res.Error != nil {
+				return total, res.Error
+			}
+			total += int(res.RowsAffected)
+		}
+		if len(rows) < batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"myapp/internal/migrations"
+	"myapp/pkg/database"
+	"myapp/pkg/encryption"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Connect(database.Config{Driver: database.SQLite, DSN: ":memory:"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	m, err := migrations.New(db, 0)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func testKeyring(t *testing.T) encryption.Keyring {
+	t.Helper()
+	kms, err := encryption.NewLocalKMS("m1", map[string][]byte{"m1": bytes.Repeat([]byte{1}, encryption.KeySize)})
+	require.NoError(t, err)
+	return encryption.NewKeyring(encryption.NewMemoryStore(), kms, time.Minute)
+}
+
+func TestGormStore_EncryptsMessageAndResult(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := NewGormStore(db, WithEncryption(testKeyring(t)))
+	job := &Job{ID: "j1", Status: StatusQueued, Message: "gizli soru", TenantID: "acme", UserID: "u1"}
+
+	//act
+	require.NoError(t, store.Create(ctx, job))
+	job.Result = "gizli cevap"
+	require.NoError(t, store.Update(ctx, job))
+	got, err := store.Get(ctx, "j1")
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, "gizli soru", got.Message)
+	assert.Equal(t, "gizli cevap", got.Result)
+	assert.Equal(t, "gizli soru", job.Message, "caller's job stays plaintext")
+
+	var row Job
+	require.NoError(t, db.First(&row, "id = ?", "j1").Error)
+	assert.NotContains(t, row.Message, "gizli")
+	assert.NotContains(t, row.Result, "gizli")
+	assert.Equal(t, 1, row.KeyVersion)
+
+	listed, err := store.ListByUser(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "gizli cevap", listed[0].Result)
+}
+
+func TestSealedTable_EncryptsLegacyAndRotatedJobs(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	db := newTestDB(t)
+	keys := testKeyring(t)
+	require.NoError(t, NewGormStore(db).Create(ctx, &Job{ID: "j1", Message: "eski", TenantID: "acme"}))
+	store := NewGormStore(db, WithEncryption(keys))
+	require.NoError(t, store.Create(ctx, &Job{ID: "j2", Message: "yeni", TenantID: "acme"}))
+	table := NewSealedTable(db, keys)
+
+	//act
+	tenants, err := table.PlainTenants(ctx)
+	require.NoError(t, err)
+	version, key, err := keys.Current(ctx, "acme")
+	require.NoError(t, err)
+	n, err := table.Reencrypt(ctx, "acme", version, key, 1)
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme"}, tenants)
+	assert.Equal(t, 1, n)
+
+	version, err = keys.Rotate(ctx, "acme")
+	require.NoError(t, err)
+	_, key, err = keys.Current(ctx, "acme")
+	require.NoError(t, err)
+	n, err = table.Reencrypt(ctx, "acme", version, key, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	var versions []int
+	require.NoError(t, db.Model(&Job{}).Order("id").Pluck("key_version", &versions).Error)
+	assert.Equal(t, []int{2, 2}, versions)
+	old, err := store.Get(ctx, "j1")
+	require.NoError(t, err)
+	assert.Equal(t, "eski", old.Message)
+}
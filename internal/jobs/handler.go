@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"errors"
+	"myapp/internal/chat"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
+	"myapp/pkg/webhook"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+// Request POST v1/chat/async gövdesidir; WebhookURL verilirse sonuç oraya da gönderilir.
+type Request struct {
+	Message    string
+	SessionID  string
+	WebhookURL string
+}
+
+type Handler interface {
+	Enqueue(c echo.Context) error
+	Show(c echo.Context) error
+}
+
+type handler struct {
+	store        Store
+	webhooks     bool
+	allowPrivate bool
+}
+
+// NewHandler webhooks false ise (imza secret'ı yoksa) webhookUrl'li istekler
+// reddedilir. allowPrivate false ise localhost ve iç IP adresli URL'ler de
+// reddedilir; adı iç adrese çözülen host'ları deliverer bağlanırken engeller.
+func NewHandler(store Store, webhooks, allowPrivate bool) Handler {
+	return &handler{
+		store:        store,
+		webhooks:     webhooks,
+		allowPrivate: allowPrivate,
+	}
+}
+
+func (h *handler) Enqueue(c echo.Context) error {
+	ctx := c.Request().Context()
+	log := logger.FromContext(ctx)
+	input := new(Request)
+	if err := c.Bind(input); err != nil {
+		log.Warn("failed to bind request", zap.Error(err))
+		return chat.ErrInvalidRequest
+	}
+	if input.SessionID != "" {
+		c.Set(middleware.SessionIDKey, input.SessionID)
+	}
+	if err := chat.ValidateInput(input.SessionID, input.Message); err != nil {
+		log.Warn("invalid chat input", zap.Error(err))
+		return err
+	}
+	if input.WebhookURL != "" {
+		if !h.webhooks {
+			return echo.NewHTTPError(http.StatusBadRequest, "webhooks are not enabled")
+		}
+		if !validWebhookURL(input.WebhookURL, h.allowPrivate) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid webhookUrl")
+		}
+	}
+
+	id := identity.FromContext(ctx)
+	job := Job{
+		ID:         uuid.New().String(),
+		Status:     StatusQueued,
+		SessionID:  input.SessionID,
+		Message:    input.Message,
+		UserID:     id.UserID,
+		TenantID:   id.TenantID,
+		Tier:       id.Tier,
+		Persona:    id.Persona,
+		RequestID:  middleware.RequestIDFromContext(ctx),
+		WebhookURL: input.WebhookURL,
+	}
+	if err := h.store.Create(ctx, &job); err != nil {
+		log.Error("failed to enqueue job", zap.Error(err))
+		return chat.ErrStorage
+	}
+	log.Info("job enqueued", zap.String("jobID", job.ID))
+
+	c.Response().Header().Set(echo.HeaderLocation, "/v1/jobs/"+job.ID)
+	return c.JSON(http.StatusAccepted, NewView(job))
+}
+
+func (h *handler) Show(c echo.Context) error {
+	ctx := c.Request().Context()
+	job, err := h.store.Get(ctx, c.Param("id"))
+	if errors.Is(err, ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "job not found")
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to load job", zap.Error(err))
+		return chat.ErrStorage
+	}
+	// başkasının job'ının varlığı da sızmasın
+	if job.UserID != "" && job.UserID != identity.FromContext(ctx).UserID {
+		return echo.NewHTTPError(http.StatusNotFound, "job not found")
+	}
+	return c.JSON(http.StatusOK, NewView(job))
+}
+
+func validWebhookURL(raw string, allowPrivate bool) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return false
+	}
+	if allowPrivate {
+		return true
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return webhook.PublicAddr(ip)
+	}
+	return true
+}
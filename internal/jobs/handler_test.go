@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"myapp/internal/chat"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func serve(t *testing.T, store Store, webhooks bool, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	logger.Log = zap.NewNop()
+	e := echo.New()
+	e.HTTPErrorHandler = chat.HTTPErrorHandler
+	e.Use(identity.Middleware())
+	h := NewHandler(store, webhooks, false)
+	e.POST("/v1/chat/async", h.Enqueue)
+	e.GET("/v1/jobs/:id", h.Show)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEnqueue_Success(t *testing.T) {
+	//arrange
+	store := NewMockStore(gomock.NewController(t))
+	var created Job
+	store.EXPECT().Create(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { created = *j }).Return(nil)
+
+	//act
+	rec := serve(t, store, true, http.MethodPost, "/v1/chat/async",
+		`{"Message":"merhaba","WebhookURL":"https://example.com/hook"}`, map[string]string{identity.HeaderUserID: "u1"})
+
+	//assert
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var view View
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &view))
+	assert.Equal(t, created.ID, view.ID)
+	assert.Equal(t, StatusQueued, view.Status)
+	assert.Equal(t, "/v1/jobs/"+created.ID, rec.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, "u1", created.UserID)
+	assert.Equal(t, "https://example.com/hook", created.WebhookURL)
+}
+
+func TestEnqueue_InvalidMessage(t *testing.T) {
+	store := NewMockStore(gomock.NewController(t))
+
+	rec := serve(t, store, true, http.MethodPost, "/v1/chat/async", `{"Message":"a"}`, nil)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"invalid_message"`)
+}
+
+func TestEnqueue_WebhookRejected(t *testing.T) {
+	store := NewMockStore(gomock.NewController(t))
+
+	disabled := serve(t, store, false, http.MethodPost, "/v1/chat/async", `{"Message":"merhaba","WebhookURL":"https://example.com"}`, nil)
+	invalid := serve(t, store, true, http.MethodPost, "/v1/chat/async", `{"Message":"merhaba","WebhookURL":"ftp://example.com"}`, nil)
+
+	assert.Equal(t, http.StatusBadRequest, disabled.Code)
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+}
+
+func TestEnqueue_InternalWebhookRejected(t *testing.T) {
+	store := NewMockStore(gomock.NewController(t))
+	for _, u := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]/hook", "http://10.0.0.5/hook", "http://localhost/hook"} {
+		rec := serve(t, store, true, http.MethodPost, "/v1/chat/async", `{"Message":"merhaba","WebhookURL":"`+u+`"}`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, u)
+	}
+}
+
+func TestShow(t *testing.T) {
+	store := NewMockStore(gomock.NewController(t))
+	job := Job{ID: "j1", Status: StatusFailed, UserID: "u1", ErrorCode: "session_not_found", Error: "sessionId not found"}
+	store.EXPECT().Get(gomock.Any(), "j1").Return(job, nil).Times(2)
+	store.EXPECT().Get(gomock.Any(), "nope").Return(Job{}, ErrNotFound)
+
+	own := serve(t, store, true, http.MethodGet, "/v1/jobs/j1", "", map[string]string{identity.HeaderUserID: "u1"})
+	other := serve(t, store, true, http.MethodGet, "/v1/jobs/j1", "", map[string]string{identity.HeaderUserID: "u2"})
+	missing := serve(t, store, true, http.MethodGet, "/v1/jobs/nope", "", nil)
+
+	assert.Equal(t, http.StatusOK, own.Code)
+	assert.JSONEq(t, `{"id":"j1","status":"failed","error":{"code":"session_not_found","message":"sessionId not found"},"createdAt":"0001-01-01T00:00:00Z","updatedAt":"0001-01-01T00:00:00Z"}`, own.Body.String())
+	assert.Equal(t, http.StatusNotFound, other.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
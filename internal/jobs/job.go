@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+type WebhookStatus string
+
+const (
+	WebhookNone      WebhookStatus = ""
+	WebhookPending   WebhookStatus = "pending"
+	WebhookDelivered WebhookStatus = "delivered"
+	WebhookFailed    WebhookStatus = "failed"
+)
+
+// Job POST v1/chat/async ile kuyruğa alınan bir chat turn'üdür. Kuyruk DB'de
+// durduğu için restart'ta kaybolmaz; LockedUntil'i geçmiş running job'lar
+// başka bir worker tarafından tekrar alınır.
+type Job struct {
+	ID        string `gorm:"primaryKey;size:36"`
+	Status    Status `gorm:"size:16;index:idx_jobs_claim,priority:1"`
+	SessionID string `gorm:"size:64"`
+	Message   string
+	Result    string // LLM cevabı
+	ErrorCode string `gorm:"size:64"`
+	Error     string
+	Attempts  int // worker'ın job'ı kaç kez aldığı
+	// PromptSeq prompt kaydedilince, LLM çağrısından önce yazılır. Turn
+	// shutdown ya da çökme yüzünden yarıda kaldıysa job tekrar alınınca prompt
+	// yeniden gönderilmez, turn Resume ile devam ettirilir.
+	PromptSeq int64
+	// KeyVersion Message ve Result'ı şifreleyen tenant key'inin versiyonudur;
+	// 0 ise düz metindir. Store dışında her zaman 0'dır.
+	KeyVersion int `gorm:"not null;default:0" json:"-"`
+
+	// turn worker'da çalışırken routing vb. için isteği yapanın kimliği
+	UserID    string `gorm:"size:255"`
+	TenantID  string `gorm:"size:255"`
+	Tier      string `gorm:"size:64"`
+	Persona   string `gorm:"size:64"`
+	RequestID string `gorm:"size:128"`
+
+	WebhookURL      string
+	WebhookStatus   WebhookStatus `gorm:"size:16;index:idx_jobs_webhook,priority:1"`
+	WebhookAttempts int
+	NextWebhookAt   *time.Time `gorm:"index:idx_jobs_webhook,priority:2"`
+
+	LockedUntil *time.Time `gorm:"index:idx_jobs_claim,priority:2"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (Job) TableName() string {
+	return "chat_jobs"
+}
+
+// View GET v1/jobs/:id cevabı ve webhook payload'ıdır.
+type View struct {
+	ID            string        `json:"id"`
+	Status        Status        `json:"status"`
+	SessionID     string        `json:"sessionId,omitempty"`
+	Message       string        `json:"message,omitempty"`
+	Error         *ViewError    `json:"error,omitempty"`
+	WebhookStatus WebhookStatus `json:"webhookStatus,omitempty"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
+}
+
+type ViewError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewView(job Job) View {
+	v := View{
+		ID:            job.ID,
+		Status:        job.Status,
+		SessionID:     job.SessionID,
+		Message:       job.Result,
+		WebhookStatus: job.WebhookStatus,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+	}
+	if job.Status == StatusFailed {
+		v.Error = &ViewError{Code: job.ErrorCode, Message: job.Error}
+	}
+	return v
+}
+
+var (
+	ErrNotFound = errors.New("job not found")
+	// ErrNoJob Claim'de alınacak iş olmadığında döner.
+	ErrNoJob = errors.New("no job available")
+)
+
+type Store interface {
+	Create(ctx context.Context, job *Job) error
+	Get(ctx context.Context, id string) (Job, error)
+	// Claim sıradaki queued job'ı (ya da lease'i dolmuş running job'ı) lease
+	// süresince bu worker'a ayırır ve Attempts'i artırır.
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (Job, error)
+	// ClaimWebhook zamanı gelmiş pending webhook'u lease süresince ayırır ve
+	// WebhookAttempts'i artırır.
+	ClaimWebhook(ctx context.Context, now time.Time, lease time.Duration) (Job, error)
+	Update(ctx context.Context, job *Job) error
+	// Depth işlenmeyi bekleyen (queued) job sayısıdır.
+	Depth(ctx context.Context) (int64, error)
+	ListByUser(ctx context.Context, userID string) ([]Job, error)
+	// DeleteByUser kullanıcının job'larını kalıcı olarak siler ve sayısını döner.
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
@@ -0,0 +1,179 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"myapp/pkg/encryption"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormStore struct {
+	db   *gorm.DB
+	keys encryption.Keyring
+}
+
+// StoreOption NewGormStore'un opsiyonel ayarlarıdır.
+type StoreOption func(*gormStore)
+
+// WithEncryption Message ve Result'ı chat mesajları gibi tenant'ın data
+// key'iyle şifreler. Şifresiz eski satırları Reencryptor şifreler.
+func WithEncryption(keys encryption.Keyring) StoreOption {
+	return func(s *gormStore) {
+		s.keys = keys
+	}
+}
+
+// NewGormStore job kuyruğunu chat_jobs tablosunda tutar. Claim'ler koşullu
+// UPDATE ile yapılır; aynı job'ı iki worker (ya da replica) birlikte alamaz.
+func NewGormStore(db *gorm.DB, opts ...StoreOption) Store {
+	s := &gormStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *gormStore) Create(ctx context.Context, job *Job) error {
+	return s.write(ctx, job, func(db *gorm.DB, row *Job) error {
+		return db.Create(row).Error
+	})
+}
+
+// write çağıranın job'ını düz metin bırakır; DB'ye şifrelenmiş kopya gider.
+func (s *gormStore) write(ctx context.Context, job *Job, fn func(db *gorm.DB, row *Job) error) error {
+	row := job
+	if s.keys != nil {
+		sealed, err := sealJob(ctx, s.keys, job)
+		if err != nil {
+			return err
+		}
+		row = sealed
+	}
+	if err := fn(s.db.WithContext(ctx), row); err != nil {
+		return err
+	}
+	job.CreatedAt, job.UpdatedAt = row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+func (s *gormStore) open(ctx context.Context, job *Job) error {
+	if s.keys == nil {
+		return nil
+	}
+	return openJob(ctx, s.keys, job)
+}
+
+func (s *gormStore) Get(ctx context.Context, id string) (Job, error) {
+	var job Job
+	err := s.db.WithContext(ctx).Where("id = ?", id).Take(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Job{}, ErrNotFound
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	err = s.open(ctx, &job)
+	return job, err
+}
+
+func (s *gormStore) Claim(ctx context.Context, now time.Time, lease time.Duration) (Job, error) {
+	db := s.db.WithContext(ctx)
+	var job Job
+	err := db.Where("status = ? OR (status = ? AND locked_until < ?)", StatusQueued, StatusRunning, now).
+		Order("created_at").Take(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Job{}, ErrNoJob
+	}
+	if err != nil {
+		return Job{}, err
+	}
+
+	// Attempts her claim'de arttığı için aynı satırı iki worker güncelleyemez
+	res := db.Model(&Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, job.Status, job.Attempts).
+		Updates(map[string]any{
+			"status":       StatusRunning,
+			"attempts":     job.Attempts + 1,
+			"locked_until": now.Add(lease),
+			"updated_at":   now,
+		})
+	if res.Error != nil {
+		return Job{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return Job{}, ErrNoJob
+	}
+	job.Status = StatusRunning
+	job.Attempts++
+	lockedUntil := now.Add(lease)
+	job.LockedUntil = &lockedUntil
+	job.UpdatedAt = now
+	err = s.open(ctx, &job)
+	return job, err
+}
+
+func (s *gormStore) ClaimWebhook(ctx context.Context, now time.Time, lease time.Duration) (Job, error) {
+	db := s.db.WithContext(ctx)
+	var job Job
+	err := db.Where("webhook_status = ? AND next_webhook_at <= ?", WebhookPending, now).
+		Order("next_webhook_at").Take(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Job{}, ErrNoJob
+	}
+	if err != nil {
+		return Job{}, err
+	}
+
+	res := db.Model(&Job{}).
+		Where("id = ? AND webhook_status = ? AND webhook_attempts = ?", job.ID, WebhookPending, job.WebhookAttempts).
+		Updates(map[string]any{
+			"webhook_attempts": job.WebhookAttempts + 1,
+			"next_webhook_at":  now.Add(lease),
+			"updated_at":       now,
+		})
+	if res.Error != nil {
+		return Job{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return Job{}, ErrNoJob
+	}
+	job.WebhookAttempts++
+	next := now.Add(lease)
+	job.NextWebhookAt = &next
+	job.UpdatedAt = now
+	err = s.open(ctx, &job)
+	return job, err
+}
+
+func (s *gormStore) Update(ctx context.Context, job *Job) error {
+	return s.write(ctx, job, func(db *gorm.DB, row *Job) error {
+		return db.Save(row).Error
+	})
+}
+
+func (s *gormStore) Depth(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.db.WithContext(ctx).Model(&Job{}).Where("status = ?", StatusQueued).Count(&n).Error
+	return n, err
+}
+
+func (s *gormStore) ListByUser(ctx context.Context, userID string) ([]Job, error) {
+	var jobs []Job
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		if err := s.open(ctx, &jobs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+func (s *gormStore) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	res := s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&Job{})
+	return res.RowsAffected, res.Error
+}
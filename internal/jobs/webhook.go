@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"myapp/pkg/webhook"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HeaderWebhookID alıcının tekrar gelen teslimatları ayıklaması içindir.
+const HeaderWebhookID = "X-Webhook-ID"
+
+// Deliverer tamamlanan job'ın payload'ını webhook URL'ine gönderir.
+type Deliverer interface {
+	Deliver(ctx context.Context, url, jobID string, payload []byte) error
+}
+
+type httpDeliverer struct {
+	secret string
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewHTTPDeliverer 2xx dışındaki cevapları hata sayar; tekrar denemeyi Worker yapar.
+// URL'leri client verdiği için allowPrivate false ise loopback, özel ağ ve
+// link-local adreslere bağlanılmaz (bkz. webhook.PublicOnly).
+func NewHTTPDeliverer(secret string, timeout time.Duration, allowPrivate bool) Deliverer {
+	return &httpDeliverer{
+		secret: secret,
+		client: webhook.NewClient(timeout, allowPrivate),
+		now:    time.Now,
+	}
+}
+
+func (d *httpDeliverer) Deliver(ctx context.Context, url, jobID string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	ts := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookID, jobID)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.secret, ts, payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"myapp/internal/chat"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	Workers      int
+	PollInterval time.Duration
+	// JobTimeout tek bir turn'ün (LLM retry'ları dahil) en fazla süresi.
+	JobTimeout time.Duration
+	// MaxAttempts worker'ın job'ı kaç kez alabileceği; process turn ortasında
+	// ölürse job lease dolunca tekrar alınır, sınır aşılınca failed olur.
+	MaxAttempts int
+
+	WebhookMaxAttempts int
+	WebhookBaseDelay   time.Duration
+	WebhookTimeout     time.Duration
+}
+
+// ErrJobAbandoned job MaxAttempts kez alınıp hiç bitirilemediğinde job'a yazılan hatadır.
+var ErrJobAbandoned = &chat.Error{Code: "job_abandoned", Message: "job could not be completed"}
+
+// leaseMargin lease'in job timeout'undan ne kadar uzun tutulduğu; süresi dolan
+// turn kaydedilmeden başka worker job'ı almasın.
+const leaseMargin = 30 * time.Second
+
+type Worker struct {
+	store     Store
+	service   chat.Service
+	deliverer Deliverer
+	cfg       Config
+	now       func() time.Time
+	wg        sync.WaitGroup
+}
+
+// NewWorker deliverer nil ise webhook'lar gönderilmez.
+func NewWorker(store Store, service chat.Service, deliverer Deliverer, cfg Config) *Worker {
+	return &Worker{
+		store:     store,
+		service:   service,
+		deliverer: deliverer,
+		cfg:       cfg,
+		now:       time.Now,
+	}
+}
+
+// Start worker goroutine'lerini başlatır; ctx iptal edilince yeni job almayı
+// bırakırlar. Çalışan turn'ler ctx'ten bağımsız olarak JobTimeout'a kadar sürer,
+// Wait hepsinin bitmesini bekler.
+func (w *Worker) Start(ctx context.Context) {
+	for i := 0; i < w.cfg.Workers; i++ {
+		w.wg.Add(1)
+		go w.loop(ctx, w.processNext)
+	}
+	if w.deliverer != nil {
+		w.wg.Add(1)
+		go w.loop(ctx, w.deliverNext)
+	}
+}
+
+func (w *Worker) Wait() {
+	w.wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context, next func(context.Context) (bool, error)) {
+	defer w.wg.Done()
+	for {
+		did, err := next(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Log.Error("job worker error", zap.Error(err))
+		}
+		if did && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.cfg.PollInterval):
+		}
+	}
+}
+
+// processNext sıradaki job'ı alıp çalıştırır; alınacak job yoksa false döner.
+func (w *Worker) processNext(ctx context.Context) (bool, error) {
+	job, err := w.store.Claim(ctx, w.now(), w.cfg.JobTimeout+leaseMargin)
+	if errors.Is(err, ErrNoJob) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// shutdown'da yarıda kalmasın; sınırı JobTimeout koyar
+	jobCtx := context.WithoutCancel(ctx)
+	log := logger.Log.With(zap.String("jobID", job.ID), zap.String("requestID", job.RequestID))
+	jobCtx = logger.WithContext(jobCtx, log)
+
+	if job.Attempts > w.cfg.MaxAttempts {
+		log.Warn("job abandoned", zap.Int("attempts", job.Attempts))
+		w.fail(&job, ErrJobAbandoned)
+	} else {
+		w.run(jobCtx, &job)
+	}
+
+	job.LockedUntil = nil
+	if job.WebhookURL != "" && job.Status != StatusQueued {
+		now := w.now()
+		job.WebhookStatus = WebhookPending
+		job.NextWebhookAt = &now
+	}
+	if err := w.store.Update(jobCtx, &job); err != nil {
+		return true, err
+	}
+	log.Info("job finished", zap.String("status", string(job.Status)))
+	return true, nil
+}
+
+func (w *Worker) run(ctx context.Context, job *Job) {
+	ctx = identity.WithContext(ctx, identity.Identity{
+		UserID:   job.UserID,
+		TenantID: job.TenantID,
+		Tier:     job.Tier,
+		Persona:  job.Persona,
+	})
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.JobTimeout)
+	defer cancel()
+	// process turn ortasında ölürse lease dolunca job başka worker'da Resume ile devam eder
+	ctx = chat.WithPromptSaved(ctx, func(ctx context.Context, sessionID string, seq int64) error {
+		job.SessionID = sessionID
+		job.PromptSeq = seq
+		return w.store.Update(context.WithoutCancel(ctx), job)
+	})
+
+	var res chat.Chat
+	var err error
+	if job.PromptSeq > 0 {
+		res, err = w.service.Resume(ctx, job.SessionID, job.PromptSeq)
+	} else {
+		res, err = w.service.SendMessage(ctx, job.SessionID, job.Message)
+	}
+	if errors.Is(err, chat.ErrShuttingDown) {
+		// turn yarıda kaldı; job kuyruğa geri döner, sonraki açılışta Resume ile
+		// devam eder. PromptSeq hook'ta yazıldı; hata da aynı yeri taşır.
+		var turn *chat.InterruptedTurnError
+		if errors.As(err, &turn) {
+			job.SessionID = turn.SessionID
+			job.PromptSeq = turn.PromptSeq
+		}
+		logger.FromContext(ctx).Warn("job requeued on shutdown", zap.Int64("promptSeq", job.PromptSeq))
+		job.Status = StatusQueued
+		return
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("job failed", zap.Error(err))
+		w.fail(job, err)
+		return
+	}
+	job.Status = StatusSucceeded
+	job.SessionID = res.SessionID
+	job.Result = res.Message
+}
+
+func (w *Worker) fail(job *Job, err error) {
+	job.Status = StatusFailed
+	job.ErrorCode = "internal_error"
+	job.Error = "internal server error"
+	var de *chat.Error
+	if errors.As(err, &de) {
+		job.ErrorCode = de.Code
+		job.Error = de.Message
+	}
+}
+
+// deliverNext zamanı gelmiş webhook'u gönderir; başarısızsa üstel artan
+// beklemeyle WebhookMaxAttempts'e kadar tekrar dener.
+func (w *Worker) deliverNext(ctx context.Context) (bool, error) {
+	job, err := w.store.ClaimWebhook(ctx, w.now(), w.cfg.WebhookTimeout+leaseMargin)
+	if errors.Is(err, ErrNoJob) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	log := logger.Log.With(zap.String("jobID", job.ID))
+
+	payload, err := json.Marshal(NewView(job))
+	if err != nil {
+		return true, err
+	}
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.cfg.WebhookTimeout)
+	err = w.deliverer.Deliver(sendCtx, job.WebhookURL, job.ID, payload)
+	cancel()
+
+	switch {
+	case err == nil:
+		job.WebhookStatus = WebhookDelivered
+		job.NextWebhookAt = nil
+		log.Info("webhook delivered", zap.Int("attempts", job.WebhookAttempts))
+	case job.WebhookAttempts >= w.cfg.WebhookMaxAttempts:
+		job.WebhookStatus = WebhookFailed
+		job.NextWebhookAt = nil
+		log.Error("webhook delivery failed", zap.Int("attempts", job.WebhookAttempts), zap.Error(err))
+	default:
+		next := w.now().Add(w.cfg.WebhookBaseDelay << (job.WebhookAttempts - 1))
+		job.NextWebhookAt = &next
+		log.Warn("webhook delivery will be retried", zap.Time("next", next), zap.Error(err))
+	}
+	return true, w.store.Update(context.WithoutCancel(ctx), &job)
+}
@@ -0,0 +1,254 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"myapp/internal/chat"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"myapp/pkg/webhook"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+var testConfig = Config{
+	Workers:            1,
+	PollInterval:       10 * time.Millisecond,
+	JobTimeout:         time.Minute,
+	MaxAttempts:        3,
+	WebhookMaxAttempts: 3,
+	WebhookBaseDelay:   time.Second,
+	WebhookTimeout:     time.Second,
+}
+
+func newTestWorker(t *testing.T, d Deliverer) (*Worker, *MockStore, *chat.MockService) {
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	store := NewMockStore(ctrl)
+	service := chat.NewMockService(ctrl)
+	w := NewWorker(store, service, d, testConfig)
+	now := time.Unix(1756212819, 0)
+	w.now = func() time.Time { return now }
+	return w, store, service
+}
+
+func TestProcessNext_Success(t *testing.T) {
+	//arrange
+	w, store, service := newTestWorker(t, nil)
+	job := Job{ID: "j1", Status: StatusRunning, Message: "merhaba", UserID: "u1", Tier: "pro", Attempts: 1, WebhookURL: "https://example.com/hook"}
+	store.EXPECT().Claim(gomock.Any(), w.now(), testConfig.JobTimeout+leaseMargin).Return(job, nil)
+	service.EXPECT().SendMessage(gomock.Any(), "", "merhaba").DoAndReturn(func(ctx context.Context, _, _ string) (chat.Chat, error) {
+		assert.Equal(t, identity.Identity{UserID: "u1", Tier: "pro"}, identity.FromContext(ctx))
+		return chat.Chat{Message: "selam", SessionID: "s1"}, nil
+	})
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil)
+
+	//act
+	did, err := w.processNext(context.Background())
+
+	//assert
+	assert.True(t, did)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, saved.Status)
+	assert.Equal(t, "selam", saved.Result)
+	assert.Equal(t, "s1", saved.SessionID)
+	assert.Nil(t, saved.LockedUntil)
+	assert.Equal(t, WebhookPending, saved.WebhookStatus)
+}
+
+func TestProcessNext_ServiceError(t *testing.T) {
+	w, store, service := newTestWorker(t, nil)
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(Job{ID: "j1", SessionID: "s1", Message: "merhaba", Attempts: 1}, nil)
+	service.EXPECT().SendMessage(gomock.Any(), "s1", "merhaba").Return(chat.Chat{}, chat.ErrSessionNotFound)
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil)
+
+	_, err := w.processNext(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, saved.Status)
+	assert.Equal(t, "session_not_found", saved.ErrorCode)
+	assert.Equal(t, WebhookNone, saved.WebhookStatus)
+}
+
+func TestProcessNext_Abandoned(t *testing.T) {
+	w, store, _ := newTestWorker(t, nil)
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(Job{ID: "j1", Message: "merhaba", Attempts: 4}, nil)
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil)
+
+	_, err := w.processNext(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, saved.Status)
+	assert.Equal(t, "job_abandoned", saved.ErrorCode)
+}
+
+func TestProcessNext_NoJob(t *testing.T) {
+	w, store, _ := newTestWorker(t, nil)
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(Job{}, ErrNoJob)
+
+	did, err := w.processNext(context.Background())
+
+	assert.False(t, did)
+	assert.NoError(t, err)
+}
+
+func TestDeliverNext_SignedPayload(t *testing.T) {
+	//arrange
+	var gotBody []byte
+	var gotTs int64
+	var gotSig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotTs, _ = strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		gotSig = r.Header.Get(webhook.HeaderSignature)
+	}))
+	defer srv.Close()
+	w, store, _ := newTestWorker(t, NewHTTPDeliverer("s3cret", time.Second, true))
+	store.EXPECT().ClaimWebhook(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(Job{ID: "j1", Status: StatusSucceeded, Result: "selam", WebhookURL: srv.URL, WebhookStatus: WebhookPending, WebhookAttempts: 1}, nil)
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil)
+
+	//act
+	_, err := w.deliverNext(context.Background())
+
+	//assert
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookDelivered, saved.WebhookStatus)
+	assert.True(t, webhook.Verify("s3cret", gotTs, gotBody, gotSig))
+	assert.False(t, webhook.Verify("wrong", gotTs, gotBody, gotSig))
+	assert.Contains(t, string(gotBody), `"message":"selam"`)
+}
+
+type failingDeliverer struct{}
+
+func (failingDeliverer) Deliver(context.Context, string, string, []byte) error {
+	return errors.New("connection refused")
+}
+
+func TestDeliverNext_RetriesWithBackoff(t *testing.T) {
+	w, store, _ := newTestWorker(t, failingDeliverer{})
+	store.EXPECT().ClaimWebhook(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(Job{ID: "j1", WebhookURL: "https://example.com", WebhookStatus: WebhookPending, WebhookAttempts: 2}, nil)
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil)
+
+	_, err := w.deliverNext(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookPending, saved.WebhookStatus)
+	assert.Equal(t, w.now().Add(2*time.Second), *saved.NextWebhookAt)
+}
+
+func TestDeliverNext_GivesUp(t *testing.T) {
+	w, store, _ := newTestWorker(t, failingDeliverer{})
+	store.EXPECT().ClaimWebhook(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(Job{ID: "j1", WebhookURL: "https://example.com", WebhookStatus: WebhookPending, WebhookAttempts: 3}, nil)
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil)
+
+	_, err := w.deliverNext(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookFailed, saved.WebhookStatus)
+	assert.Nil(t, saved.NextWebhookAt)
+}
+
+func TestWorker_StopsOnCancel(t *testing.T) {
+	w, store, _ := newTestWorker(t, nil)
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(Job{}, ErrNoJob).AnyTimes()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.Start(ctx)
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	done := make(chan struct{})
+	go func() { w.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+}
+
+func TestProcessNext_RequeuedOnShutdown(t *testing.T) {
+	w, store, service := newTestWorker(t, nil)
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(Job{ID: "j1", Message: "merhaba", Attempts: 1, WebhookURL: "https://example.com"}, nil)
+	service.EXPECT().SendMessage(gomock.Any(), "", "merhaba").Return(chat.Chat{}, chat.ErrShuttingDown)
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil)
+
+	_, err := w.processNext(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusQueued, saved.Status)
+	assert.Nil(t, saved.LockedUntil)
+	assert.Equal(t, WebhookNone, saved.WebhookStatus)
+}
+
+func TestProcessNext_SavesPromptSeqBeforeLLMCall(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	ctrl := gomock.NewController(t)
+	store := NewMockStore(ctrl)
+	repo := chat.NewMockRepository(ctrl)
+	client := chat.NewMockClient(ctrl)
+	w := NewWorker(store, chat.NewService(repo, client), nil, testConfig)
+
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(Job{ID: "j1", Message: "merhaba", Attempts: 1}, nil)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	var saved []Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = append(saved, *j) }).Return(nil).Times(2)
+	client.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).DoAndReturn(
+		func(context.Context, string, []chat.ChatMessage) (chat.Completion, error) {
+			// process burada ölürse job'da devam etmek için gereken her şey kayıtlı olmalı
+			require.Len(t, saved, 1)
+			assert.NotEmpty(t, saved[0].SessionID)
+			assert.Equal(t, int64(1), saved[0].PromptSeq)
+			return chat.Completion{}, errors.New("boom")
+		})
+	repo.EXPECT().AddEvents(gomock.Any(), gomock.Any()).Return(nil)
+
+	//act
+	_, err := w.processNext(context.Background())
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, saved[1].Status)
+}
+
+func TestProcessNext_ResumesInterruptedTurn(t *testing.T) {
+	//arrange
+	w, store, service := newTestWorker(t, nil)
+	interrupted := fmt.Errorf("%w: %w", chat.ErrShuttingDown, &chat.InterruptedTurnError{SessionID: "s1", PromptSeq: 3, Err: context.Canceled})
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(Job{ID: "j1", Message: "merhaba", Attempts: 1}, nil)
+	service.EXPECT().SendMessage(gomock.Any(), "", "merhaba").Return(chat.Chat{}, interrupted)
+	var saved Job
+	store.EXPECT().Update(gomock.Any(), gomock.Any()).Do(func(_ context.Context, j *Job) { saved = *j }).Return(nil).Times(2)
+
+	//act
+	_, err := w.processNext(context.Background())
+	require.NoError(t, err)
+	store.EXPECT().Claim(gomock.Any(), gomock.Any(), gomock.Any()).Return(saved, nil)
+	service.EXPECT().Resume(gomock.Any(), "s1", int64(3)).Return(chat.Chat{SessionID: "s1", Message: "cevap"}, nil)
+	_, err = w.processNext(context.Background())
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, saved.Status)
+	assert.Equal(t, "cevap", saved.Result)
+}
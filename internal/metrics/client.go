@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"myapp/internal/chat"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+)
+
+type instrumentedClient struct {
+	next     chat.Client
+	m        *Metrics
+	provider string
+	model    string
+}
+
+// InstrumentClient tek bir provider'ın client'ını sarar; retry'ın içinde
+// kullanılırsa her deneme ayrı ölçülür. Model router override'ı varsa odur.
+func (m *Metrics) InstrumentClient(next chat.Client, provider, model string) chat.Client {
+	return &instrumentedClient{next: next, m: m, provider: provider, model: model}
+}
+
+func (c *instrumentedClient) GetCompletion(ctx context.Context, message string, messages []chat.ChatMessage) (chat.Completion, error) {
+	model := c.model
+	if override := chat.ModelFromContext(ctx); override != "" {
+		model = override
+	}
+	start := time.Now()
+	completion, err := c.next.GetCompletion(ctx, message, messages)
+
+	c.m.llmRequests.WithLabelValues(c.provider, model).Inc()
+	c.m.llmDuration.WithLabelValues(c.provider, model).Observe(time.Since(start).Seconds())
+	if err != nil {
+		c.m.llmErrors.WithLabelValues(c.provider, model, errorReason(err)).Inc()
+		return completion, err
+	}
+	c.m.llmTokens.WithLabelValues(c.provider, model, "prompt").Add(float64(completion.PromptTokens))
+	c.m.llmTokens.WithLabelValues(c.provider, model, "completion").Add(float64(completion.CompletionTokens))
+	return completion, nil
+}
+
+func errorReason(err error) string {
+	var apiErr *openai.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.StatusCode == 429:
+			return "rate_limited"
+		case apiErr.StatusCode >= 500:
+			return "server_error"
+		}
+		return "client_error"
+	}
+	return "other"
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// Middleware istekleri route şablonuyla sayar (/v1/chat/:sessionId), böylece
+// session id'ler label'a girmez. Eşleşmeyen istekler "unmatched" olur.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// status'u görebilmek için hatayı burada yazdırıyoruz
+				c.Error(err)
+			}
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			method := c.Request().Method
+			m.httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Response().Status)).Inc()
+			m.httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics servisin Prometheus metrikleridir. Business kodu bunlara doğrudan
+// dokunmaz; HTTP middleware'i ve Client/Repository/Service decorator'ları yazar.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	llmRequests *prometheus.CounterVec
+	llmErrors   *prometheus.CounterVec
+	llmDuration *prometheus.HistogramVec
+	llmTokens   *prometheus.CounterVec
+
+	dbDuration *prometheus.HistogramVec
+	dbErrors   *prometheus.CounterVec
+
+	turns          *prometheus.CounterVec
+	turnDuration   prometheus.Histogram
+	activeTurns    prometheus.Gauge
+	activeSessions prometheus.Gauge
+
+	retentionRows *prometheus.CounterVec
+}
+
+// llmBuckets LLM çağrıları saniyeler sürdüğü için HTTP'den geniş tutulur.
+var llmBuckets = []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30, 60, 120}
+
+func New() *Metrics {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	m := &Metrics{
+		registry: reg,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		llmRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "llm_requests_total",
+			Help: "LLM completion calls (each retry attempt counts) by provider and model.",
+		}, []string{"provider", "model"}),
+		llmErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "llm_errors_total",
+			Help: "Failed LLM completion calls by provider, model and reason.",
+		}, []string{"provider", "model", "reason"}),
+		llmDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "llm_request_duration_seconds",
+			Help:    "LLM completion call latency by provider and model.",
+			Buckets: llmBuckets,
+		}, []string{"provider", "model"}),
+		llmTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "llm_tokens_total",
+			Help: "Tokens used by provider, model and type (prompt, completion).",
+		}, []string{"provider", "model", "type"}),
+
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Repository call latency by operation.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Failed repository calls by operation.",
+		}, []string{"operation"}),
+
+		turns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chat_turns_total",
+			Help: "Chat turns by result code (ok or the error code).",
+		}, []string{"code"}),
+		turnDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "chat_turn_duration_seconds",
+			Help:    "End to end SendMessage latency.",
+			Buckets: llmBuckets,
+		}),
+		activeTurns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "chat_active_turns",
+			Help: "SendMessage calls in flight.",
+		}),
+		activeSessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "chat_active_sessions",
+			Help: "Distinct sessions with a turn in flight.",
+		}),
+
+		retentionRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "retention_rows_total",
+			Help: "Rows deleted or anonymized by retention policies, by rule and action.",
+		}, []string{"rule", "action"}),
+	}
+	reg.MustRegister(
+		m.httpRequests, m.httpDuration,
+		m.llmRequests, m.llmErrors, m.llmDuration, m.llmTokens,
+		m.dbDuration, m.dbErrors,
+		m.turns, m.turnDuration, m.activeTurns, m.activeSessions,
+		m.retentionRows,
+	)
+	return m
+}
+
+// Handler /metrics endpoint'idir.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterQueue scrape anında depth'i sorgulayan queue_depth{queue=name} gauge'u ekler.
+func (m *Metrics) RegisterQueue(name string, depth func(ctx context.Context) (int64, error)) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "queue_depth",
+		Help:        "Items waiting in a background queue.",
+		ConstLabels: prometheus.Labels{"queue": name},
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		n, err := depth(ctx)
+		if err != nil {
+			return -1
+		}
+		return float64(n)
+	}))
+}
+
+// RetentionApplied retention.Reporter'ı karşılar.
+func (m *Metrics) RetentionApplied(rule string, action string, rows int64) {
+	m.retentionRows.WithLabelValues(rule, action).Add(float64(rows))
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"myapp/internal/chat"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	//arrange
+	m := New()
+	e := echo.New()
+	e.HTTPErrorHandler = chat.HTTPErrorHandler
+	e.Use(m.Middleware())
+	e.GET("/v1/chat/:sessionId", func(c echo.Context) error { return chat.ErrSessionNotFound })
+
+	//act
+	for _, id := range []string{"a", "b"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chat/"+id, nil))
+	}
+
+	//assert
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/v1/chat/:sessionId", "404")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestInstrumentClient(t *testing.T) {
+	//arrange
+	m := New()
+	ctrl := gomock.NewController(t)
+	next := chat.NewMockClient(ctrl)
+	c := m.InstrumentClient(next, "openai", "gpt-4o")
+	gomock.InOrder(
+		next.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).
+			Return(chat.Completion{Message: "selam", PromptTokens: 10, CompletionTokens: 4}, nil),
+		next.EXPECT().GetCompletion(gomock.Any(), "merhaba", gomock.Any()).
+			Return(chat.Completion{}, context.DeadlineExceeded),
+	)
+
+	//act
+	c.GetCompletion(chat.WithModel(context.Background(), "gpt-4o-mini"), "merhaba", nil)
+	c.GetCompletion(context.Background(), "merhaba", nil)
+
+	//assert
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.llmRequests.WithLabelValues("openai", "gpt-4o-mini")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(m.llmTokens.WithLabelValues("openai", "gpt-4o-mini", "prompt")))
+	assert.Equal(t, 4.0, testutil.ToFloat64(m.llmTokens.WithLabelValues("openai", "gpt-4o-mini", "completion")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.llmErrors.WithLabelValues("openai", "gpt-4o", "timeout")))
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	m := New()
+	ctrl := gomock.NewController(t)
+	next := chat.NewMockRepository(ctrl)
+	repo := m.InstrumentRepository(next)
+	next.EXPECT().Find(gomock.Any(), "s1").Return(nil, errors.New("db down"))
+	next.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil)
+
+	repo.Find(context.Background(), "s1")
+	repo.Save(context.Background(), &chat.ChatMessage{})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.dbErrors.WithLabelValues("find")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.dbErrors.WithLabelValues("save")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.dbDuration))
+}
+
+func TestInstrumentService_ActiveSessions(t *testing.T) {
+	//arrange
+	m := New()
+	ctrl := gomock.NewController(t)
+	next := chat.NewMockService(ctrl)
+	svc := m.InstrumentService(next)
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	next.EXPECT().SendMessage(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(context.Context, string, string) (chat.Chat, error) {
+			started <- struct{}{}
+			<-release
+			return chat.Chat{}, nil
+		}).Times(3)
+	done := make(chan struct{})
+	for _, id := range []string{"s1", "s1", ""} {
+		go func() {
+			svc.SendMessage(context.Background(), id, "merhaba")
+			done <- struct{}{}
+		}()
+	}
+	for range 3 {
+		<-started
+	}
+
+	//act & assert
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.activeTurns))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.activeSessions))
+	close(release)
+	for range 3 {
+		<-done
+	}
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.activeTurns))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.activeSessions))
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.turns.WithLabelValues("ok")))
+}
+
+func TestInstrumentService_ErrorCode(t *testing.T) {
+	m := New()
+	next := chat.NewMockService(gomock.NewController(t))
+	next.EXPECT().SendMessage(gomock.Any(), "s1", "merhaba").Return(chat.Chat{}, chat.ErrSessionNotFound)
+
+	m.InstrumentService(next).SendMessage(context.Background(), "s1", "merhaba")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.turns.WithLabelValues("session_not_found")))
+}
+
+func TestHandler_ExposesQueueDepth(t *testing.T) {
+	m := New()
+	m.RegisterQueue("jobs", func(context.Context) (int64, error) { return 7, nil })
+	rec := httptest.NewRecorder()
+
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), `queue_depth{queue="jobs"} 7`))
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"myapp/internal/chat"
+	"myapp/internal/events"
+	"time"
+)
+
+type instrumentedRepository struct {
+	next chat.Repository
+	m    *Metrics
+}
+
+func (m *Metrics) InstrumentRepository(next chat.Repository) chat.Repository {
+	return &instrumentedRepository{next: next, m: m}
+}
+
+func (r *instrumentedRepository) observe(op string, start time.Time, err error) {
+	r.m.dbDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		r.m.dbErrors.WithLabelValues(op).Inc()
+	}
+}
+
+func (r *instrumentedRepository) Save(ctx context.Context, message *chat.ChatMessage, evs ...events.Event) error {
+	start := time.Now()
+	err := r.next.Save(ctx, message, evs...)
+	r.observe("save", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) Find(ctx context.Context, sessionID string) ([]chat.ChatMessage, error) {
+	start := time.Now()
+	messages, err := r.next.Find(ctx, sessionID)
+	r.observe("find", start, err)
+	return messages, err
+}
+
+func (r *instrumentedRepository) Flagged(ctx context.Context, beforeID int, limit int) ([]chat.ChatMessage, error) {
+	start := time.Now()
+	messages, err := r.next.Flagged(ctx, beforeID, limit)
+	r.observe("flagged", start, err)
+	return messages, err
+}
+
+func (r *instrumentedRepository) AddEvents(ctx context.Context, evs ...events.Event) error {
+	start := time.Now()
+	err := r.next.AddEvents(ctx, evs...)
+	r.observe("add_events", start, err)
+	return err
+}
+
+func (r *instrumentedRepository) UserMessages(ctx context.Context, userID string) ([]chat.ChatMessage, error) {
+	start := time.Now()
+	messages, err := r.next.UserMessages(ctx, userID)
+	r.observe("user_messages", start, err)
+	return messages, err
+}
+
+func (r *instrumentedRepository) EraseUser(ctx context.Context, userID string) (chat.Erasure, error) {
+	start := time.Now()
+	erasure, err := r.next.EraseUser(ctx, userID)
+	r.observe("erase_user", start, err)
+	return erasure, err
+}
+
+func (r *instrumentedRepository) Search(ctx context.Context, userID, query string, limit int) ([]chat.ChatMessage, error) {
+	start := time.Now()
+	messages, err := r.next.Search(ctx, userID, query, limit)
+	r.observe("search", start, err)
+	return messages, err
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"myapp/internal/chat"
+	"sync"
+	"time"
+)
+
+type instrumentedService struct {
+	next chat.Service
+	m    *Metrics
+
+	mu       sync.Mutex
+	sessions map[string]int // session başına süren turn sayısı
+	fresh    int            // henüz session id'si olmayan (yeni session) turn'ler
+	turns    int
+}
+
+func (m *Metrics) InstrumentService(next chat.Service) chat.Service {
+	return &instrumentedService{next: next, m: m, sessions: make(map[string]int)}
+}
+
+func (s *instrumentedService) SendMessage(ctx context.Context, sessionID string, message string) (chat.Chat, error) {
+	s.enter(sessionID)
+	defer s.leave(sessionID)
+
+	start := time.Now()
+	res, err := s.next.SendMessage(ctx, sessionID, message)
+	s.m.turnDuration.Observe(time.Since(start).Seconds())
+	s.m.turns.WithLabelValues(resultCode(err)).Inc()
+	return res, err
+}
+
+func (s *instrumentedService) Resume(ctx context.Context, sessionID string, promptSeq int64) (chat.Chat, error) {
+	s.enter(sessionID)
+	defer s.leave(sessionID)
+
+	start := time.Now()
+	res, err := s.next.Resume(ctx, sessionID, promptSeq)
+	s.m.turnDuration.Observe(time.Since(start).Seconds())
+	s.m.turns.WithLabelValues(resultCode(err)).Inc()
+	return res, err
+}
+
+func (s *instrumentedService) FindHistory(ctx context.Context, sessionID string) ([]chat.ChatMessage, error) {
+	return s.next.FindHistory(ctx, sessionID)
+}
+
+func (s *instrumentedService) Drain(ctx context.Context) error {
+	return s.next.Drain(ctx)
+}
+
+func (s *instrumentedService) enter(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns++
+	if sessionID == "" {
+		s.fresh++
+	} else {
+		s.sessions[sessionID]++
+	}
+	s.update()
+}
+
+func (s *instrumentedService) leave(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns--
+	if sessionID == "" {
+		s.fresh--
+	} else if s.sessions[sessionID]--; s.sessions[sessionID] == 0 {
+		delete(s.sessions, sessionID)
+	}
+	s.update()
+}
+
+func (s *instrumentedService) update() {
+	s.m.activeTurns.Set(float64(s.turns))
+	s.m.activeSessions.Set(float64(s.fresh + len(s.sessions)))
+}
+
+func resultCode(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	var de *chat.Error
+	if errors.As(err, &de) {
+		return de.Code
+	}
+	return "internal_error"
+}
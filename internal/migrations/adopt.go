@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"myapp/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// baselineChatMessage chat_messages'ın 0001_baseline'daki halidir. Migration'lardan
+// önceki sürümler tabloyu AutoMigrate ile kuruyordu; ilk sürümde sadece id, kind,
+// message, timestamp ve session_id (MySQL'de longtext) vardı. Model sonradan
+// değişse de bu struct değişmez.
+type baselineChatMessage struct {
+	ID           int
+	Kind         string
+	Message      string
+	Timestamp    int64  `gorm:"index"`
+	SessionID    string `gorm:"size:64;index:idx_session_seq,priority:1"`
+	TenantID     string `gorm:"size:64;not null;default:'';index:idx_tenant_key,priority:1"`
+	UserID       string `gorm:"size:255;index"`
+	Persona      string `gorm:"size:64;not null;default:''"`
+	KeyVersion   int    `gorm:"not null;default:0;index:idx_tenant_key,priority:2"`
+	Seq          int64  `gorm:"index:idx_session_seq,priority:2"`
+	Attempts     int
+	Provider     string
+	Model        string
+	Route        string
+	Moderation   string `gorm:"size:255;index"`
+	GuardAction  string `gorm:"size:16"`
+	GuardScore   float64
+	GuardSignals string `gorm:"size:255"`
+}
+
+func (baselineChatMessage) TableName() string {
+	return "chat_messages"
+}
+
+// adopt 0001_baseline'dan önce çalışır. Baseline'ın CREATE TABLE IF NOT EXISTS'i
+// mevcut tabloya dokunmadığı için AutoMigrate'li bir sürümden kalan
+// chat_messages'a eksik kolon ve index'ler burada eklenir, session_id
+// varchar(64)'e çevrilir. Diğer tablolar o sürümlerde yoktu; baseline kurar.
+// Yeni veritabanlarında bir şey yapmaz.
+func adopt(_ context.Context, db *gorm.DB) error {
+	table := &baselineChatMessage{}
+	if !db.Migrator().HasTable(table) {
+		return nil
+	}
+	if err := db.AutoMigrate(table); err != nil {
+		return err
+	}
+	// arama index'inin GORM karşılığı yok; Postgres'te baseline kendisi ekler
+	if db.Dialector.Name() == database.MySQL && !db.Migrator().HasIndex(table, "idx_chat_messages_search") {
+		return db.Exec("ALTER TABLE `chat_messages` ADD FULLTEXT INDEX `idx_chat_messages_search` (`message`)").Error
+	}
+	return nil
+}
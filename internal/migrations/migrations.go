@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"embed"
+	"myapp/pkg/migrate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FS veritabanı başına bir dizin içerir (mysql, postgres, sqlite). Yeni bir
+// değişiklik her dizine aynı version ve adla eklenir; uygulanmış dosyalar
+// değiştirilmez, checksum'ları tutulur.
+//
+//go:embed mysql postgres sqlite
+var FS embed.FS
+
+// New db'nin dialect'ine ait migration'larla bir Migrator kurar. AutoMigrate'li
+// sürümlerden kalan veritabanları ilk Up'ta adopt ile baseline'a uyarlanır.
+func New(db *gorm.DB, lockTimeout time.Duration) (*migrate.Migrator, error) {
+	list, err := migrate.Load(FS, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, list, lockTimeout, migrate.WithAdopt(adopt)), nil
+}
@@ -0,0 +1,180 @@
+package migrations
+
+import (
+	"context"
+	"myapp/internal/audit"
+	"myapp/internal/chat"
+	"myapp/internal/events"
+	"myapp/internal/jobs"
+	"myapp/internal/privacy"
+	"myapp/internal/retention"
+	"myapp/pkg/database"
+	"myapp/pkg/encryption"
+	"myapp/pkg/idempotency"
+	"myapp/pkg/migrate"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// models migration'ların karşılaması gereken tablolardır.
+var models = []any{&chat.ChatMessage{}, &idempotency.Record{}, &jobs.Job{}, &events.Record{}, &encryption.DataKey{}, &privacy.ErasureRecord{}, &retention.Hold{}, &audit.Entry{}}
+
+// forEachDialect SQLite'ta her zaman, MySQL ve Postgres'te DSN verildiyse
+// çalışır (bkz. internal/chat repository testleri). Tablolar silinip baştan kurulur.
+func forEachDialect(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	dialects := []struct{ driver, dsnEnv string }{
+		{database.SQLite, ""},
+		{database.MySQL, "TEST_MYSQL_DSN"},
+		{database.Postgres, "TEST_POSTGRES_DSN"},
+	}
+	for _, d := range dialects {
+		t.Run(d.driver, func(t *testing.T) {
+			dsn := ":memory:"
+			if d.dsnEnv != "" {
+				if dsn = os.Getenv(d.dsnEnv); dsn == "" {
+					t.Skipf("%s is not set", d.dsnEnv)
+				}
+			}
+			db, err := database.Connect(database.Config{Driver: d.driver, DSN: dsn})
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				if sqlDB, err := db.DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+			require.NoError(t, db.Migrator().DropTable(append(models, &migrate.Record{})...))
+			test(t, db)
+		})
+	}
+}
+
+func TestMigrations_MatchModels(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		//arrange
+		m, err := New(db, 0)
+		require.NoError(t, err)
+
+		//act
+		_, err = m.Up(context.Background())
+
+		//assert
+		require.NoError(t, err)
+		assertMatchesModels(t, db)
+	})
+}
+
+func assertMatchesModels(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		require.NoError(t, err)
+		for _, f := range s.Fields {
+			if f.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(model, f.DBName), "%s.%s", s.Table, f.DBName)
+			}
+		}
+		for _, idx := range s.ParseIndexes() {
+			assert.True(t, db.Migrator().HasIndex(model, idx.Name), "%s: index %s", s.Table, idx.Name)
+		}
+	}
+}
+
+func TestMigrations_DownAndUpAgain(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		m, err := New(db, 0)
+		require.NoError(t, err)
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+
+		reverted, err := m.Down(ctx, len(applied))
+		require.NoError(t, err)
+		assert.Len(t, reverted, len(applied))
+		assert.False(t, db.Migrator().HasTable(&chat.ChatMessage{}))
+
+		_, err = m.Up(ctx)
+		require.NoError(t, err)
+		assert.True(t, db.Migrator().HasIndex(&chat.ChatMessage{}, "idx_session_timestamp"))
+	})
+}
+
+// originalChatMessage migration'lardan önceki ilk sürümün AutoMigrate ile
+// kurduğu tablodur.
+type originalChatMessage struct {
+	ID        int
+	Kind      string
+	Message   string
+	Timestamp int64
+	SessionID string
+}
+
+func (originalChatMessage) TableName() string {
+	return "chat_messages"
+}
+
+// İlk sürümden kalan veritabanları baseline'ın şemasına getirilmeli.
+func TestMigrations_AdoptOriginalSchema(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		//arrange
+		require.NoError(t, db.AutoMigrate(&originalChatMessage{}))
+		require.NoError(t, db.Create(&originalChatMessage{Kind: "USER_PROMPT", Message: "eski", Timestamp: 1, SessionID: "s1"}).Error)
+		m, err := New(db, 0)
+		require.NoError(t, err)
+
+		//act
+		applied, err := m.Up(context.Background())
+
+		//assert
+		require.NoError(t, err)
+		all, _ := migrate.Load(FS, db.Dialector.Name())
+		assert.Len(t, applied, len(all))
+		assertMatchesModels(t, db)
+		var old chat.ChatMessage
+		require.NoError(t, db.First(&old).Error)
+		assert.Equal(t, "eski", old.Message)
+		assert.Equal(t, 0, old.KeyVersion, "old rows are plaintext")
+		assert.Equal(t, "", old.TenantID)
+		if db.Dialector.Name() == database.MySQL {
+			assert.True(t, db.Migrator().HasIndex(&chat.ChatMessage{}, "idx_chat_messages_search"))
+		}
+	})
+}
+
+// adopt yarıda kalırsa bir sonraki Up'ta tekrar çalışır.
+func TestMigrations_AdoptIsRepeatable(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		require.NoError(t, db.AutoMigrate(&originalChatMessage{}))
+		require.NoError(t, adopt(context.Background(), db))
+		m, err := New(db, 0)
+		require.NoError(t, err)
+
+		_, err = m.Up(context.Background())
+
+		require.NoError(t, err)
+		assertMatchesModels(t, db)
+	})
+}
+
+func TestMigrations_SameVersionsForAllDialects(t *testing.T) {
+	var want []string
+	for i, dir := range []string{database.MySQL, database.Postgres, database.SQLite} {
+		list, err := migrate.Load(FS, dir)
+		require.NoError(t, err)
+		var got []string
+		for _, m := range list {
+			got = append(got, m.String())
+			assert.NotEmpty(t, m.Down, "%s/%s has no down script", dir, m)
+		}
+		if i == 0 {
+			want = got
+			continue
+		}
+		assert.Equal(t, want, got, dir)
+	}
+}
@@ -0,0 +1,138 @@
+-- AutoMigrate'in oluşturduğu şema. IF NOT EXISTS sayesinde AutoMigrate ile
+-- kurulmuş veritabanlarında hiçbir şey değiştirmeden kaydedilir.
+CREATE TABLE IF NOT EXISTS `chat_messages` (
+  `id` bigint AUTO_INCREMENT,
+  `kind` longtext,
+  `message` longtext,
+  `timestamp` bigint,
+  `session_id` varchar(64),
+  `tenant_id` varchar(64) NOT NULL DEFAULT '',
+  `user_id` varchar(255),
+  `persona` varchar(64) NOT NULL DEFAULT '',
+  `key_version` bigint NOT NULL DEFAULT 0,
+  `seq` bigint,
+  `attempts` bigint,
+  `provider` longtext,
+  `model` longtext,
+  `route` longtext,
+  `moderation` varchar(255),
+  `guard_action` varchar(16),
+  `guard_score` double,
+  `guard_signals` varchar(255),
+  PRIMARY KEY (`id`),
+  INDEX `idx_chat_messages_timestamp` (`timestamp`),
+  INDEX `idx_session_seq` (`session_id`, `seq`),
+  INDEX `idx_tenant_key` (`tenant_id`, `key_version`),
+  INDEX `idx_chat_messages_user_id` (`user_id`),
+  INDEX `idx_chat_messages_moderation` (`moderation`),
+  FULLTEXT INDEX `idx_chat_messages_search` (`message`)
+);
+
+CREATE TABLE IF NOT EXISTS `idempotency_records` (
+  `idempotency_key` varchar(255),
+  `fingerprint` varchar(64),
+  `status` varchar(16),
+  `status_code` bigint,
+  `content_type` varchar(128),
+  `body` longblob,
+  `created_at` datetime(3) NULL,
+  `expires_at` datetime(3) NULL,
+  PRIMARY KEY (`idempotency_key`),
+  INDEX `idx_idempotency_records_expires_at` (`expires_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `chat_jobs` (
+  `id` varchar(36),
+  `status` varchar(16),
+  `session_id` varchar(64),
+  `message` longtext,
+  `result` longtext,
+  `error_code` varchar(64),
+  `error` longtext,
+  `attempts` bigint,
+  `user_id` varchar(255),
+  `tenant_id` varchar(255),
+  `tier` varchar(64),
+  `persona` varchar(64),
+  `request_id` varchar(128),
+  `webhook_url` longtext,
+  `webhook_status` varchar(16),
+  `webhook_attempts` bigint,
+  `next_webhook_at` datetime(3) NULL,
+  `locked_until` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_jobs_claim` (`status`, `locked_until`),
+  INDEX `idx_jobs_webhook` (`webhook_status`, `next_webhook_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `event_id` varchar(36),
+  `type` varchar(64),
+  `session_id` varchar(64),
+  `payload` longblob,
+  `occurred_at` datetime(3) NULL,
+  `published_at` datetime(3) NULL,
+  `next_attempt_at` datetime(3) NULL,
+  `attempts` bigint,
+  `last_error` longtext,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_outbox_events_event_id` (`event_id`),
+  INDEX `idx_outbox_pending` (`published_at`, `next_attempt_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `tenant_data_keys` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `tenant_id` varchar(64),
+  `version` bigint,
+  `wrapped_key` varbinary(512),
+  `master_key_id` varchar(64),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_tenant_version` (`tenant_id`, `version`)
+);
+
+CREATE TABLE IF NOT EXISTS `erasure_audits` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `subject` varchar(64),
+  `tenant_id` varchar(255),
+  `request_id` varchar(128),
+  `sessions` bigint,
+  `messages` bigint,
+  `events` bigint,
+  `jobs` bigint,
+  `erased_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_erasure_audits_subject` (`subject`)
+);
+
+CREATE TABLE IF NOT EXISTS `legal_holds` (
+  `session_id` varchar(64),
+  `reason` varchar(255),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`session_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `audit_log` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `action` varchar(64),
+  `actor` varchar(255),
+  `tenant_id` varchar(255),
+  `target` varchar(255),
+  `status` bigint,
+  `ip` varchar(64),
+  `request_id` varchar(128),
+  `detail` varchar(1024),
+  `prev_hash` varchar(64),
+  `hash` varchar(64),
+  PRIMARY KEY (`id`),
+  INDEX `idx_audit_log_created_at` (`created_at`),
+  INDEX `idx_audit_log_action` (`action`),
+  INDEX `idx_audit_log_actor` (`actor`),
+  INDEX `idx_audit_log_tenant_id` (`tenant_id`),
+  INDEX `idx_audit_log_target` (`target`),
+  UNIQUE INDEX `idx_audit_log_prev_hash` (`prev_hash`)
+);
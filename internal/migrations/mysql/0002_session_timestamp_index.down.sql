@@ -0,0 +1 @@
+DROP INDEX idx_session_timestamp ON chat_messages;
@@ -0,0 +1,2 @@
+-- session history ve retention sorguları session_id + timestamp ile filtreler
+CREATE INDEX idx_session_timestamp ON chat_messages (session_id, `timestamp`);
@@ -0,0 +1 @@
+ALTER TABLE `chat_messages` DROP INDEX `idx_session_seq`, ADD INDEX `idx_session_seq` (`session_id`, `seq`);
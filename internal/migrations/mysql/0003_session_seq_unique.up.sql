@@ -0,0 +1,3 @@
+-- aynı session'a eşzamanlı iki turn aynı seq'i alamasın. Daha önce çakışmış
+-- satırlar varsa başarısız olur; bunlar elle ayıklanmalıdır.
+ALTER TABLE `chat_messages` DROP INDEX `idx_session_seq`, ADD UNIQUE INDEX `idx_session_seq` (`session_id`, `seq`);
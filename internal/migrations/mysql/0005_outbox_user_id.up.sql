@@ -0,0 +1,2 @@
+-- erasure, kullanıcının turn'lerinden doğan event'leri user_id ile bulur
+ALTER TABLE `outbox_events` ADD COLUMN `user_id` varchar(255), ADD INDEX `idx_outbox_events_user_id` (`user_id`);
@@ -0,0 +1 @@
+ALTER TABLE `idempotency_records` DROP COLUMN `tenant_id`, DROP COLUMN `key_version`;
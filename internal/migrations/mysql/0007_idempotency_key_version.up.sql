@@ -0,0 +1,2 @@
+-- şifreleme açıkken body'yi şifreleyen tenant key'i; key_version 0 düz metin
+ALTER TABLE `idempotency_records` ADD COLUMN `tenant_id` varchar(64) NOT NULL DEFAULT '', ADD COLUMN `key_version` bigint NOT NULL DEFAULT 0;
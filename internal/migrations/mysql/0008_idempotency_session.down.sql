@@ -0,0 +1 @@
+ALTER TABLE `idempotency_records` DROP COLUMN `persona`, DROP COLUMN `session_id`;
@@ -0,0 +1,2 @@
+-- retention kayıtlara chat mesajlarıyla aynı kuralları uygular ve hold'daki session'ları atlar
+ALTER TABLE `idempotency_records` ADD COLUMN `persona` varchar(64) NOT NULL DEFAULT '', ADD COLUMN `session_id` varchar(64) NOT NULL DEFAULT '';
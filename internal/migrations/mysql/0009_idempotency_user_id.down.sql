@@ -0,0 +1 @@
+ALTER TABLE `idempotency_records` DROP INDEX `idx_idempotency_records_user_id`, DROP COLUMN `user_id`;
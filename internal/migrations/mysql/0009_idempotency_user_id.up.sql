@@ -0,0 +1,2 @@
+-- anahtar hash'lendiği için erasure kullanıcının kayıtlarını user_id ile bulur
+ALTER TABLE `idempotency_records` ADD COLUMN `user_id` varchar(255) NOT NULL DEFAULT '', ADD INDEX `idx_idempotency_records_user_id` (`user_id`);
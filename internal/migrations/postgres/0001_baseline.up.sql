@@ -0,0 +1,141 @@
+-- AutoMigrate'in oluşturduğu şema. IF NOT EXISTS sayesinde AutoMigrate ile
+-- kurulmuş veritabanlarında hiçbir şey değiştirmeden kaydedilir.
+CREATE TABLE IF NOT EXISTS chat_messages (
+  id bigserial,
+  kind text,
+  message text,
+  "timestamp" bigint,
+  session_id varchar(64),
+  tenant_id varchar(64) NOT NULL DEFAULT '',
+  user_id varchar(255),
+  persona varchar(64) NOT NULL DEFAULT '',
+  key_version bigint NOT NULL DEFAULT 0,
+  seq bigint,
+  attempts bigint,
+  provider text,
+  model text,
+  route text,
+  moderation varchar(255),
+  guard_action varchar(16),
+  guard_score decimal,
+  guard_signals varchar(255),
+  PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_timestamp ON chat_messages ("timestamp");
+CREATE INDEX IF NOT EXISTS idx_session_seq ON chat_messages (session_id, seq);
+CREATE INDEX IF NOT EXISTS idx_tenant_key ON chat_messages (tenant_id, key_version);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_user_id ON chat_messages (user_id);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_moderation ON chat_messages (moderation);
+-- mesaj araması; diller karışık olduğu için stemming yapmayan simple kullanılır
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (to_tsvector('simple', coalesce(message, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_chat_messages_search ON chat_messages USING GIN (search_vector);
+
+CREATE TABLE IF NOT EXISTS idempotency_records (
+  idempotency_key varchar(255),
+  fingerprint varchar(64),
+  status varchar(16),
+  status_code bigint,
+  content_type varchar(128),
+  body bytea,
+  created_at timestamptz,
+  expires_at timestamptz,
+  PRIMARY KEY (idempotency_key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
+
+CREATE TABLE IF NOT EXISTS chat_jobs (
+  id varchar(36),
+  status varchar(16),
+  session_id varchar(64),
+  message text,
+  result text,
+  error_code varchar(64),
+  error text,
+  attempts bigint,
+  user_id varchar(255),
+  tenant_id varchar(255),
+  tier varchar(64),
+  persona varchar(64),
+  request_id varchar(128),
+  webhook_url text,
+  webhook_status varchar(16),
+  webhook_attempts bigint,
+  next_webhook_at timestamptz,
+  locked_until timestamptz,
+  created_at timestamptz,
+  updated_at timestamptz,
+  PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_claim ON chat_jobs (status, locked_until);
+CREATE INDEX IF NOT EXISTS idx_jobs_webhook ON chat_jobs (webhook_status, next_webhook_at);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+  id bigserial,
+  event_id varchar(36),
+  type varchar(64),
+  session_id varchar(64),
+  payload jsonb,
+  occurred_at timestamptz,
+  published_at timestamptz,
+  next_attempt_at timestamptz,
+  attempts bigint,
+  last_error text,
+  PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events (event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox_events (published_at, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS tenant_data_keys (
+  id bigserial,
+  tenant_id varchar(64),
+  version bigint,
+  wrapped_key bytea,
+  master_key_id varchar(64),
+  created_at timestamptz,
+  PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_version ON tenant_data_keys (tenant_id, version);
+
+CREATE TABLE IF NOT EXISTS erasure_audits (
+  id bigserial,
+  subject varchar(64),
+  tenant_id varchar(255),
+  request_id varchar(128),
+  sessions bigint,
+  messages bigint,
+  events bigint,
+  jobs bigint,
+  erased_at timestamptz,
+  PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_erasure_audits_subject ON erasure_audits (subject);
+
+CREATE TABLE IF NOT EXISTS legal_holds (
+  session_id varchar(64),
+  reason varchar(255),
+  created_at timestamptz,
+  PRIMARY KEY (session_id)
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id bigserial,
+  created_at timestamptz,
+  action varchar(64),
+  actor varchar(255),
+  tenant_id varchar(255),
+  target varchar(255),
+  status bigint,
+  ip varchar(64),
+  request_id varchar(128),
+  detail varchar(1024),
+  prev_hash varchar(64),
+  hash varchar(64),
+  PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor);
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_id ON audit_log (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_log_prev_hash ON audit_log (prev_hash);
@@ -0,0 +1,2 @@
+-- shutdown'da ya da çökmede yarıda kalan turn'ü devam ettirmek için kaydedilen prompt'un seq'i
+ALTER TABLE chat_jobs ADD COLUMN IF NOT EXISTS prompt_seq bigint;
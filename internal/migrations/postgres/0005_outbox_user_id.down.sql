@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_outbox_events_user_id;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS user_id;
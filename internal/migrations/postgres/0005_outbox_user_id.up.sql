@@ -0,0 +1,3 @@
+-- erasure, kullanıcının turn'lerinden doğan event'leri user_id ile bulur
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS user_id varchar(255);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_user_id ON outbox_events (user_id);
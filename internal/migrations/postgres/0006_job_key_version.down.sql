@@ -0,0 +1 @@
+ALTER TABLE chat_jobs DROP COLUMN IF EXISTS key_version;
@@ -0,0 +1,2 @@
+-- şifreleme açıkken message/result'ı şifreleyen tenant key'inin versiyonu; 0 düz metin
+ALTER TABLE chat_jobs ADD COLUMN IF NOT EXISTS key_version bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- şifreleme açıkken body'yi şifreleyen tenant key'i; key_version 0 düz metin
+ALTER TABLE idempotency_records ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_records ADD COLUMN IF NOT EXISTS key_version bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_records DROP COLUMN IF EXISTS session_id;
+ALTER TABLE idempotency_records DROP COLUMN IF EXISTS persona;
@@ -0,0 +1,3 @@
+-- retention kayıtlara chat mesajlarıyla aynı kuralları uygular ve hold'daki session'ları atlar
+ALTER TABLE idempotency_records ADD COLUMN IF NOT EXISTS persona varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_records ADD COLUMN IF NOT EXISTS session_id varchar(64) NOT NULL DEFAULT '';
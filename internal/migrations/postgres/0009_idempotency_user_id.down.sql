@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_idempotency_records_user_id;
+ALTER TABLE idempotency_records DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,3 @@
+-- anahtar hash'lendiği için erasure kullanıcının kayıtlarını user_id ile bulur
+ALTER TABLE idempotency_records ADD COLUMN IF NOT EXISTS user_id varchar(255) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_user_id ON idempotency_records (user_id);
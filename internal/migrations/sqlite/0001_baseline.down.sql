@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS legal_holds;
+DROP TABLE IF EXISTS erasure_audits;
+DROP TABLE IF EXISTS tenant_data_keys;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS chat_jobs;
+DROP TABLE IF EXISTS idempotency_records;
+DROP TABLE IF EXISTS chat_messages;
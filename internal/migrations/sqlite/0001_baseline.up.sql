@@ -0,0 +1,133 @@
+-- AutoMigrate'in oluşturduğu şema. IF NOT EXISTS sayesinde AutoMigrate ile
+-- kurulmuş veritabanlarında hiçbir şey değiştirmeden kaydedilir.
+-- Mesaj araması LIKE ile yapıldığı için arama index'i yoktur.
+CREATE TABLE IF NOT EXISTS chat_messages (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  kind text,
+  message text,
+  "timestamp" integer,
+  session_id text,
+  tenant_id text NOT NULL DEFAULT '',
+  user_id text,
+  persona text NOT NULL DEFAULT '',
+  key_version integer NOT NULL DEFAULT 0,
+  seq integer,
+  attempts integer,
+  provider text,
+  model text,
+  route text,
+  moderation text,
+  guard_action text,
+  guard_score real,
+  guard_signals text
+);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_timestamp ON chat_messages ("timestamp");
+CREATE INDEX IF NOT EXISTS idx_session_seq ON chat_messages (session_id, seq);
+CREATE INDEX IF NOT EXISTS idx_tenant_key ON chat_messages (tenant_id, key_version);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_user_id ON chat_messages (user_id);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_moderation ON chat_messages (moderation);
+
+CREATE TABLE IF NOT EXISTS idempotency_records (
+  idempotency_key text,
+  fingerprint text,
+  status text,
+  status_code integer,
+  content_type text,
+  body blob,
+  created_at datetime,
+  expires_at datetime,
+  PRIMARY KEY (idempotency_key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
+
+CREATE TABLE IF NOT EXISTS chat_jobs (
+  id text,
+  status text,
+  session_id text,
+  message text,
+  result text,
+  error_code text,
+  error text,
+  attempts integer,
+  user_id text,
+  tenant_id text,
+  tier text,
+  persona text,
+  request_id text,
+  webhook_url text,
+  webhook_status text,
+  webhook_attempts integer,
+  next_webhook_at datetime,
+  locked_until datetime,
+  created_at datetime,
+  updated_at datetime,
+  PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_claim ON chat_jobs (status, locked_until);
+CREATE INDEX IF NOT EXISTS idx_jobs_webhook ON chat_jobs (webhook_status, next_webhook_at);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  event_id text,
+  type text,
+  session_id text,
+  payload blob,
+  occurred_at datetime,
+  published_at datetime,
+  next_attempt_at datetime,
+  attempts integer,
+  last_error text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events (event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox_events (published_at, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS tenant_data_keys (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  tenant_id text,
+  version integer,
+  wrapped_key blob,
+  master_key_id text,
+  created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_version ON tenant_data_keys (tenant_id, version);
+
+CREATE TABLE IF NOT EXISTS erasure_audits (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  subject text,
+  tenant_id text,
+  request_id text,
+  sessions integer,
+  messages integer,
+  events integer,
+  jobs integer,
+  erased_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_erasure_audits_subject ON erasure_audits (subject);
+
+CREATE TABLE IF NOT EXISTS legal_holds (
+  session_id text,
+  reason text,
+  created_at datetime,
+  PRIMARY KEY (session_id)
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  created_at datetime,
+  action text,
+  actor text,
+  tenant_id text,
+  target text,
+  status integer,
+  ip text,
+  request_id text,
+  detail text,
+  prev_hash text,
+  hash text
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor);
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_id ON audit_log (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_log_prev_hash ON audit_log (prev_hash);
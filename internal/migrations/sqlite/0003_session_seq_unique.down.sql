@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_session_seq;
+CREATE INDEX IF NOT EXISTS idx_session_seq ON chat_messages (session_id, seq);
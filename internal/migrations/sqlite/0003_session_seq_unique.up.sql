@@ -0,0 +1,4 @@
+-- aynı session'a eşzamanlı iki turn aynı seq'i alamasın. Daha önce çakışmış
+-- satırlar varsa başarısız olur; bunlar elle ayıklanmalıdır.
+DROP INDEX IF EXISTS idx_session_seq;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_seq ON chat_messages (session_id, seq);
@@ -0,0 +1,2 @@
+-- şifreleme açıkken message/result'ı şifreleyen tenant key'inin versiyonu; 0 düz metin
+ALTER TABLE chat_jobs ADD COLUMN key_version integer NOT NULL DEFAULT 0;
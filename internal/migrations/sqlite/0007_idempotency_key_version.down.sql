@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_records DROP COLUMN key_version;
+ALTER TABLE idempotency_records DROP COLUMN tenant_id;
@@ -0,0 +1,3 @@
+-- şifreleme açıkken body'yi şifreleyen tenant key'i; key_version 0 düz metin
+ALTER TABLE idempotency_records ADD COLUMN tenant_id text NOT NULL DEFAULT '';
+ALTER TABLE idempotency_records ADD COLUMN key_version integer NOT NULL DEFAULT 0;
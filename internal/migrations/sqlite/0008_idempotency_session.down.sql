@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_records DROP COLUMN session_id;
+ALTER TABLE idempotency_records DROP COLUMN persona;
@@ -0,0 +1,3 @@
+-- retention kayıtlara chat mesajlarıyla aynı kuralları uygular ve hold'daki session'ları atlar
+ALTER TABLE idempotency_records ADD COLUMN persona text NOT NULL DEFAULT '';
+ALTER TABLE idempotency_records ADD COLUMN session_id text NOT NULL DEFAULT '';
@@ -0,0 +1,111 @@
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"myapp/internal/chat"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
+	"net/http"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Export(c echo.Context) error
+	Erase(c echo.Context) error
+}
+
+type handler struct {
+	service Service
+}
+
+// NewHandler kimliği gateway'in X-User-ID header'ından alır; kullanıcı sadece
+// kendi verisini indirip silebilir.
+func NewHandler(service Service) Handler {
+	return &handler{
+		service: service,
+	}
+}
+
+var errNoUser = echo.NewHTTPError(http.StatusUnauthorized, "X-User-ID header is required")
+
+// Export ?format=zip verilirse session başına bir dosya içeren ZIP, yoksa tek JSON döner.
+func (h *handler) Export(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := identity.FromContext(ctx)
+	if id.UserID == "" {
+		return errNoUser
+	}
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "zip" {
+		return chat.ErrInvalidRequest
+	}
+	export, err := h.service.Export(ctx, id.UserID, id.TenantID)
+	if err != nil {
+		logger.FromContext(ctx).Error("user export failed", zap.Error(err))
+		return chat.ErrStorage
+	}
+
+	name := "export-" + export.ExportedAt.Format("20060102T150405Z")
+	if format != "zip" {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.json"`, name))
+		return c.JSON(http.StatusOK, export)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+	c.Response().WriteHeader(http.StatusOK)
+	// header yazıldıktan sonraki hata cevaba yansıtılamaz, sadece loglanır
+	if err := writeZip(c.Response(), export); err != nil {
+		logger.FromContext(ctx).Error("failed to write export archive", zap.Error(err))
+	}
+	return nil
+}
+
+func writeZip(w http.ResponseWriter, export Export) error {
+	zw := zip.NewWriter(w)
+	type file struct {
+		name string
+		v    any
+	}
+	files := []file{
+		{"user.json", map[string]any{
+			"userId":     export.UserID,
+			"tenantId":   export.TenantID,
+			"exportedAt": export.ExportedAt,
+		}},
+		{"jobs.json", export.Jobs},
+	}
+	for _, s := range export.Sessions {
+		files = append(files, file{"sessions/" + s.SessionID + ".json", s})
+	}
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.v); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (h *handler) Erase(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := identity.FromContext(ctx)
+	if id.UserID == "" {
+		return errNoUser
+	}
+	record, err := h.service.Erase(ctx, id.UserID, id.TenantID, middleware.RequestIDFromContext(ctx))
+	if err != nil {
+		logger.FromContext(ctx).Error("user erasure failed", zap.Error(err))
+		return chat.ErrStorage
+	}
+	return c.JSON(http.StatusOK, record)
+}
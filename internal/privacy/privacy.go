@@ -0,0 +1,172 @@
+package privacy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"myapp/internal/chat"
+	"myapp/internal/jobs"
+	"myapp/pkg/idempotency"
+	"myapp/pkg/logger"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Export kullanıcı hakkında tutulan her şeydir.
+type Export struct {
+	UserID     string    `json:"userId"`
+	TenantID   string    `json:"tenantId,omitempty"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Sessions   []Session `json:"sessions"`
+	Jobs       []Job     `json:"jobs"`
+}
+
+// Job async job'ın export'a giren alanlarıdır: API'nin döndüğü görünüm ve
+// kullanıcının prompt'u. Lease, webhook ve request id gibi iç alanlar girmez.
+type Job struct {
+	jobs.View
+	Prompt string `json:"prompt"`
+}
+
+type Session struct {
+	SessionID string             `json:"sessionId"`
+	Messages  []chat.ChatMessage `json:"messages"`
+}
+
+// ErasureRecord silme işleminin audit kaydıdır. Kullanıcı id'si saklanmaz,
+// sadece hash'i tutulur; "bu kullanıcı silindi mi" sorusu id'yi hash'leyerek
+// cevaplanır.
+type ErasureRecord struct {
+	ID        uint      `json:"id"`
+	Subject   string    `gorm:"size:64;index" json:"subject"` // sha256(userID)
+	TenantID  string    `gorm:"size:255" json:"tenantId,omitempty"`
+	RequestID string    `gorm:"size:128" json:"requestId,omitempty"`
+	Sessions  int64     `json:"sessions"`
+	Messages  int64     `json:"messages"`
+	Events    int64     `json:"events"`
+	Jobs      int64     `json:"jobs"`
+	ErasedAt  time.Time `json:"erasedAt"`
+}
+
+func (ErasureRecord) TableName() string {
+	return "erasure_audits"
+}
+
+// AuditStore silme kayıtlarını saklar.
+type AuditStore interface {
+	Record(ctx context.Context, record *ErasureRecord) error
+}
+
+type gormAuditStore struct {
+	db *gorm.DB
+}
+
+func NewGormAuditStore(db *gorm.DB) AuditStore {
+	return &gormAuditStore{db: db}
+}
+
+func (s *gormAuditStore) Record(ctx context.Context, record *ErasureRecord) error {
+	return s.db.WithContext(ctx).Create(record).Error
+}
+
+type Service interface {
+	Export(ctx context.Context, userID, tenantID string) (Export, error)
+	// Erase kullanıcının turn'lerini, bunların outbox event'lerini, job'larını
+	// ve saklanan idempotency cevaplarını kalıcı olarak siler, sonra audit
+	// kaydı yazar. Yarıda kalırsa tekrar çağrılabilir.
+	Erase(ctx context.Context, userID, tenantID, requestID string) (ErasureRecord, error)
+}
+
+type service struct {
+	repo        chat.Repository
+	jobs        jobs.Store
+	idempotency idempotency.Store
+	audit       AuditStore
+	now         func() time.Time
+}
+
+func NewService(repo chat.Repository, jobStore jobs.Store, idempotencyStore idempotency.Store, audit AuditStore) Service {
+	return &service{
+		repo:        repo,
+		jobs:        jobStore,
+		idempotency: idempotencyStore,
+		audit:       audit,
+		now:         time.Now,
+	}
+}
+
+func (s *service) Export(ctx context.Context, userID, tenantID string) (Export, error) {
+	messages, err := s.repo.UserMessages(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	userJobs, err := s.jobs.ListByUser(ctx, userID)
+	if err != nil {
+		return Export{}, err
+	}
+	export := Export{
+		UserID:     userID,
+		TenantID:   tenantID,
+		ExportedAt: s.now().UTC(),
+		Sessions:   []Session{},
+		Jobs:       make([]Job, 0, len(userJobs)),
+	}
+	for _, job := range userJobs {
+		export.Jobs = append(export.Jobs, Job{View: jobs.NewView(job), Prompt: job.Message})
+	}
+	// mesajlar session'a göre sıralı gelir
+	for _, msg := range messages {
+		n := len(export.Sessions)
+		if n == 0 || export.Sessions[n-1].SessionID != msg.SessionID {
+			export.Sessions = append(export.Sessions, Session{SessionID: msg.SessionID})
+			n++
+		}
+		export.Sessions[n-1].Messages = append(export.Sessions[n-1].Messages, msg)
+	}
+	return export, nil
+}
+
+func (s *service) Erase(ctx context.Context, userID, tenantID, requestID string) (ErasureRecord, error) {
+	// yarıda kesilip kısmi silme kalmasın
+	ctx = context.WithoutCancel(ctx)
+	erasure, err := s.repo.EraseUser(ctx, userID)
+	if err != nil {
+		return ErasureRecord{}, err
+	}
+	deletedJobs, err := s.jobs.DeleteByUser(ctx, userID)
+	if err != nil {
+		return ErasureRecord{}, err
+	}
+	deletedResponses, err := s.idempotency.DeleteByUser(ctx, userID)
+	if err != nil {
+		return ErasureRecord{}, err
+	}
+	record := ErasureRecord{
+		Subject:   Subject(userID),
+		TenantID:  tenantID,
+		RequestID: requestID,
+		Sessions:  erasure.Sessions,
+		Messages:  erasure.Messages,
+		Events:    erasure.Events,
+		Jobs:      deletedJobs,
+		ErasedAt:  s.now().UTC(),
+	}
+	if err := s.audit.Record(ctx, &record); err != nil {
+		return ErasureRecord{}, err
+	}
+	logger.FromContext(ctx).Info("user data erased",
+		zap.String("subject", record.Subject),
+		zap.Int64("sessions", record.Sessions),
+		zap.Int64("messages", record.Messages),
+		zap.Int64("jobs", record.Jobs),
+		zap.Int64("idempotencyRecords", deletedResponses))
+	return record, nil
+}
+
+// Subject audit kayıtlarında kullanıcı id'sinin yerine tutulan hash'tir.
+func Subject(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(sum[:])
+}
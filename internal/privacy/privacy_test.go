@@ -0,0 +1,175 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"myapp/internal/chat"
+	"myapp/internal/jobs"
+	"myapp/pkg/idempotency"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+type fakeAudit struct {
+	records []ErasureRecord
+	err     error
+}
+
+func (a *fakeAudit) Record(_ context.Context, r *ErasureRecord) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.records = append(a.records, *r)
+	return nil
+}
+
+func serve(t *testing.T, service Service, method, target, userID string) *httptest.ResponseRecorder {
+	logger.Log = zap.NewNop()
+	e := echo.New()
+	e.HTTPErrorHandler = chat.HTTPErrorHandler
+	e.Use(identity.Middleware())
+	h := NewHandler(service)
+	e.GET("/v1/me/export", h.Export)
+	e.DELETE("/v1/me", h.Erase)
+
+	req := httptest.NewRequest(method, target, nil)
+	if userID != "" {
+		req.Header.Set(identity.HeaderUserID, userID)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestExport_GroupsMessagesBySession(t *testing.T) {
+	//arrange
+	ctrl := gomock.NewController(t)
+	repo := chat.NewMockRepository(ctrl)
+	store := jobs.NewMockStore(ctrl)
+	repo.EXPECT().UserMessages(gomock.Any(), "u1").Return([]chat.ChatMessage{
+		{ID: 1, SessionID: "s1", Kind: chat.UserPrompt, Message: "merhaba", UserID: "u1"},
+		{ID: 2, SessionID: "s1", Kind: chat.LLMOutput, Message: "selam", UserID: "u1"},
+		{ID: 3, SessionID: "s2", Kind: chat.UserPrompt, Message: "nasılsın", UserID: "u1"},
+	}, nil)
+	store.EXPECT().ListByUser(gomock.Any(), "u1").Return([]jobs.Job{{
+		ID: "j1", UserID: "u1", Message: "soru", Result: "cevap",
+		WebhookURL: "https://hooks.example.com/secret-token", RequestID: "req-1",
+	}}, nil)
+
+	//act
+	rec := serve(t, NewService(repo, store, idempotency.NewMemoryStore(), &fakeAudit{}), http.MethodGet, "/v1/me/export", "u1")
+
+	//assert
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment")
+	var export Export
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &export))
+	assert.Equal(t, "u1", export.UserID)
+	require.Len(t, export.Sessions, 2)
+	assert.Equal(t, "s1", export.Sessions[0].SessionID)
+	assert.Len(t, export.Sessions[0].Messages, 2)
+	assert.Equal(t, "nasılsın", export.Sessions[1].Messages[0].Message)
+	require.Len(t, export.Jobs, 1)
+	assert.Equal(t, "j1", export.Jobs[0].ID)
+	assert.Equal(t, "soru", export.Jobs[0].Prompt)
+	assert.Equal(t, "cevap", export.Jobs[0].Message)
+	assert.NotContains(t, rec.Body.String(), "secret-token", "internal job fields are not exported")
+	assert.NotContains(t, rec.Body.String(), "req-1")
+}
+
+func TestExport_Zip(t *testing.T) {
+	//arrange
+	ctrl := gomock.NewController(t)
+	repo := chat.NewMockRepository(ctrl)
+	store := jobs.NewMockStore(ctrl)
+	repo.EXPECT().UserMessages(gomock.Any(), "u1").Return([]chat.ChatMessage{{ID: 1, SessionID: "s1", Message: "merhaba"}}, nil)
+	store.EXPECT().ListByUser(gomock.Any(), "u1").Return(nil, nil)
+
+	//act
+	rec := serve(t, NewService(repo, store, idempotency.NewMemoryStore(), &fakeAudit{}), http.MethodGet, "/v1/me/export?format=zip", "u1")
+
+	//assert
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"user.json", "jobs.json", "sessions/s1.json"}, names)
+	f, _ := zr.File[2].Open()
+	body, _ := io.ReadAll(f)
+	assert.Contains(t, string(body), "merhaba")
+}
+
+func TestExport_RequiresUser(t *testing.T) {
+	rec := serve(t, NewService(nil, nil, nil, nil), http.MethodGet, "/v1/me/export", "")
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestErase_DeletesAndAudits(t *testing.T) {
+	//arrange
+	ctrl := gomock.NewController(t)
+	repo := chat.NewMockRepository(ctrl)
+	store := jobs.NewMockStore(ctrl)
+	audit := &fakeAudit{}
+	responses := idempotency.NewMemoryStore()
+	for _, user := range []string{"u1", "u2"} {
+		ctx := identity.WithContext(context.Background(), identity.Identity{UserID: user})
+		_, _, err := responses.Begin(ctx, "k-"+user, "f", time.Hour)
+		require.NoError(t, err)
+	}
+	repo.EXPECT().EraseUser(gomock.Any(), "u1").Return(chat.Erasure{Sessions: 2, Messages: 6, Events: 9}, nil)
+	store.EXPECT().DeleteByUser(gomock.Any(), "u1").Return(int64(1), nil)
+
+	//act
+	rec := serve(t, NewService(repo, store, responses, audit), http.MethodDelete, "/v1/me", "u1")
+
+	//assert
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, audit.records, 1)
+	record := audit.records[0]
+	assert.Equal(t, Subject("u1"), record.Subject)
+	assert.NotContains(t, rec.Body.String(), `"u1"`)
+	assert.Equal(t, int64(2), record.Sessions)
+	assert.Equal(t, int64(6), record.Messages)
+	assert.Equal(t, int64(9), record.Events)
+	assert.Equal(t, int64(1), record.Jobs)
+	n, err := responses.DeleteByUser(context.Background(), "u1")
+	require.NoError(t, err)
+	assert.Zero(t, n, "the user's stored responses are erased")
+	n, err = responses.DeleteByUser(context.Background(), "u2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "other users' responses are kept")
+}
+
+func TestErase_StorageError(t *testing.T) {
+	//arrange
+	ctrl := gomock.NewController(t)
+	repo := chat.NewMockRepository(ctrl)
+	audit := &fakeAudit{}
+	repo.EXPECT().EraseUser(gomock.Any(), "u1").Return(chat.Erasure{}, errors.New("db down"))
+
+	//act
+	rec := serve(t, NewService(repo, jobs.NewMockStore(ctrl), idempotency.NewMemoryStore(), audit), http.MethodDelete, "/v1/me", "u1")
+
+	//assert
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, audit.records, "failed erasure must not be audited as done")
+}
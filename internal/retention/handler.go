@@ -0,0 +1,80 @@
+package retention
+
+import (
+	"errors"
+	"myapp/internal/chat"
+	"myapp/pkg/logger"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	Report(c echo.Context) error
+	Holds(c echo.Context) error
+	PlaceHold(c echo.Context) error
+	ReleaseHold(c echo.Context) error
+}
+
+type handler struct {
+	purger *Purger
+	holds  HoldStore
+}
+
+// NewHandler retention dry-run raporu ve legal hold yönetimi için admin endpoint'leridir.
+func NewHandler(purger *Purger, holds HoldStore) Handler {
+	return &handler{
+		purger: purger,
+		holds:  holds,
+	}
+}
+
+// Report hiçbir şeyi değiştirmeden şu an çalışsa neyin silineceğini döner.
+func (h *handler) Report(c echo.Context) error {
+	ctx := c.Request().Context()
+	report, err := h.purger.Run(ctx, true)
+	if err != nil {
+		logger.FromContext(ctx).Error("retention dry run failed", zap.Error(err))
+		return chat.ErrStorage
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+func (h *handler) Holds(c echo.Context) error {
+	holds, err := h.holds.List(c.Request().Context())
+	if err != nil {
+		return chat.ErrStorage
+	}
+	return c.JSON(http.StatusOK, echo.Map{"holds": holds})
+}
+
+func (h *handler) PlaceHold(c echo.Context) error {
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return chat.ErrInvalidRequest
+	}
+	hold := Hold{SessionID: c.Param("sessionId"), Reason: input.Reason, CreatedAt: time.Now()}
+	if hold.SessionID == "" || len(hold.SessionID) > 64 || len(hold.Reason) > 255 {
+		return chat.ErrInvalidRequest
+	}
+	if err := h.holds.Place(c.Request().Context(), &hold); err != nil {
+		logger.FromContext(c.Request().Context()).Error("failed to place legal hold", zap.Error(err))
+		return chat.ErrStorage
+	}
+	return c.JSON(http.StatusOK, hold)
+}
+
+func (h *handler) ReleaseHold(c echo.Context) error {
+	err := h.holds.Release(c.Request().Context(), c.Param("sessionId"))
+	if errors.Is(err, ErrHoldNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "legal hold not found")
+	}
+	if err != nil {
+		return chat.ErrStorage
+	}
+	return c.NoContent(http.StatusNoContent)
+}
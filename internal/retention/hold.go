@@ -0,0 +1,63 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Hold legal hold altındaki session'dır; hold kalkana kadar session'ın
+// mesajlarına ve event'lerine retention uygulanmaz.
+type Hold struct {
+	SessionID string    `gorm:"primaryKey;size:64" json:"sessionId"`
+	Reason    string    `gorm:"size:255" json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (Hold) TableName() string {
+	return "legal_holds"
+}
+
+var ErrHoldNotFound = errors.New("legal hold not found")
+
+type HoldStore interface {
+	// Place hold zaten varsa sebebini günceller.
+	Place(ctx context.Context, hold *Hold) error
+	Release(ctx context.Context, sessionID string) error
+	List(ctx context.Context) ([]Hold, error)
+}
+
+type gormHoldStore struct {
+	db *gorm.DB
+}
+
+func NewGormHoldStore(db *gorm.DB) HoldStore {
+	return &gormHoldStore{db: db}
+}
+
+func (s *gormHoldStore) Place(ctx context.Context, hold *Hold) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason"}),
+	}).Create(hold).Error
+}
+
+func (s *gormHoldStore) Release(ctx context.Context, sessionID string) error {
+	res := s.db.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&Hold{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrHoldNotFound
+	}
+	return nil
+}
+
+func (s *gormHoldStore) List(ctx context.Context) ([]Hold, error) {
+	var holds []Hold
+	err := s.db.WithContext(ctx).Order("created_at").Find(&holds).Error
+	return holds, err
+}
@@ -0,0 +1,114 @@
+package retention
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+type Action string
+
+const (
+	// Delete satırı kalıcı olarak siler.
+	Delete Action = "delete"
+	// Anonymize satırı istatistikler için tutar; mesaj metni ve kullanıcı
+	// bilgisi silinir.
+	Anonymize Action = "anonymize"
+)
+
+// Policy retention kurallarının JSON karşılığıdır. Kurallar sırayla denenir,
+// mesajın tenant'ı ve persona'sıyla eşleşen ilk kural uygulanır; hiçbiri
+// eşleşmezse Default. Default yoksa eşleşmeyen mesajlar silinmez.
+type Policy struct {
+	Default *Rule  `json:"default,omitempty"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule'daki boş listeler her şeyle eşleşir; dolu olanların hepsi sağlanmalıdır.
+type Rule struct {
+	Name     string   `json:"name"`
+	Tenants  []string `json:"tenants,omitempty"`
+	Personas []string `json:"personas,omitempty"`
+	// MaxAgeDays'ten eski mesajlara Action uygulanır; 0 mesajları süresiz tutar
+	// (ör. bir tenant'ı sonraki kurallardan ve Default'tan muaf tutmak için).
+	MaxAgeDays int    `json:"maxAgeDays"`
+	Action     Action `json:"action,omitempty"` // boşsa delete
+}
+
+// LoadPolicy JSON dosyasından retention kurallarını okur.
+func LoadPolicy(path string) (Policy, error) {
+	var p Policy
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return p, p.validate()
+}
+
+func (p *Policy) validate() error {
+	seen := map[string]bool{"default": true}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" || seen[rule.Name] {
+			return fmt.Errorf("rule %d: name is empty or not unique", i)
+		}
+		seen[rule.Name] = true
+		// her şeyle eşleşen kural sonrakileri erişilmez kılar; onun yerine default kullanılır
+		if len(rule.Tenants) == 0 && len(rule.Personas) == 0 {
+			return fmt.Errorf("rule %s matches everything, use default instead", rule.Name)
+		}
+		if err := rule.normalize(); err != nil {
+			return err
+		}
+	}
+	if p.Default != nil {
+		if len(p.Default.Tenants) > 0 || len(p.Default.Personas) > 0 {
+			return fmt.Errorf("default rule cannot have tenants or personas")
+		}
+		p.Default.Name = "default"
+		return p.Default.normalize()
+	}
+	return nil
+}
+
+func (r *Rule) normalize() error {
+	if r.MaxAgeDays < 0 {
+		return fmt.Errorf("rule %s: maxAgeDays must not be negative", r.Name)
+	}
+	switch r.Action {
+	case "":
+		r.Action = Delete
+	case Delete, Anonymize:
+	default:
+		return fmt.Errorf("rule %s: unknown action %q", r.Name, r.Action)
+	}
+	return nil
+}
+
+// For mesajın tenant'ı ve persona'sına uygulanacak kuralı döner; uygulanacak
+// kural yoksa false.
+func (p Policy) For(tenantID, persona string) (Rule, bool) {
+	for _, rule := range p.Rules {
+		if rule.matches(tenantID, persona) {
+			return rule, true
+		}
+	}
+	if p.Default != nil {
+		return *p.Default, true
+	}
+	return Rule{}, false
+}
+
+func (r Rule) matches(tenantID, persona string) bool {
+	if len(r.Tenants) > 0 && !slices.Contains(r.Tenants, tenantID) {
+		return false
+	}
+	if len(r.Personas) > 0 && !slices.Contains(r.Personas, persona) {
+		return false
+	}
+	return true
+}
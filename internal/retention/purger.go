@@ -0,0 +1,311 @@
+package retention
+
+import (
+	"context"
+	"myapp/internal/chat"
+	"myapp/internal/events"
+	"myapp/internal/jobs"
+	"myapp/pkg/idempotency"
+	"myapp/pkg/logger"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Config struct {
+	Interval  time.Duration
+	BatchSize int
+	// DryRun true ise hiçbir satır değiştirilmez; her çalışmada neyin
+	// silineceği loglanır.
+	DryRun bool
+	// OutboxMaxAgeDays sıfırdan büyükse bundan eski yayınlanmış outbox
+	// event'leri silinir; payload'larında mesaj metni vardır.
+	OutboxMaxAgeDays int
+}
+
+// Reporter işlenen satır sayılarını metriklere yazar.
+type Reporter interface {
+	RetentionApplied(rule string, action string, rows int64)
+}
+
+// Report bir çalışmanın (ya da dry-run'ın) sonucudur.
+type Report struct {
+	DryRun       bool         `json:"dryRun"`
+	Rules        []RuleReport `json:"rules"`
+	OutboxEvents int64        `json:"outboxEvents"`
+}
+
+type RuleReport struct {
+	Rule   string    `json:"rule"`
+	Action Action    `json:"action"`
+	Cutoff time.Time `json:"cutoff"`
+	Rows   int64     `json:"rows"`
+	// Jobs ve IdempotencyRecords kuralla eşleşip silinen async job'lar ve
+	// saklanan idempotency cevaplarıdır; Anonymize kurallarında da silinirler.
+	Jobs               int64 `json:"jobs"`
+	IdempotencyRecords int64 `json:"idempotencyRecords"`
+}
+
+// Purger retention kurallarını ChatMessage satırlarına batch'ler halinde
+// uygular; aynı prompt ve cevapları taşıyan chat_jobs ve idempotency_records
+// satırları da aynı cutoff'la silinir. Legal hold altındaki session'lara dokunmaz. Birden fazla replica'da
+// aynı anda çalışması güvenlidir; silme ve anonimleştirme idempotenttir.
+type Purger struct {
+	db       *gorm.DB
+	policy   Policy
+	cfg      Config
+	reporter Reporter
+	now      func() time.Time
+	wg       sync.WaitGroup
+}
+
+// NewPurger reporter nil olabilir.
+func NewPurger(db *gorm.DB, policy Policy, cfg Config, reporter Reporter) *Purger {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Purger{
+		db:       db,
+		policy:   policy,
+		cfg:      cfg,
+		reporter: reporter,
+		now:      time.Now,
+	}
+}
+
+// Start ctx iptal edilene kadar her Interval'da Run çalıştırır.
+func (p *Purger) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			report, err := p.Run(ctx, p.cfg.DryRun)
+			if err != nil && ctx.Err() == nil {
+				logger.Log.Error("retention run failed", zap.Error(err))
+			} else if err == nil {
+				logger.Log.Info("retention run finished", zap.Any("report", report))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.cfg.Interval):
+			}
+		}
+	}()
+}
+
+func (p *Purger) Wait() {
+	p.wg.Wait()
+}
+
+// Run kuralları sırayla uygular. dryRun'da satırlar sadece sayılır.
+func (p *Purger) Run(ctx context.Context, dryRun bool) (Report, error) {
+	report := Report{DryRun: dryRun, Rules: []RuleReport{}}
+	now := p.now()
+	for i, rule := range p.rules() {
+		if rule.MaxAgeDays == 0 {
+			continue
+		}
+		rr := RuleReport{Rule: rule.Name, Action: rule.Action, Cutoff: cutoff(now, rule.MaxAgeDays)}
+		q := func() *gorm.DB { return p.messages(ctx, i, rr.Cutoff) }
+		var err error
+		if dryRun {
+			err = q().Count(&rr.Rows).Error
+		} else {
+			rr.Rows, err = p.apply(ctx, q, rule)
+		}
+		if err == nil {
+			rr.Jobs, err = p.purge(ctx, "jobs", &jobs.Job{}, "id", func() *gorm.DB {
+				return p.related(ctx, i, &jobs.Job{}, rr.Cutoff).
+					Where("status IN ? AND webhook_status <> ?", []jobs.Status{jobs.StatusSucceeded, jobs.StatusFailed}, jobs.WebhookPending)
+			}, dryRun)
+		}
+		if err == nil {
+			rr.IdempotencyRecords, err = p.purge(ctx, "idempotency", &idempotency.Record{}, "idempotency_key", func() *gorm.DB {
+				return p.related(ctx, i, &idempotency.Record{}, rr.Cutoff).
+					Where("status = ?", idempotency.StatusCompleted)
+			}, dryRun)
+		}
+		report.Rules = append(report.Rules, rr)
+		if err != nil {
+			return report, err
+		}
+	}
+	if p.cfg.OutboxMaxAgeDays > 0 {
+		var err error
+		report.OutboxEvents, err = p.purgeOutbox(ctx, cutoff(now, p.cfg.OutboxMaxAgeDays), dryRun)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// rules Default'u en sona ekler.
+func (p *Purger) rules() []Rule {
+	rules := p.policy.Rules
+	if p.policy.Default != nil {
+		rules = append(rules[:len(rules):len(rules)], *p.policy.Default)
+	}
+	return rules
+}
+
+// messages i. kuralın uygulanacağı satırlardır: kuralla eşleşen, önceki
+// kuralların hiçbiriyle eşleşmeyen, cutoff'tan eski ve hold'da olmayan.
+func (p *Purger) messages(ctx context.Context, i int, before time.Time) *gorm.DB {
+	rules := p.rules()
+	q := p.db.WithContext(ctx).Model(&chat.ChatMessage{}).
+		Where("timestamp < ?", before.Unix()).
+		Where("session_id NOT IN (?)", p.db.Model(&Hold{}).Select("session_id"))
+	if rules[i].Action == Anonymize {
+		q = q.Where("message <> ''")
+	}
+	if cond := p.match(rules[i]); cond != nil {
+		q = q.Where(cond)
+	}
+	for _, earlier := range rules[:i] {
+		q = q.Not(p.match(earlier))
+	}
+	return q
+}
+
+// related messages'ın chat_jobs ve idempotency_records karşılığıdır; yaş
+// created_at'ten bakılır.
+func (p *Purger) related(ctx context.Context, i int, model any, before time.Time) *gorm.DB {
+	rules := p.rules()
+	q := p.db.WithContext(ctx).Model(model).
+		Where("created_at < ?", before).
+		Where("session_id NOT IN (?)", p.db.Model(&Hold{}).Select("session_id"))
+	if cond := p.match(rules[i]); cond != nil {
+		q = q.Where(cond)
+	}
+	for _, earlier := range rules[:i] {
+		q = q.Not(p.match(earlier))
+	}
+	return q
+}
+
+// match kuralın tenant ve persona koşuludur; Default için nil.
+func (p *Purger) match(rule Rule) *gorm.DB {
+	if len(rule.Tenants) == 0 && len(rule.Personas) == 0 {
+		return nil
+	}
+	cond := p.db.Session(&gorm.Session{NewDB: true})
+	if len(rule.Tenants) > 0 {
+		cond = cond.Where("tenant_id IN ?", rule.Tenants)
+	}
+	if len(rule.Personas) > 0 {
+		cond = cond.Where("persona IN ?", rule.Personas)
+	}
+	return cond
+}
+
+func (p *Purger) apply(ctx context.Context, q func() *gorm.DB, rule Rule) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		var ids []int
+		if err := q().Order("id").Limit(p.cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		batch := p.db.WithContext(ctx).Where("id IN ?", ids)
+		var res *gorm.DB
+		if rule.Action == Anonymize {
+			res = batch.Model(&chat.ChatMessage{}).Updates(map[string]any{
+				"message":       "",
+				"key_version":   0,
+				"user_id":       "",
+				"guard_signals": "",
+			})
+		} else {
+			res = batch.Delete(&chat.ChatMessage{})
+		}
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if p.reporter != nil {
+			p.reporter.RetentionApplied(rule.Name, string(rule.Action), res.RowsAffected)
+		}
+		if len(ids) < p.cfg.BatchSize {
+			break
+		}
+	}
+	return total, ctx.Err()
+}
+
+// purge q'nun döndürdüğü satırları string primary key'leri üzerinden batch'ler
+// halinde siler.
+func (p *Purger) purge(ctx context.Context, name string, model any, pk string, q func() *gorm.DB, dryRun bool) (int64, error) {
+	if dryRun {
+		var n int64
+		err := q().Count(&n).Error
+		return n, err
+	}
+	var total int64
+	for ctx.Err() == nil {
+		var keys []string
+		if err := q().Order(pk).Limit(p.cfg.BatchSize).Pluck(pk, &keys).Error; err != nil {
+			return total, err
+		}
+		if len(keys) == 0 {
+			break
+		}
+		// koşullar tekrar uygulanır; bu arada hold konan session'a dokunulmaz
+		res := q().Where(pk+" IN ?", keys).Delete(model)
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if p.reporter != nil {
+			p.reporter.RetentionApplied(name, string(Delete), res.RowsAffected)
+		}
+		if len(keys) < p.cfg.BatchSize {
+			break
+		}
+	}
+	return total, ctx.Err()
+}
+
+func (p *Purger) purgeOutbox(ctx context.Context, before time.Time, dryRun bool) (int64, error) {
+	q := func() *gorm.DB {
+		return p.db.WithContext(ctx).Model(&events.Record{}).
+			Where("published_at IS NOT NULL AND occurred_at < ?", before).
+			Where("session_id NOT IN (?)", p.db.Model(&Hold{}).Select("session_id"))
+	}
+	if dryRun {
+		var n int64
+		err := q().Count(&n).Error
+		return n, err
+	}
+	var total int64
+	for ctx.Err() == nil {
+		var ids []uint64
+		if err := q().Order("id").Limit(p.cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		res := p.db.WithContext(ctx).Where("id IN ?", ids).Delete(&events.Record{})
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if p.reporter != nil {
+			p.reporter.RetentionApplied("outbox", string(Delete), res.RowsAffected)
+		}
+		if len(ids) < p.cfg.BatchSize {
+			break
+		}
+	}
+	return total, ctx.Err()
+}
+
+func cutoff(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
@@ -0,0 +1,191 @@
+package retention
+
+import (
+	"context"
+	"myapp/internal/chat"
+	"myapp/internal/jobs"
+	"myapp/internal/migrations"
+	"myapp/pkg/database"
+	"myapp/pkg/idempotency"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestLoadPolicy_Example(t *testing.T) {
+	p, err := LoadPolicy("../../config/retention_policy.example.json")
+
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 3)
+	assert.Equal(t, Delete, p.Rules[0].Action, "empty action defaults to delete")
+	assert.Equal(t, "default", p.Default.Name)
+}
+
+func TestLoadPolicy_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"catch-all rule":   `{"rules":[{"name":"all","maxAgeDays":30}]}`,
+		"duplicate name":   `{"rules":[{"name":"a","tenants":["x"]},{"name":"a","tenants":["y"]}]}`,
+		"negative age":     `{"rules":[{"name":"a","tenants":["x"],"maxAgeDays":-1}]}`,
+		"unknown action":   `{"rules":[{"name":"a","tenants":["x"],"action":"archive"}]}`,
+		"scoped default":   `{"default":{"tenants":["x"],"maxAgeDays":30}}`,
+		"reserved name":    `{"rules":[{"name":"default","tenants":["x"]}]}`,
+		"malformed policy": `{"rules":`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+
+			_, err := LoadPolicy(path)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPolicyFor_FirstMatchWins(t *testing.T) {
+	p, err := LoadPolicy("../../config/retention_policy.example.json")
+	require.NoError(t, err)
+
+	cases := []struct {
+		tenant, persona, want string
+	}{
+		{"acme", "support", "acme-legal"},
+		{"globex", "support", "short-lived"},
+		{"other", "support", "support"},
+		{"other", "coder", "default"},
+		{"", "", "default"},
+	}
+	for _, tc := range cases {
+		rule, ok := p.For(tc.tenant, tc.persona)
+		assert.True(t, ok)
+		assert.Equal(t, tc.want, rule.Name, "%s/%s", tc.tenant, tc.persona)
+	}
+
+	_, ok := Policy{}.For("acme", "")
+	assert.False(t, ok, "no default keeps unmatched messages")
+}
+
+// dryRunDB SQL'i veritabanına bağlanmadan üretir.
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/db", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	return db
+}
+
+func TestPurgerMessages_ExcludesEarlierRulesAndHolds(t *testing.T) {
+	//arrange
+	p, err := LoadPolicy("../../config/retention_policy.example.json")
+	require.NoError(t, err)
+	purger := NewPurger(dryRunDB(t), p, Config{}, nil)
+	before := time.Unix(1700000000, 0)
+
+	//act
+	var n int64
+	stmt := purger.messages(context.Background(), 2, before).Count(&n).Statement
+	sql := stmt.Dialector.Explain(stmt.SQL.String(), stmt.Vars...)
+
+	//assert
+	assert.Contains(t, sql, "timestamp < 1700000000")
+	assert.Contains(t, sql, "session_id NOT IN (SELECT `session_id` FROM `legal_holds`")
+	assert.Contains(t, sql, "message <> ''", "anonymize skips already anonymized rows")
+	assert.Contains(t, sql, "persona IN ('support')")
+	assert.Contains(t, sql, "NOT tenant_id IN ('acme')")
+	assert.Contains(t, sql, "NOT tenant_id IN ('globex','initech')")
+}
+
+func TestPurgerRun_SQLite(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	db, err := database.Connect(database.Config{Driver: database.SQLite, DSN: ":memory:"})
+	require.NoError(t, err)
+	m, err := migrations.New(db, 0)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	now := time.Now()
+	days := func(n int) int64 { return now.AddDate(0, 0, -n).Unix() }
+	rows := []chat.ChatMessage{
+		{SessionID: "a1", TenantID: "acme", UserID: "u1", Message: "eski", Timestamp: days(40), Seq: 1},
+		{SessionID: "a1", TenantID: "acme", UserID: "u1", Message: "yeni", Timestamp: days(1), Seq: 2},
+		{SessionID: "g1", TenantID: "globex", Message: "çok eski", Timestamp: days(100)},
+		{SessionID: "g2", TenantID: "globex", Message: "hold", Timestamp: days(100)},
+	}
+	require.NoError(t, db.Create(&rows).Error)
+	require.NoError(t, NewGormHoldStore(db).Place(ctx, &Hold{SessionID: "g2", Reason: "dava"}))
+	policy := Policy{
+		Default: &Rule{Name: "default", MaxAgeDays: 90, Action: Delete},
+		Rules:   []Rule{{Name: "acme", Tenants: []string{"acme"}, MaxAgeDays: 30, Action: Anonymize}},
+	}
+	purger := NewPurger(db, policy, Config{BatchSize: 1}, nil)
+	purger.now = func() time.Time { return now }
+
+	//act
+	dry, err := purger.Run(ctx, true)
+	require.NoError(t, err)
+	report, err := purger.Run(ctx, false)
+	require.NoError(t, err)
+
+	//assert
+	assert.Equal(t, dry.Rules, report.Rules, "dry run reports the same rows")
+	require.Len(t, report.Rules, 2)
+	assert.Equal(t, int64(1), report.Rules[0].Rows)
+	assert.Equal(t, int64(1), report.Rules[1].Rows)
+
+	var left []chat.ChatMessage
+	require.NoError(t, db.Order("id").Find(&left).Error)
+	require.Len(t, left, 3)
+	assert.Equal(t, "", left[0].Message)
+	assert.Equal(t, "", left[0].UserID)
+	assert.Equal(t, "yeni", left[1].Message)
+	assert.Equal(t, "g2", left[2].SessionID, "held session is kept")
+}
+
+func TestPurgerRun_PurgesJobsAndIdempotencyRecords(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	db, err := database.Connect(database.Config{Driver: database.SQLite, DSN: ":memory:"})
+	require.NoError(t, err)
+	m, err := migrations.New(db, 0)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	now := time.Now()
+	old, recent := now.AddDate(0, 0, -40), now.AddDate(0, 0, -1)
+	require.NoError(t, db.Create(&[]jobs.Job{
+		{ID: "old", SessionID: "s1", Status: jobs.StatusSucceeded, Message: "eski", CreatedAt: old},
+		{ID: "recent", SessionID: "s1", Status: jobs.StatusSucceeded, Message: "yeni", CreatedAt: recent},
+		{ID: "held", SessionID: "s2", Status: jobs.StatusSucceeded, Message: "hold", CreatedAt: old},
+		{ID: "queued", SessionID: "s1", Status: jobs.StatusQueued, Message: "sırada", CreatedAt: old},
+	}).Error)
+	require.NoError(t, db.Create(&[]idempotency.Record{
+		{Key: "old", SessionID: "s1", Status: idempotency.StatusCompleted, Body: []byte("eski"), CreatedAt: old, ExpiresAt: now.Add(time.Hour)},
+		{Key: "recent", SessionID: "s1", Status: idempotency.StatusCompleted, Body: []byte("yeni"), CreatedAt: recent, ExpiresAt: now.Add(time.Hour)},
+		{Key: "held", SessionID: "s2", Status: idempotency.StatusCompleted, Body: []byte("hold"), CreatedAt: old, ExpiresAt: now.Add(time.Hour)},
+	}).Error)
+	require.NoError(t, NewGormHoldStore(db).Place(ctx, &Hold{SessionID: "s2", Reason: "dava"}))
+	purger := NewPurger(db, Policy{Default: &Rule{Name: "default", MaxAgeDays: 30, Action: Delete}}, Config{BatchSize: 1}, nil)
+	purger.now = func() time.Time { return now }
+
+	//act
+	report, err := purger.Run(ctx, false)
+
+	//assert
+	require.NoError(t, err)
+	require.Len(t, report.Rules, 1)
+	assert.Equal(t, int64(1), report.Rules[0].Jobs)
+	assert.Equal(t, int64(1), report.Rules[0].IdempotencyRecords)
+
+	var jobIDs, recordKeys []string
+	require.NoError(t, db.Model(&jobs.Job{}).Order("id").Pluck("id", &jobIDs).Error)
+	require.NoError(t, db.Model(&idempotency.Record{}).Order("idempotency_key").Pluck("idempotency_key", &recordKeys).Error)
+	assert.Equal(t, []string{"held", "queued", "recent"}, jobIDs, "held and unfinished jobs are kept")
+	assert.Equal(t, []string{"held", "recent"}, recordKeys)
+}
@@ -0,0 +1,13 @@
+package tracing
+
+import "go.opentelemetry.io/otel/attribute"
+
+// semconv'da karşılığı olmayan, servise özel attribute'lar
+const (
+	attributeRequestID   = attribute.Key("request.id")
+	attributeSessionID   = attribute.Key("chat.session_id")
+	attributeMessageKind = attribute.Key("chat.message.kind")
+	attributeSeq         = attribute.Key("chat.seq")
+	attributeHistoryLen  = attribute.Key("chat.history.length")
+	attributeEvents      = attribute.Key("chat.events")
+)
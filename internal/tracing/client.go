@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"myapp/internal/chat"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedClient struct {
+	next     chat.Client
+	t        *Tracing
+	provider string
+	model    string
+}
+
+// InstrumentClient tek bir provider'ın client'ını sarar; retry'ın içinde
+// kullanılırsa her deneme ayrı bir span olur. Prompt içeriği span'e yazılmaz.
+func (t *Tracing) InstrumentClient(next chat.Client, provider, model string) chat.Client {
+	return &tracedClient{next: next, t: t, provider: provider, model: model}
+}
+
+func (c *tracedClient) GetCompletion(ctx context.Context, message string, messages []chat.ChatMessage) (chat.Completion, error) {
+	model := c.model
+	if override := chat.ModelFromContext(ctx); override != "" {
+		model = override
+	}
+	ctx, span := c.t.tracer.Start(ctx, "Client.GetCompletion",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.GenAIOperationNameChat,
+			semconv.GenAISystemKey.String(c.provider),
+			semconv.GenAIRequestModel(model),
+			attributeHistoryLen.Int(len(messages)),
+		))
+	if len(messages) > 0 {
+		span.SetAttributes(attributeSessionID.String(messages[0].SessionID))
+	}
+	completion, err := c.next.GetCompletion(ctx, message, messages)
+	if err != nil {
+		end(span, err)
+		return completion, err
+	}
+	end(span, nil,
+		semconv.GenAIResponseModel(completion.Model),
+		semconv.GenAIUsageInputTokens(int(completion.PromptTokens)),
+		semconv.GenAIUsageOutputTokens(int(completion.CompletionTokens)))
+	return completion, nil
+}
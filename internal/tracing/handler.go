@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"myapp/internal/chat"
+	"myapp/pkg/middleware"
+
+	"github.com/labstack/echo"
+)
+
+type tracedHandler struct {
+	next chat.Handler
+	t    *Tracing
+}
+
+// InstrumentHandler handler metotlarını server span'inin altında ayrı bir
+// span'de çalıştırır; bind/validation süresi de böylece görünür.
+func (t *Tracing) InstrumentHandler(next chat.Handler) chat.Handler {
+	return &tracedHandler{next: next, t: t}
+}
+
+func (h *tracedHandler) Send(c echo.Context) error {
+	return h.run(c, "handler.Send", h.next.Send)
+}
+
+func (h *tracedHandler) ShowHistory(c echo.Context) error {
+	return h.run(c, "handler.ShowHistory", h.next.ShowHistory)
+}
+
+func (h *tracedHandler) run(c echo.Context, name string, next echo.HandlerFunc) error {
+	req := c.Request()
+	ctx, span := h.t.tracer.Start(req.Context(), name)
+	c.SetRequest(req.WithContext(ctx))
+	err := next(c)
+	if id := sessionID(c); id != "" {
+		span.SetAttributes(attributeSessionID.String(id))
+	}
+	end(span, err)
+	return err
+}
+
+func sessionID(c echo.Context) string {
+	if id, ok := c.Get(middleware.SessionIDKey).(string); ok && id != "" {
+		return id
+	}
+	return c.Param("sessionId")
+}
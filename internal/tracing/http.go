@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"fmt"
+	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
+
+	"github.com/labstack/echo"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Middleware gelen traceparent/tracestate header'larından trace'i devam
+// ettirir ve isteğin server span'ini açar. RequestID'den sonra gelmeli ki
+// request logger'ına traceID eklenebilsin.
+func (t *Tracing) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := t.propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			ctx, span := t.tracer.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(req.URL.Path),
+				))
+			defer span.End()
+			if id := middleware.RequestIDFromContext(ctx); id != "" {
+				span.SetAttributes(attributeRequestID.String(id))
+			}
+			if sc := span.SpanContext(); sc.IsValid() {
+				ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(zap.String("traceID", sc.TraceID().String())))
+			}
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// status'u görebilmek için hatayı burada yazdırıyoruz
+				c.Error(err)
+			}
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			// server span'lerinde sadece 5xx hatadır
+			if status >= 500 {
+				span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"myapp/internal/chat"
+	"myapp/internal/events"
+	"myapp/pkg/database"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedRepository struct {
+	next   chat.Repository
+	t      *Tracing
+	system attribute.KeyValue
+}
+
+// InstrumentRepository driver span'lerin db.system.name'idir (database.MySQL vb.).
+func (t *Tracing) InstrumentRepository(next chat.Repository, driver string) chat.Repository {
+	return &tracedRepository{next: next, t: t, system: dbSystem(driver)}
+}
+
+func dbSystem(driver string) attribute.KeyValue {
+	switch driver {
+	case database.Postgres:
+		return semconv.DBSystemNamePostgreSQL
+	case database.SQLite:
+		return semconv.DBSystemNameSQLite
+	default:
+		return semconv.DBSystemNameMySQL
+	}
+}
+
+func (r *tracedRepository) start(ctx context.Context, op string) (context.Context, trace.Span) {
+	return r.t.tracer.Start(ctx, "Repository."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(r.system, semconv.DBOperationName(op)))
+}
+
+func (r *tracedRepository) Save(ctx context.Context, message *chat.ChatMessage, evs ...events.Event) error {
+	ctx, span := r.start(ctx, "Save")
+	err := r.next.Save(ctx, message, evs...)
+	end(span, err,
+		attributeSessionID.String(message.SessionID),
+		attributeMessageKind.String(string(message.Kind)),
+		attributeSeq.Int64(message.Seq),
+		attributeEvents.Int(len(evs)))
+	return err
+}
+
+func (r *tracedRepository) Find(ctx context.Context, sessionID string) ([]chat.ChatMessage, error) {
+	ctx, span := r.start(ctx, "Find")
+	messages, err := r.next.Find(ctx, sessionID)
+	end(span, err, attributeSessionID.String(sessionID), attributeHistoryLen.Int(len(messages)))
+	return messages, err
+}
+
+func (r *tracedRepository) Flagged(ctx context.Context, beforeID int, limit int) ([]chat.ChatMessage, error) {
+	ctx, span := r.start(ctx, "Flagged")
+	messages, err := r.next.Flagged(ctx, beforeID, limit)
+	end(span, err, attributeHistoryLen.Int(len(messages)))
+	return messages, err
+}
+
+func (r *tracedRepository) AddEvents(ctx context.Context, evs ...events.Event) error {
+	ctx, span := r.start(ctx, "AddEvents")
+	err := r.next.AddEvents(ctx, evs...)
+	end(span, err, attributeEvents.Int(len(evs)))
+	return err
+}
+
+// user id PII olduğu için span'e yazılmaz
+func (r *tracedRepository) UserMessages(ctx context.Context, userID string) ([]chat.ChatMessage, error) {
+	ctx, span := r.start(ctx, "UserMessages")
+	messages, err := r.next.UserMessages(ctx, userID)
+	end(span, err, attributeHistoryLen.Int(len(messages)))
+	return messages, err
+}
+
+func (r *tracedRepository) EraseUser(ctx context.Context, userID string) (chat.Erasure, error) {
+	ctx, span := r.start(ctx, "EraseUser")
+	erasure, err := r.next.EraseUser(ctx, userID)
+	end(span, err, attributeEvents.Int64(erasure.Events))
+	return erasure, err
+}
+
+// query kullanıcı içeriği olduğu için span'e yazılmaz
+func (r *tracedRepository) Search(ctx context.Context, userID, query string, limit int) ([]chat.ChatMessage, error) {
+	ctx, span := r.start(ctx, "Search")
+	messages, err := r.next.Search(ctx, userID, query, limit)
+	end(span, err, attributeHistoryLen.Int(len(messages)))
+	return messages, err
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"myapp/internal/chat"
+)
+
+type tracedService struct {
+	next chat.Service
+	t    *Tracing
+}
+
+func (t *Tracing) InstrumentService(next chat.Service) chat.Service {
+	return &tracedService{next: next, t: t}
+}
+
+func (s *tracedService) SendMessage(ctx context.Context, sessionID string, message string) (chat.Chat, error) {
+	ctx, span := s.t.tracer.Start(ctx, "Service.SendMessage")
+	res, err := s.next.SendMessage(ctx, sessionID, message)
+	// yeni session'da id'yi service üretir
+	if res.SessionID != "" {
+		sessionID = res.SessionID
+	}
+	end(span, err, attributeSessionID.String(sessionID))
+	return res, err
+}
+
+func (s *tracedService) Resume(ctx context.Context, sessionID string, promptSeq int64) (chat.Chat, error) {
+	ctx, span := s.t.tracer.Start(ctx, "Service.Resume")
+	res, err := s.next.Resume(ctx, sessionID, promptSeq)
+	end(span, err, attributeSessionID.String(sessionID))
+	return res, err
+}
+
+func (s *tracedService) FindHistory(ctx context.Context, sessionID string) ([]chat.ChatMessage, error) {
+	ctx, span := s.t.tracer.Start(ctx, "Service.FindHistory")
+	history, err := s.next.FindHistory(ctx, sessionID)
+	end(span, err, attributeSessionID.String(sessionID), attributeHistoryLen.Int(len(history)))
+	return history, err
+}
+
+func (s *tracedService) Drain(ctx context.Context) error {
+	return s.next.Drain(ctx)
+}
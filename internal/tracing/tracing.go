@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const instrumentationName = "myapp"
+
+// Tracing servisin span'lerini üretir. Metrics gibi business koduna dokunmaz;
+// HTTP middleware'i ve Handler/Service/Repository/Client decorator'ları yazar.
+type Tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// New verilen provider ile span üretir; testlerde tracetest kullanılır.
+func New(tp trace.TracerProvider) *Tracing {
+	return &Tracing{
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+}
+
+// Config OTLP exporter ayarlarıdır. Endpoint, header'lar ve sampler standart
+// OTEL_* env değişkenlerinden exporter/SDK tarafından okunur.
+type Config struct {
+	Enabled        bool
+	ServiceName    string
+	ServiceVersion string
+}
+
+// Setup Enabled ise OTLP/HTTP exporter'lı bir provider kurar, değilse span'ler
+// kaydedilmez ama traceparent header'ı yine de taşınır. Dönen fonksiyon
+// bekleyen span'leri gönderip exporter'ı kapatır.
+func Setup(ctx context.Context, cfg Config) (*Tracing, func(context.Context) error, error) {
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	otel.SetTextMapPropagator(propagator)
+	if !cfg.Enabled {
+		return New(noop.NewTracerProvider()), func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+	// sampler verilmedi: SDK OTEL_TRACES_SAMPLER(_ARG)'dan okur
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return New(tp), tp.Shutdown, nil
+}
+
+// end span'i hata varsa error statüsüyle kapatır.
+func end(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"myapp/internal/chat"
+	"myapp/pkg/database"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+const testSessionID = "811360d0-462f-4fbf-b90b-ccba665986f1"
+
+func newTracing() (*Tracing, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return New(tp), exporter
+}
+
+// byName span'leri isimle indeksler; aynı isimli span'ler sırayla tutulur.
+func byName(spans tracetest.SpanStubs) map[string][]tracetest.SpanStub {
+	out := make(map[string][]tracetest.SpanStub)
+	for _, s := range spans {
+		out[s.Name] = append(out[s.Name], s)
+	}
+	return out
+}
+
+func attr(s tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestSend_SpanTree(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	tr, exporter := newTracing()
+	ctrl := gomock.NewController(t)
+	repo := chat.NewMockRepository(ctrl)
+	llm := chat.NewMockClient(ctrl)
+
+	client := chat.NewRetryingClient(tr.InstrumentClient(llm, "openai", "gpt-4o"),
+		chat.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	service := tr.InstrumentService(chat.NewService(tr.InstrumentRepository(repo, database.MySQL), client))
+	handler := tr.InstrumentHandler(chat.NewHandler(service))
+
+	history := []chat.ChatMessage{{SessionID: testSessionID, Kind: chat.UserPrompt, Message: "selam", Seq: 1}}
+	repo.EXPECT().Find(gomock.Any(), testSessionID).Return(history, nil)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	gomock.InOrder(
+		llm.EXPECT().GetCompletion(gomock.Any(), "merhaba canım", gomock.Any()).Return(chat.Completion{}, context.DeadlineExceeded),
+		llm.EXPECT().GetCompletion(gomock.Any(), "merhaba canım", gomock.Any()).
+			Return(chat.Completion{Message: "selam", Model: "gpt-4o-2024", PromptTokens: 12, CompletionTokens: 3}, nil),
+	)
+
+	e := echo.New()
+	e.HTTPErrorHandler = chat.HTTPErrorHandler
+	e.Use(tr.Middleware())
+	e.POST("/v1/chat", handler.Send)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat",
+		strings.NewReader(`{"Message":"merhaba canım","SessionID":"`+testSessionID+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	//act
+	e.ServeHTTP(rec, req)
+
+	//assert
+	require.Equal(t, http.StatusOK, rec.Code)
+	spans := byName(exporter.GetSpans())
+	require.Len(t, spans["POST /v1/chat"], 1)
+	require.Len(t, spans["handler.Send"], 1)
+	require.Len(t, spans["Service.SendMessage"], 1)
+	require.Len(t, spans["Repository.Find"], 1)
+	require.Len(t, spans["Repository.Save"], 2)
+	require.Len(t, spans["Client.GetCompletion"], 2, "her retry denemesi ayrı span")
+
+	server := spans["POST /v1/chat"][0]
+	send := spans["handler.Send"][0]
+	svc := spans["Service.SendMessage"][0]
+	assert.False(t, server.Parent.IsValid())
+	assert.Equal(t, server.SpanContext.SpanID(), send.Parent.SpanID())
+	assert.Equal(t, send.SpanContext.SpanID(), svc.Parent.SpanID())
+	assert.Equal(t, svc.SpanContext.SpanID(), spans["Repository.Find"][0].Parent.SpanID())
+	for _, s := range append(spans["Repository.Save"], spans["Client.GetCompletion"]...) {
+		assert.Equal(t, svc.SpanContext.SpanID(), s.Parent.SpanID(), s.Name)
+	}
+
+	assert.Equal(t, int64(200), attr(server, "http.response.status_code").AsInt64())
+	assert.Equal(t, testSessionID, attr(send, attributeSessionID).AsString())
+	assert.Equal(t, testSessionID, attr(svc, attributeSessionID).AsString())
+
+	failed, ok := spans["Client.GetCompletion"][0], spans["Client.GetCompletion"][1]
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.Equal(t, "gpt-4o", attr(ok, "gen_ai.request.model").AsString())
+	assert.Equal(t, "gpt-4o-2024", attr(ok, "gen_ai.response.model").AsString())
+	assert.Equal(t, int64(12), attr(ok, "gen_ai.usage.input_tokens").AsInt64())
+	assert.Equal(t, int64(3), attr(ok, "gen_ai.usage.output_tokens").AsInt64())
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	//arrange
+	tr, exporter := newTracing()
+	e := echo.New()
+	e.Use(tr.Middleware())
+	e.GET("/v1/chat/:sessionId", func(c echo.Context) error { return errors.New("boom") })
+	req := httptest.NewRequest(http.MethodGet, "/v1/chat/"+testSessionID, nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	//act
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	//assert
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /v1/chat/:sessionId", spans[0].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+	assert.True(t, spans[0].Parent.IsRemote())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestInstrumentRepository_RecordsError(t *testing.T) {
+	tr, exporter := newTracing()
+	ctrl := gomock.NewController(t)
+	next := chat.NewMockRepository(ctrl)
+	next.EXPECT().Find(gomock.Any(), testSessionID).Return(nil, errors.New("db down"))
+
+	tr.InstrumentRepository(next, database.MySQL).Find(context.Background(), testSessionID)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "Repository.Find", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "mysql", attr(spans[0], "db.system.name").AsString())
+	assert.Equal(t, testSessionID, attr(spans[0], attributeSessionID).AsString())
+}
+
+func TestInstrumentRepository_DBSystemFollowsDriver(t *testing.T) {
+	tr, exporter := newTracing()
+	ctrl := gomock.NewController(t)
+	next := chat.NewMockRepository(ctrl)
+	next.EXPECT().Search(gomock.Any(), "u1", "kargo", 10).Return(nil, nil)
+
+	tr.InstrumentRepository(next, database.Postgres).Search(context.Background(), "u1", "kargo", 10)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "postgresql", attr(spans[0], "db.system.name").AsString())
+}
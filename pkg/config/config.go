@@ -2,16 +2,151 @@ package config
 
 import (
 	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// LLMProvider OpenAI ya da OpenAI uyumlu bir endpoint'tir; sıra failover sırasıdır.
+type LLMProvider struct {
+	Name    string
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
 type Config struct {
-	Env         string
-	Port        string
-	DatabaseURL string
-	ApiKey      string
+	Env            string
+	Port           string
+	DatabaseDriver string // mysql, postgres, sqlite
+	DatabaseURL    string
+	ApiKey         string
+
+	// AdminToken /debug endpoint'lerinin bearer token'ı; boşsa kapalıdır
+	AdminToken string
+
+	ReadinessTimeout time.Duration
+	LLMProbeCacheTTL time.Duration // provider erişilebilirlik kontrolünün cache süresi
+
+	// ShutdownTimeout SIGTERM'den sonra süren istek ve job'lar için beklenen süre
+	ShutdownTimeout time.Duration
+
+	// her LLM çağrısı ve DB sorgusu için ayrı deadline
+	LLMTimeout time.Duration
+	DBTimeout  time.Duration
+
+	// connection pool; SQLite'ta kullanılmaz
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// MigrateOnStart kapalıysa şema `myapp migrate up` ile güncellenir ve
+	// bekleyen migration varken uygulama başlamaz.
+	MigrateOnStart     bool
+	MigrateLockTimeout time.Duration // başka replica'nın migration kilidini bekleme süresi
+
+	LLMRetryMaxAttempts int
+	LLMRetryBaseDelay   time.Duration
+	LLMRetryMaxDelay    time.Duration
+	LLMRetryBudget      time.Duration // tüm denemeler için toplam süre
+
+	LLMProviders []LLMProvider
+	// ModelRoutesFile model routing kurallarının JSON dosyası; boşsa routing kapalı
+	ModelRoutesFile string
+
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+	BreakerHalfOpenMaxCalls int
+
+	RateLimitEnabled   bool
+	RateLimitPerMinute int
+	RateLimitBurst     int
+	RateLimitKey       string // ip, api_key, user, session
+	// RateLimitTrustedProxies api_key ve user anahtarlarının header'larına
+	// güvenilen gateway adresleridir (virgülle ayrılmış IP/CIDR)
+	RateLimitTrustedProxies string
+
+	IdempotencyTTL   time.Duration
+	IdempotencyStore string // memory, db
+
+	SessionLocker   string        // local, mysql, postgres
+	SessionLockWait time.Duration // mysql GET_LOCK bekleme süresi
+
+	JobWorkers      int
+	JobPollInterval time.Duration
+	JobTimeout      time.Duration
+	JobMaxAttempts  int
+
+	// WebhookSecret boşsa async job'larda webhook kapalıdır
+	WebhookSecret      string
+	WebhookMaxAttempts int
+	WebhookBaseDelay   time.Duration
+	WebhookTimeout     time.Duration
+	// WebhookAllowPrivate job webhook'larının localhost ve iç ağ adreslerine
+	// gönderilmesine izin verir; sadece geliştirme ortamı içindir.
+	WebhookAllowPrivate bool
+
+	// domain event'leri outbox'tan bu sink'lere gider; boş olan sink kapalıdır
+	EventsWebhookURL    string
+	EventsWebhookSecret string
+	NATSURL             string
+	NATSSubjectPrefix   string
+
+	OutboxPollInterval   time.Duration
+	OutboxBatchSize      int
+	OutboxPublishTimeout time.Duration
+	OutboxRetryBaseDelay time.Duration
+	OutboxRetryMaxDelay  time.Duration
+
+	// TracingEnabled ise span'ler OTLP/HTTP ile gönderilir; endpoint, header ve
+	// sampler standart OTEL_EXPORTER_OTLP_* / OTEL_TRACES_SAMPLER env'lerinden okunur
+	TracingEnabled bool
+	ServiceName    string
+
+	// RedactLogs log alanlarındaki PII'ı maskeler; RedactPrompts prompt'u
+	// provider'a göndermeden önce maskeler, cevapta geri açar
+	RedactLogs     bool
+	RedactPrompts  bool
+	RedactPatterns string // ek kurallar: "NAME=regex;NAME=regex"
+
+	// ModerationProviders boşsa moderasyon kapalıdır; "local", "openai" ya da "local,openai"
+	ModerationProviders      string
+	ModerationRulesFile      string // local moderator'ın kuralları
+	ModerationAction         string // reject, refuse
+	ModerationRefusalMessage string
+	ModerationFailOpen       bool
+	ModerationTimeout        time.Duration
+
+	// GuardrailAction boşsa prompt injection guardrail'i kapalıdır; warn, strip, block
+	GuardrailAction     string
+	GuardrailThreshold  float64
+	GuardrailJudgeModel string // boşsa sadece sezgiler kullanılır
+
+	// EncryptionMasterKey (base64, 32 byte) ya da EncryptionKeyFile verilirse
+	// mesajlar DB'de şifrelenir; ikisi de boşsa şifreleme kapalıdır.
+	EncryptionMasterKey         string
+	EncryptionMasterKeyID       string
+	EncryptionKeyFile           string // encryption.KeyFile formatında, master key rotasyonu için
+	EncryptionKeyCacheTTL       time.Duration
+	EncryptionKeyMaxAge         time.Duration // sıfırsa data key'ler otomatik rotate edilmez
+	EncryptionReencryptInterval time.Duration
+	EncryptionReencryptBatch    int
+
+	// RetentionPolicyFile boşsa retention kapalıdır.
+	RetentionPolicyFile    string
+	RetentionInterval      time.Duration
+	RetentionBatchSize     int
+	RetentionDryRun        bool
+	RetentionOutboxMaxDays int
+
+	// AuditHashChain açıksa audit kayıtları hash zinciriyle yazılır ve
+	// /debug/audit/verify ile değiştirilip değiştirilmedikleri kontrol edilir.
+	AuditHashChain bool
 }
 
 // godotenv uyumlu değil bu
@@ -21,18 +156,215 @@ func Load() *Config {
 	// 	log.Fatal("Error loading .env file")
 	// }
 	cfg := &Config{
-		Env:         getEnv("APP_ENV", "dev"),
-		Port:        getEnv("APP_PORT", "8080"),
-		DatabaseURL: getEnv("DATABASE_URL", ""),
-		ApiKey:      getEnv("OPENAI_API_KEY", ""),
+		Env:            getEnv("APP_ENV", "dev"),
+		Port:           getEnv("APP_PORT", "8080"),
+		DatabaseDriver: getEnv("DB_DRIVER", "mysql"),
+		DatabaseURL:    getEnv("DATABASE_URL", ""),
+		ApiKey:         getEnv("OPENAI_API_KEY", ""),
+
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+
+		ReadinessTimeout: getEnvDuration("READINESS_TIMEOUT", 2*time.Second),
+		LLMProbeCacheTTL: getEnvDuration("LLM_PROBE_CACHE_TTL", 30*time.Second),
+
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 25*time.Second),
+
+		LLMTimeout: getEnvDuration("LLM_TIMEOUT", 60*time.Second),
+		DBTimeout:  getEnvDuration("DB_TIMEOUT", 5*time.Second),
+
+		DBMaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		DBConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+
+		MigrateOnStart:     getEnvBool("MIGRATE_ON_START", true),
+		MigrateLockTimeout: getEnvDuration("MIGRATE_LOCK_TIMEOUT", time.Minute),
+
+		LLMRetryMaxAttempts: getEnvInt("LLM_RETRY_MAX_ATTEMPTS", 3),
+		LLMRetryBaseDelay:   getEnvDuration("LLM_RETRY_BASE_DELAY", 500*time.Millisecond),
+		LLMRetryMaxDelay:    getEnvDuration("LLM_RETRY_MAX_DELAY", 8*time.Second),
+		LLMRetryBudget:      getEnvDuration("LLM_RETRY_BUDGET", 90*time.Second),
+
+		ModelRoutesFile: getEnv("MODEL_ROUTES_FILE", ""),
+
+		BreakerFailureThreshold: getEnvInt("BREAKER_FAILURE_THRESHOLD", 5),
+		BreakerOpenTimeout:      getEnvDuration("BREAKER_OPEN_TIMEOUT", 30*time.Second),
+		BreakerHalfOpenMaxCalls: getEnvInt("BREAKER_HALF_OPEN_MAX_CALLS", 1),
+
+		RateLimitEnabled:        getEnvBool("RATE_LIMIT_ENABLED", true),
+		RateLimitPerMinute:      getEnvInt("RATE_LIMIT_PER_MINUTE", 30),
+		RateLimitBurst:          getEnvInt("RATE_LIMIT_BURST", 10),
+		RateLimitKey:            getEnv("RATE_LIMIT_KEY", "ip"),
+		RateLimitTrustedProxies: getEnv("RATE_LIMIT_TRUSTED_PROXIES", ""),
+
+		IdempotencyTTL:   getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyStore: getEnv("IDEMPOTENCY_STORE", "db"),
+
+		SessionLocker:   getEnv("SESSION_LOCKER", "local"),
+		SessionLockWait: getEnvDuration("SESSION_LOCK_WAIT", 2*time.Minute),
+
+		JobWorkers:      getEnvInt("JOB_WORKERS", 2),
+		JobPollInterval: getEnvDuration("JOB_POLL_INTERVAL", time.Second),
+		JobTimeout:      getEnvDuration("JOB_TIMEOUT", 5*time.Minute),
+		JobMaxAttempts:  getEnvInt("JOB_MAX_ATTEMPTS", 3),
+
+		WebhookSecret:       getEnv("WEBHOOK_SECRET", ""),
+		WebhookMaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookBaseDelay:    getEnvDuration("WEBHOOK_BASE_DELAY", 5*time.Second),
+		WebhookTimeout:      getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookAllowPrivate: getEnvBool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		EventsWebhookURL:    getEnv("EVENTS_WEBHOOK_URL", ""),
+		EventsWebhookSecret: getEnv("EVENTS_WEBHOOK_SECRET", ""),
+		NATSURL:             getEnv("NATS_URL", ""),
+		NATSSubjectPrefix:   getEnv("NATS_SUBJECT_PREFIX", "chat.events"),
+
+		OutboxPollInterval:   getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		OutboxBatchSize:      getEnvInt("OUTBOX_BATCH_SIZE", 100),
+		OutboxPublishTimeout: getEnvDuration("OUTBOX_PUBLISH_TIMEOUT", 10*time.Second),
+		OutboxRetryBaseDelay: getEnvDuration("OUTBOX_RETRY_BASE_DELAY", time.Second),
+		OutboxRetryMaxDelay:  getEnvDuration("OUTBOX_RETRY_MAX_DELAY", 5*time.Minute),
+
+		TracingEnabled: getEnvBool("TRACING_ENABLED", false),
+		ServiceName:    getEnv("OTEL_SERVICE_NAME", "llm-chat-service"),
+
+		RedactLogs:     getEnvBool("REDACT_LOGS", true),
+		RedactPrompts:  getEnvBool("REDACT_PROMPTS", false),
+		RedactPatterns: getEnv("REDACT_PATTERNS", ""),
+
+		ModerationProviders:      getEnv("MODERATION_PROVIDERS", ""),
+		ModerationRulesFile:      getEnv("MODERATION_RULES_FILE", ""),
+		ModerationAction:         getEnv("MODERATION_ACTION", "reject"),
+		ModerationRefusalMessage: getEnv("MODERATION_REFUSAL_MESSAGE", ""),
+		ModerationFailOpen:       getEnvBool("MODERATION_FAIL_OPEN", false),
+		ModerationTimeout:        getEnvDuration("MODERATION_TIMEOUT", 10*time.Second),
+
+		GuardrailAction:     getEnv("GUARDRAIL_ACTION", ""),
+		GuardrailThreshold:  getEnvFloat("GUARDRAIL_THRESHOLD", 0.5),
+		GuardrailJudgeModel: getEnv("GUARDRAIL_JUDGE_MODEL", ""),
+
+		EncryptionMasterKey:         getEnv("ENCRYPTION_MASTER_KEY", ""),
+		EncryptionMasterKeyID:       getEnv("ENCRYPTION_MASTER_KEY_ID", "default"),
+		EncryptionKeyFile:           getEnv("ENCRYPTION_KEY_FILE", ""),
+		EncryptionKeyCacheTTL:       getEnvDuration("ENCRYPTION_KEY_CACHE_TTL", time.Minute),
+		EncryptionKeyMaxAge:         getEnvDuration("ENCRYPTION_KEY_MAX_AGE", 0),
+		EncryptionReencryptInterval: getEnvDuration("ENCRYPTION_REENCRYPT_INTERVAL", time.Minute),
+		EncryptionReencryptBatch:    getEnvInt("ENCRYPTION_REENCRYPT_BATCH", 500),
+
+		RetentionPolicyFile:    getEnv("RETENTION_POLICY_FILE", ""),
+		RetentionInterval:      getEnvDuration("RETENTION_INTERVAL", time.Hour),
+		RetentionBatchSize:     getEnvInt("RETENTION_BATCH_SIZE", 500),
+		RetentionDryRun:        getEnvBool("RETENTION_DRY_RUN", false),
+		RetentionOutboxMaxDays: getEnvInt("RETENTION_OUTBOX_MAX_DAYS", 0),
+
+		AuditHashChain: getEnvBool("AUDIT_HASH_CHAIN", false),
 	}
 	if cfg.ApiKey == "" {
 		log.Println("Warning: OPENAI_API_KEY is not set")
 	}
+	cfg.LLMProviders = loadProviders(cfg.ApiKey)
 
 	return cfg
 }
 
+const redacted = "[REDACTED]"
+
+// Redacted /debug/info'da gösterilmek üzere secret'ları temizlenmiş bir kopya döner.
+// DSN ve URL'lerde sadece parola kısmı gizlenir.
+func (c *Config) Redacted() Config {
+	out := *c
+	out.DatabaseURL = redactDSN(c.DatabaseURL)
+	out.ApiKey = redact(c.ApiKey)
+	out.AdminToken = redact(c.AdminToken)
+	out.WebhookSecret = redact(c.WebhookSecret)
+	out.EventsWebhookSecret = redact(c.EventsWebhookSecret)
+	out.EncryptionMasterKey = redact(c.EncryptionMasterKey)
+	out.NATSURL = redactURL(c.NATSURL)
+	out.LLMProviders = make([]LLMProvider, len(c.LLMProviders))
+	for i, p := range c.LLMProviders {
+		p.APIKey = redact(p.APIKey)
+		out.LLMProviders[i] = p
+	}
+	return out
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactDSN "user:pass@tcp(host)/db" biçimindeki MySQL DSN'inde parolayı gizler.
+func redactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		return redactURL(dsn)
+	}
+	if strings.Contains(dsn, "password=") {
+		return redactKeywordDSN(dsn)
+	}
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return dsn
+	}
+	user, _, hasPass := strings.Cut(dsn[:at], ":")
+	if !hasPass {
+		return dsn
+	}
+	return user + ":" + redacted + dsn[at:]
+}
+
+// redactKeywordDSN postgres'in "host=db user=app password=secret" biçimidir.
+func redactKeywordDSN(dsn string) string {
+	fields := strings.Fields(dsn)
+	for i, f := range fields {
+		if strings.HasPrefix(f, "password=") {
+			fields[i] = "password=" + redacted
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		return strings.Replace(u.String(), "xxxxx", redacted, 1)
+	}
+	return raw
+}
+
+// loadProviders failover zincirini kurar: OpenAI -> ikincil OpenAI uyumlu
+// endpoint -> lokal model. Base URL'i verilmeyen yedekler zincire girmez.
+func loadProviders(apiKey string) []LLMProvider {
+	providers := []LLMProvider{{
+		Name:    "openai",
+		BaseURL: getEnv("OPENAI_BASE_URL", ""),
+		APIKey:  apiKey,
+		Model:   getEnv("OPENAI_MODEL", "gpt-4o"),
+	}}
+	if url := getEnv("LLM_SECONDARY_BASE_URL", ""); url != "" {
+		providers = append(providers, LLMProvider{
+			Name:    getEnv("LLM_SECONDARY_NAME", "secondary"),
+			BaseURL: url,
+			APIKey:  getEnv("LLM_SECONDARY_API_KEY", ""),
+			Model:   getEnv("LLM_SECONDARY_MODEL", "gpt-4o"),
+		})
+	}
+	if url := getEnv("LLM_LOCAL_BASE_URL", ""); url != "" {
+		providers = append(providers, LLMProvider{
+			Name:    getEnv("LLM_LOCAL_NAME", "local"),
+			BaseURL: url,
+			APIKey:  getEnv("LLM_LOCAL_API_KEY", "local"),
+			Model:   getEnv("LLM_LOCAL_MODEL", "llama3.1"),
+		})
+	}
+	return providers
+}
+
 func getEnv(key, fallback string) string {
 	value := os.Getenv(key)
 
@@ -41,3 +373,55 @@ func getEnv(key, fallback string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: %s is not a valid integer, using %d", key, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: %s is not a valid boolean, using %t", key, fallback)
+		return fallback
+	}
+	return b
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: %s is not a valid number, using %g", key, fallback)
+		return fallback
+	}
+	return f
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: %s is not a valid duration, using %s", key, fallback)
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type uniqueRow struct {
+	ID  int
+	Key string `gorm:"uniqueIndex;size:64"`
+}
+
+func TestConnect_UnsupportedDriver(t *testing.T) {
+	_, err := Connect(Config{Driver: "oracle", DSN: "x"})
+
+	assert.ErrorContains(t, err, `unsupported database driver "oracle"`)
+}
+
+func TestConnect_EmptyMySQLDSN(t *testing.T) {
+	_, err := Connect(Config{Driver: MySQL})
+
+	assert.Error(t, err)
+}
+
+func TestConnect_SQLiteTranslatesDuplicateKey(t *testing.T) {
+	//arrange
+	db, err := Connect(Config{Driver: SQLite, DSN: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&uniqueRow{}))
+
+	//act
+	require.NoError(t, db.Create(&uniqueRow{Key: "a"}).Error)
+	err = db.Create(&uniqueRow{Key: "a"}).Error
+
+	//assert
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+}
+
+type jsonRow struct {
+	ID      int
+	Payload JSON
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	db, err := Connect(Config{Driver: SQLite})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&jsonRow{}))
+
+	require.NoError(t, db.Create(&jsonRow{Payload: JSON(`{"a":1}`)}).Error)
+	require.NoError(t, db.Create(&jsonRow{}).Error)
+	var rows []jsonRow
+	require.NoError(t, db.Order("id").Find(&rows).Error)
+
+	require.Len(t, rows, 2)
+	assert.JSONEq(t, `{"a":1}`, string(rows[0].Payload))
+	assert.Nil(t, rows[1].Payload)
+}
+
+func TestJSON_ColumnType(t *testing.T) {
+	db, err := Connect(Config{Driver: SQLite})
+	require.NoError(t, err)
+
+	assert.Equal(t, "BLOB", JSON{}.GormDBDataType(db, nil))
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// JSON ham JSON tutan kolondur. Postgres'te JSONB olarak saklanır ve
+// sorgulanabilir; MySQL ve SQLite'ta kolon tipi değişmez (blob).
+type JSON []byte
+
+func (j JSON) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *JSON) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(JSON(nil), v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("database: cannot scan %T into JSON", src)
+	}
+	return nil
+}
+
+func (JSON) GormDataType() string {
+	return string(schema.Bytes)
+}
+
+func (JSON) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	switch db.Dialector.Name() {
+	case Postgres:
+		return "JSONB"
+	case MySQL:
+		return "LONGBLOB"
+	default:
+		return "BLOB"
+	}
+}
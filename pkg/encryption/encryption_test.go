@@ -0,0 +1,167 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKMS(t *testing.T, current string, ids ...string) KMS {
+	t.Helper()
+	keys := make(map[string][]byte)
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, KeySize)
+	}
+	kms, err := NewLocalKMS(current, keys)
+	require.NoError(t, err)
+	return kms
+}
+
+func TestSealOpen(t *testing.T) {
+	key, err := NewKey()
+	require.NoError(t, err)
+
+	sealed, err := Seal(key, []byte("merhaba"), []byte("t1/s1"))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "merhaba")
+
+	plaintext, err := Open(key, sealed, []byte("t1/s1"))
+	require.NoError(t, err)
+	assert.Equal(t, "merhaba", string(plaintext))
+
+	_, err = Open(key, sealed, []byte("t1/s2"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	other, _ := NewKey()
+	_, err = Open(other, sealed, []byte("t1/s1"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = Seal([]byte("short"), []byte("x"), nil)
+	assert.Error(t, err)
+}
+
+func TestLocalKMS(t *testing.T) {
+	kms := testKMS(t, "k2", "k1", "k2")
+	dataKey, _ := NewKey()
+
+	wrapped, id, err := kms.Wrap(context.Background(), dataKey)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", id)
+
+	unwrapped, err := kms.Unwrap(context.Background(), id, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	_, err = kms.Unwrap(context.Background(), "k1", wrapped)
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = kms.Unwrap(context.Background(), "k3", wrapped)
+	assert.Error(t, err)
+
+	_, err = NewLocalKMS("missing", map[string][]byte{"k1": make([]byte, KeySize)})
+	assert.Error(t, err)
+	_, err = NewLocalKMS("k1", map[string][]byte{"k1": make([]byte, 16)})
+	assert.Error(t, err)
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KeySize))
+	require.NoError(t, os.WriteFile(path, []byte(`{"current":"2025-10","keys":{"2025-10":"`+key+`"}}`), 0o600))
+
+	kms, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-10", kms.CurrentKeyID())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"current":"2025-10","keys":{"2025-10":"!!"}}`), 0o600))
+	_, err = LoadKeyFile(path)
+	assert.Error(t, err)
+}
+
+func TestKeyringCurrentCreatesKey(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	store := NewMemoryStore()
+	keys := NewKeyring(store, testKMS(t, "m1", "m1"), time.Minute)
+
+	//act
+	version, key, err := keys.Current(ctx, "acme")
+	again, sameKey, _ := keys.Current(ctx, "acme")
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, 1, again)
+	assert.Equal(t, key, sameKey)
+	stored, _ := store.List(ctx)
+	require.Len(t, stored, 1)
+	assert.NotEqual(t, key, stored[0].WrappedKey)
+}
+
+func TestKeyringRotate(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	keys := NewKeyring(NewMemoryStore(), testKMS(t, "m1", "m1"), time.Minute)
+	_, v1, err := keys.Current(ctx, "acme")
+	require.NoError(t, err)
+
+	//act
+	version, err := keys.Rotate(ctx, "acme")
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+	current, v2, _ := keys.Current(ctx, "acme")
+	assert.Equal(t, 2, current)
+	assert.NotEqual(t, v1, v2)
+	old, err := keys.Key(ctx, "acme", 1)
+	require.NoError(t, err)
+	assert.Equal(t, v1, old)
+	versions, _ := keys.Versions(ctx)
+	assert.Equal(t, map[string]int{"acme": 2}, versions)
+}
+
+func TestKeyringRotateOlderThan(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	kr := NewKeyring(NewMemoryStore(), testKMS(t, "m1", "m1"), time.Minute).(*keyring)
+	kr.Current(ctx, "acme")
+	kr.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+
+	//act
+	rotated, err := kr.RotateOlderThan(ctx, 24*time.Hour)
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme"}, rotated)
+	rotated, _ = kr.RotateOlderThan(ctx, 72*time.Hour)
+	assert.Empty(t, rotated)
+}
+
+func TestKeyringRewrapAfterMasterKeyRotation(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_, key, err := NewKeyring(store, testKMS(t, "m1", "m1"), time.Minute).Current(ctx, "acme")
+	require.NoError(t, err)
+	keys := NewKeyring(store, testKMS(t, "m2", "m1", "m2"), time.Minute)
+
+	//act
+	n, err := keys.Rewrap(ctx)
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	stored, _ := store.List(ctx)
+	assert.Equal(t, "m2", stored[0].MasterKeyID)
+	// eski master key artık gerekmez
+	_, same, err := NewKeyring(store, testKMS(t, "m2", "m0", "m2"), time.Minute).Current(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, key, same)
+}
@@ -0,0 +1,224 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Keyring tenant başına data key'leri yönetir: yoksa üretir, açılmış
+// halini cache'ler, rotasyon ve master key değişiminde yeniden sarar.
+type Keyring interface {
+	// Current yeni yazılacak mesajlar için tenant'ın güncel key'ini döner;
+	// tenant'ın key'i yoksa oluşturulur.
+	Current(ctx context.Context, tenantID string) (version int, key []byte, err error)
+	// Key eski versiyonlar dahil belirli bir key'i döner.
+	Key(ctx context.Context, tenantID string, version int) ([]byte, error)
+	// Rotate tenant için yeni bir key versiyonu oluşturur.
+	Rotate(ctx context.Context, tenantID string) (int, error)
+	// RotateOlderThan güncel key'i maxAge'den eski tenant'ları rotate eder.
+	RotateOlderThan(ctx context.Context, maxAge time.Duration) ([]string, error)
+	// Rewrap güncel olmayan master key ile sarılmış key'leri yeniden sarar;
+	// mesajların yeniden şifrelenmesi gerekmez.
+	Rewrap(ctx context.Context) (int, error)
+	// Versions tenant başına güncel key versiyonunu döner.
+	Versions(ctx context.Context) (map[string]int, error)
+}
+
+// Table tenant key'iyle şifrelenmiş kolonları olan bir tablodur; re-encryption
+// job'ı satırlarını tenant'ın güncel key'ine taşır.
+type Table interface {
+	// PlainTenants şifresiz (key_version = 0) satırı olan tenant'ları döner.
+	PlainTenants(ctx context.Context) ([]string, error)
+	// Reencrypt tenant'ın version dışındaki satırlarını batchSize'lık gruplar
+	// halinde key'e taşır ve güncellenen satır sayısını döner.
+	Reencrypt(ctx context.Context, tenantID string, version int, key []byte, batchSize int) (int, error)
+}
+
+type current struct {
+	version int
+	expires time.Time
+}
+
+type keyring struct {
+	store Store
+	kms   KMS
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string][]byte // "tenant/version" -> açılmış key
+	current map[string]current
+}
+
+// NewKeyring güncel versiyonları ttl boyunca cache'ler; başka bir replica
+// rotate ettiğinde en geç ttl sonra yeni key kullanılır. Eski key'le yazılan
+// mesajlar yine okunabilir ve re-encryption job'ı onları da günceller.
+func NewKeyring(store Store, kms KMS, ttl time.Duration) Keyring {
+	return &keyring{
+		store:   store,
+		kms:     kms,
+		ttl:     ttl,
+		now:     time.Now,
+		keys:    make(map[string][]byte),
+		current: make(map[string]current),
+	}
+}
+
+func (k *keyring) Current(ctx context.Context, tenantID string) (int, []byte, error) {
+	k.mu.Lock()
+	cur, ok := k.current[tenantID]
+	k.mu.Unlock()
+	if ok && k.now().Before(cur.expires) {
+		key, err := k.Key(ctx, tenantID, cur.version)
+		return cur.version, key, err
+	}
+
+	dk, err := k.store.Latest(ctx, tenantID)
+	if errors.Is(err, ErrNoKey) {
+		dk, err = k.create(ctx, tenantID, 1)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	key, err := k.unwrap(ctx, dk)
+	if err != nil {
+		return 0, nil, err
+	}
+	k.mu.Lock()
+	k.current[tenantID] = current{version: dk.Version, expires: k.now().Add(k.ttl)}
+	k.mu.Unlock()
+	return dk.Version, key, nil
+}
+
+func (k *keyring) Key(ctx context.Context, tenantID string, version int) ([]byte, error) {
+	k.mu.Lock()
+	key, ok := k.keys[cacheKey(tenantID, version)]
+	k.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	dk, err := k.store.Get(ctx, tenantID, version)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %q key v%d: %w", tenantID, version, err)
+	}
+	return k.unwrap(ctx, dk)
+}
+
+func (k *keyring) Rotate(ctx context.Context, tenantID string) (int, error) {
+	version := 1
+	latest, err := k.store.Latest(ctx, tenantID)
+	switch {
+	case err == nil:
+		version = latest.Version + 1
+	case !errors.Is(err, ErrNoKey):
+		return 0, err
+	}
+	dk, err := k.create(ctx, tenantID, version)
+	if err != nil {
+		return 0, err
+	}
+	k.mu.Lock()
+	k.current[tenantID] = current{version: dk.Version, expires: k.now().Add(k.ttl)}
+	k.mu.Unlock()
+	return dk.Version, nil
+}
+
+func (k *keyring) RotateOlderThan(ctx context.Context, maxAge time.Duration) ([]string, error) {
+	keys, err := k.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]DataKey)
+	for _, dk := range keys {
+		if dk.Version > latest[dk.TenantID].Version {
+			latest[dk.TenantID] = dk
+		}
+	}
+	var rotated []string
+	for tenantID, dk := range latest {
+		if k.now().Sub(dk.CreatedAt) < maxAge {
+			continue
+		}
+		if _, err := k.Rotate(ctx, tenantID); err != nil {
+			return rotated, err
+		}
+		rotated = append(rotated, tenantID)
+	}
+	return rotated, nil
+}
+
+func (k *keyring) Rewrap(ctx context.Context) (int, error) {
+	keys, err := k.store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, dk := range keys {
+		if dk.MasterKeyID == k.kms.CurrentKeyID() {
+			continue
+		}
+		key, err := k.unwrap(ctx, dk)
+		if err != nil {
+			return n, err
+		}
+		wrapped, masterKeyID, err := k.kms.Wrap(ctx, key)
+		if err != nil {
+			return n, err
+		}
+		if err := k.store.Rewrap(ctx, dk.ID, dk.MasterKeyID, wrapped, masterKeyID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (k *keyring) Versions(ctx context.Context) (map[string]int, error) {
+	keys, err := k.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]int)
+	for _, dk := range keys {
+		if dk.Version > versions[dk.TenantID] {
+			versions[dk.TenantID] = dk.Version
+		}
+	}
+	return versions, nil
+}
+
+// create yeni key üretir; başka bir replica aynı versiyonu önce yazdıysa onunkini kullanır.
+func (k *keyring) create(ctx context.Context, tenantID string, version int) (DataKey, error) {
+	key, err := NewKey()
+	if err != nil {
+		return DataKey{}, err
+	}
+	wrapped, masterKeyID, err := k.kms.Wrap(ctx, key)
+	if err != nil {
+		return DataKey{}, err
+	}
+	dk := DataKey{TenantID: tenantID, Version: version, WrappedKey: wrapped, MasterKeyID: masterKeyID}
+	err = k.store.Create(ctx, &dk)
+	if errors.Is(err, ErrKeyExists) {
+		return k.store.Get(ctx, tenantID, version)
+	}
+	return dk, err
+}
+
+func (k *keyring) unwrap(ctx context.Context, dk DataKey) ([]byte, error) {
+	key, err := k.kms.Unwrap(ctx, dk.MasterKeyID, dk.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap tenant %q key v%d: %w", dk.TenantID, dk.Version, err)
+	}
+	k.mu.Lock()
+	k.keys[cacheKey(dk.TenantID, dk.Version)] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+func cacheKey(tenantID string, version int) string {
+	return fmt.Sprintf("%s/%d", tenantID, version)
+}
@@ -0,0 +1,86 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// KMS data key'leri master key ile sarar. Master key servisten hiç çıkmaz;
+// AWS KMS, Vault transit gibi servisler bu interface ile eklenebilir.
+type KMS interface {
+	// Wrap data key'i güncel master key ile sarar ve master key'in id'sini döner.
+	Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, masterKeyID string, err error)
+	Unwrap(ctx context.Context, masterKeyID string, wrapped []byte) ([]byte, error)
+	// CurrentKeyID yeni Wrap'lerde kullanılan master key'dir; farklı id ile
+	// sarılmış data key'ler Keyring.Rewrap ile yeniden sarılır.
+	CurrentKeyID() string
+}
+
+type localKMS struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewLocalKMS master key'leri process'te tutan KMS'tir. keys eski master
+// key'leri de içermelidir ki onlarla sarılmış data key'ler açılabilsin.
+func NewLocalKMS(current string, keys map[string][]byte) (KMS, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("encryption: current master key %q not found", current)
+	}
+	for id, key := range keys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("encryption: master key %q must be %d bytes", id, KeySize)
+		}
+	}
+	return &localKMS{current: current, keys: keys}, nil
+}
+
+// KeyFile LoadKeyFile'ın okuduğu master key dosyasıdır:
+//
+//	{"current": "2025-10", "keys": {"2025-01": "<base64>", "2025-10": "<base64>"}}
+type KeyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// LoadKeyFile master key'leri JSON dosyasından okur. Master key rotasyonu için
+// dosyaya yeni key eklenip current değiştirilir; eski key'ler silinmez.
+func LoadKeyFile(path string) (KMS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file KeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return NewLocalKMS(file.Current, keys)
+}
+
+func (k *localKMS) CurrentKeyID() string {
+	return k.current
+}
+
+func (k *localKMS) Wrap(_ context.Context, dataKey []byte) ([]byte, string, error) {
+	sealed, err := Seal(k.keys[k.current], dataKey, []byte(k.current))
+	return []byte(sealed), k.current, err
+}
+
+func (k *localKMS) Unwrap(_ context.Context, masterKeyID string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("encryption: unknown master key %q", masterKeyID)
+	}
+	return Open(key, string(wrapped), []byte(masterKeyID))
+}
@@ -0,0 +1,171 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DataKey tenant'ın mesajlarını şifreleyen anahtarın master key ile sarılmış
+// halidir. Her rotasyon yeni bir Version ekler; eski versiyonlar onlarla
+// şifrelenmiş satır kalmadığı sürece okumak için gerekir.
+type DataKey struct {
+	ID          uint
+	TenantID    string `gorm:"size:64;uniqueIndex:idx_tenant_version,priority:1"`
+	Version     int    `gorm:"uniqueIndex:idx_tenant_version,priority:2"`
+	WrappedKey  []byte `gorm:"size:512"`
+	MasterKeyID string `gorm:"size:64"`
+	CreatedAt   time.Time
+}
+
+func (DataKey) TableName() string {
+	return "tenant_data_keys"
+}
+
+var (
+	ErrNoKey     = errors.New("encryption: no data key")
+	ErrKeyExists = errors.New("encryption: data key version already exists")
+)
+
+// Store sarılmış data key'leri saklar.
+type Store interface {
+	// Latest tenant'ın en yüksek versiyonlu key'ini döner; yoksa ErrNoKey.
+	Latest(ctx context.Context, tenantID string) (DataKey, error)
+	Get(ctx context.Context, tenantID string, version int) (DataKey, error)
+	// Create aynı tenant ve versiyon varsa ErrKeyExists döner.
+	Create(ctx context.Context, key *DataKey) error
+	// List tüm key'leri döner; tablo tenant başına birkaç satırdır.
+	List(ctx context.Context) ([]DataKey, error)
+	// Rewrap key'i sadece hâlâ oldMasterKeyID ile sarılıysa günceller.
+	Rewrap(ctx context.Context, id uint, oldMasterKeyID string, wrapped []byte, masterKeyID string) error
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore database.Connect'in TranslateError ayarıyla çalışır;
+// duplicate key hatası ErrKeyExists'e çevrilir.
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Latest(ctx context.Context, tenantID string) (DataKey, error) {
+	var key DataKey
+	err := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("version desc").Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, ErrNoKey
+	}
+	return key, err
+}
+
+func (s *gormStore) Get(ctx context.Context, tenantID string, version int) (DataKey, error) {
+	var key DataKey
+	err := s.db.WithContext(ctx).Where("tenant_id = ? AND version = ?", tenantID, version).Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, ErrNoKey
+	}
+	return key, err
+}
+
+func (s *gormStore) Create(ctx context.Context, key *DataKey) error {
+	err := s.db.WithContext(ctx).Create(key).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrKeyExists
+	}
+	return err
+}
+
+func (s *gormStore) List(ctx context.Context) ([]DataKey, error) {
+	var keys []DataKey
+	err := s.db.WithContext(ctx).Order("tenant_id, version").Find(&keys).Error
+	return keys, err
+}
+
+func (s *gormStore) Rewrap(ctx context.Context, id uint, oldMasterKeyID string, wrapped []byte, masterKeyID string) error {
+	return s.db.WithContext(ctx).Model(&DataKey{}).
+		Where("id = ? AND master_key_id = ?", id, oldMasterKeyID).
+		Updates(map[string]any{"wrapped_key": wrapped, "master_key_id": masterKeyID}).Error
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	keys   []DataKey
+	nextID uint
+}
+
+// NewMemoryStore testler ve tek process'li denemeler içindir; restart'ta
+// key'ler kaybolur ve şifreli mesajlar okunamaz hale gelir.
+func NewMemoryStore() Store {
+	return &memoryStore{}
+}
+
+func (s *memoryStore) Latest(_ context.Context, tenantID string) (DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var latest *DataKey
+	for i := range s.keys {
+		if k := &s.keys[i]; k.TenantID == tenantID && (latest == nil || k.Version > latest.Version) {
+			latest = k
+		}
+	}
+	if latest == nil {
+		return DataKey{}, ErrNoKey
+	}
+	return *latest, nil
+}
+
+func (s *memoryStore) Get(_ context.Context, tenantID string, version int) (DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.TenantID == tenantID && k.Version == version {
+			return k, nil
+		}
+	}
+	return DataKey{}, ErrNoKey
+}
+
+func (s *memoryStore) Create(_ context.Context, key *DataKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.keys {
+		if k.TenantID == key.TenantID && k.Version == key.Version {
+			return ErrKeyExists
+		}
+	}
+	s.nextID++
+	key.ID = s.nextID
+	key.CreatedAt = time.Now()
+	s.keys = append(s.keys, *key)
+	return nil
+}
+
+func (s *memoryStore) List(_ context.Context) ([]DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := append([]DataKey(nil), s.keys...)
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].TenantID != keys[j].TenantID {
+			return keys[i].TenantID < keys[j].TenantID
+		}
+		return keys[i].Version < keys[j].Version
+	})
+	return keys, nil
+}
+
+func (s *memoryStore) Rewrap(_ context.Context, id uint, oldMasterKeyID string, wrapped []byte, masterKeyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.keys {
+		if s.keys[i].ID == id && s.keys[i].MasterKeyID == oldMasterKeyID {
+			s.keys[i].WrappedKey = wrapped
+			s.keys[i].MasterKeyID = masterKeyID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"myapp/pkg/encryption"
+
+	"gorm.io/gorm"
+)
+
+// recordAAD ciphertext'i tenant'a ve anahtara bağlar; başka bir kaydın
+// satırına kopyalanan ciphertext açılmaz.
+func recordAAD(tenantID, key string) []byte {
+	return []byte(tenantID + "/" + key)
+}
+
+// openRecord KeyVersion sıfırsa (şifrelenmemiş kayıt) Body'yi olduğu gibi bırakır.
+func openRecord(ctx context.Context, keys encryption.Keyring, rec *Record) error {
+	if rec.KeyVersion == 0 {
+		return nil
+	}
+	key, err := keys.Key(ctx, rec.TenantID, rec.KeyVersion)
+	if err != nil {
+		return err
+	}
+	body, err := encryption.Open(key, string(rec.Body), recordAAD(rec.TenantID, rec.Key))
+	if err != nil {
+		return fmt.Errorf("idempotency record: %w", err)
+	}
+	rec.Body = body
+	rec.KeyVersion = 0
+	return nil
+}
+
+type sealedTable struct {
+	db   *gorm.DB
+	keys encryption.Keyring
+}
+
+// NewSealedTable re-encryption job'ının idempotency_records'daki cevapları da
+// tenant'ın güncel key'ine taşıması içindir.
+func NewSealedTable(db *gorm.DB, keys encryption.Keyring) encryption.Table {
+	return &sealedTable{db: db, keys: keys}
+}
+
+func (t *sealedTable) PlainTenants(ctx context.Context) ([]string, error) {
+	var tenants []string
+	err := t.db.WithContext(ctx).Model(&Record{}).
+		Where("status = ? AND key_version = 0", StatusCompleted).Distinct().Pluck("tenant_id", &tenants).Error
+	return tenants, err
+}
+
+func (t *sealedTable) Reencrypt(ctx context.Context, tenantID string, version int, key []byte, batchSize int) (int, error) {
+	total, lastKey := 0, ""
+	for {
+		var rows []Record
+		err := t.db.WithContext(ctx).
+			Where("status = ? AND tenant_id = ? AND key_version <> ? AND idempotency_key > ?", StatusCompleted, tenantID, version, lastKey).
+			Order("idempotency_key").Limit(batchSize).Find(&rows).Error
+		if err != nil {
+			return total, err
+		}
+		for i := range rows {
+			lastKey = rows[i].Key
+			old := rows[i]
+			if err := openRecord(ctx, t.keys, &rows[i]); err != nil {
+				return total, err
+			}
+			sealed, err := encryption.Seal(key, rows[i].Body, recordAAD(tenantID, rows[i].Key))
+			if err != nil {
+				return total, err
+			}
+			// kayıt bu arada süresi dolup yeniden yazıldıysa ciphertext değişmiştir; dokunulmaz
+			res := t.db.WithContext(ctx).Model(&Record{}).
+				Where("idempotency_key = ? AND key_version = ? AND body = ?", old.Key, old.KeyVersion, old.Body).
+				UpdateColumns(map[string]any{"body": []byte(sealed), "key_version": version})
+			if res.Error != nil {
+				return total, res.Error
+			}
+			total += int(res.RowsAffected)
+		}
+		if len(rows) < batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"myapp/pkg/encryption"
+	"myapp/pkg/identity"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormStore struct {
+	db   *gorm.DB
+	keys encryption.Keyring
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// GormOption NewGormStore'un opsiyonel ayarlarıdır.
+type GormOption func(*gormStore)
+
+// WithEncryption saklanan cevapları isteği yapanın tenant'ının data key'iyle
+// şifreler; cevaplar kullanıcının prompt'unu ve LLM cevabını içerir.
+func WithEncryption(keys encryption.Keyring) GormOption {
+	return func(s *gormStore) {
+		s.keys = keys
+	}
+}
+
+// NewGormStore kayıtları idempotency_records tablosunda tutar; birden fazla
+// replika aynı anahtarı paylaşabilir. Primary key çakışması kilit görevi görür,
+// bu yüzden db gorm.Config{TranslateError: true} ile açılmış olmalıdır.
+func NewGormStore(db *gorm.DB, opts ...GormOption) Store {
+	s := &gormStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *gormStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+	s.sweep(db, now)
+
+	id := identity.FromContext(ctx)
+	rec := Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      StatusInProgress,
+		TenantID:    id.TenantID,
+		Persona:     id.Persona,
+		UserID:      id.UserID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	// süresi dolmuş eski kayıt yeni isteği engellemesin
+	if err := db.Where("idempotency_key = ? AND expires_at <= ?", key, now).Delete(&Record{}).Error; err != nil {
+		return Record{}, false, err
+	}
+	err := db.Create(&rec).Error
+	if err == nil {
+		return rec, true, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return Record{}, false, err
+	}
+
+	var existing Record
+	if err := db.Where("idempotency_key = ?", key).Take(&existing).Error; err != nil {
+		return Record{}, false, err
+	}
+	if s.keys != nil {
+		if err := openRecord(ctx, s.keys, &existing); err != nil {
+			return Record{}, false, err
+		}
+	}
+	return existing, false, nil
+}
+
+func (s *gormStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	updates := map[string]any{
+		"status":       StatusCompleted,
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"body":         body,
+		"session_id":   sessionFromContext(ctx),
+	}
+	if s.keys != nil {
+		tenantID := identity.FromContext(ctx).TenantID
+		version, dataKey, err := s.keys.Current(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+		sealed, err := encryption.Seal(dataKey, body, recordAAD(tenantID, key))
+		if err != nil {
+			return err
+		}
+		updates["body"] = []byte(sealed)
+		updates["tenant_id"] = tenantID // açarken aynı AAD kullanılsın
+		updates["key_version"] = version
+	}
+	result := s.db.WithContext(ctx).Model(&Record{}).Where("idempotency_key = ?", key).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *gormStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("idempotency_key = ? AND status = ?", key, StatusInProgress).Delete(&Record{}).Error
+}
+
+func (s *gormStore) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	res := s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&Record{})
+	return res.RowsAffected, res.Error
+}
+
+// sweep süresi dolmuş kayıtları arada bir toplu siler ki tablo büyümesin.
+func (s *gormStore) sweep(db *gorm.DB, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	db.Where("expires_at <= ?", now).Delete(&Record{})
+}
@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"myapp/pkg/database"
+	"myapp/pkg/encryption"
+	"myapp/pkg/identity"
+	"myapp/pkg/middleware"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Connect(database.Config{Driver: database.SQLite, DSN: ":memory:"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.AutoMigrate(&Record{}))
+	return db
+}
+
+func testKeyring(t *testing.T) encryption.Keyring {
+	t.Helper()
+	kms, err := encryption.NewLocalKMS("m1", map[string][]byte{"m1": bytes.Repeat([]byte{1}, encryption.KeySize)})
+	require.NoError(t, err)
+	return encryption.NewKeyring(encryption.NewMemoryStore(), kms, time.Minute)
+}
+
+func TestGormStore_EncryptsStoredBody(t *testing.T) {
+	//arrange
+	ctx := identity.WithContext(context.Background(), identity.Identity{UserID: "u1", TenantID: "acme"})
+	db := newTestDB(t)
+	keys := testKeyring(t)
+	store := NewGormStore(db, WithEncryption(keys))
+	_, started, err := store.Begin(ctx, "k1", "f1", time.Hour)
+	require.NoError(t, err)
+	require.True(t, started)
+
+	//act
+	require.NoError(t, store.Complete(ctx, "k1", 200, "application/json", []byte(`{"Message":"gizli"}`)))
+	rec, started, err := store.Begin(ctx, "k1", "f1", time.Hour)
+
+	//assert
+	require.NoError(t, err)
+	assert.False(t, started)
+	assert.Equal(t, `{"Message":"gizli"}`, string(rec.Body))
+
+	var row Record
+	require.NoError(t, db.First(&row, "idempotency_key = ?", "k1").Error)
+	assert.NotContains(t, string(row.Body), "gizli")
+	assert.Equal(t, "acme", row.TenantID)
+	assert.Equal(t, 1, row.KeyVersion)
+
+	version, err := keys.Rotate(ctx, "acme")
+	require.NoError(t, err)
+	_, key, err := keys.Current(ctx, "acme")
+	require.NoError(t, err)
+	n, err := NewSealedTable(db, keys).Reencrypt(ctx, "acme", version, key, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	rec, _, err = store.Begin(ctx, "k1", "f1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Message":"gizli"}`, string(rec.Body))
+}
+
+func TestMiddleware_StoresHandlerSession(t *testing.T) {
+	//arrange
+	db := newTestDB(t)
+	e := newTestEcho(NewGormStore(db), func(c echo.Context) error {
+		c.Set(middleware.SessionIDKey, "s1")
+		return c.String(http.StatusOK, "ok")
+	})
+
+	//act
+	rec := post(e, "k1", chatBody)
+
+	//assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var row Record
+	require.NoError(t, db.Take(&row).Error)
+	assert.Equal(t, "s1", row.SessionID)
+}
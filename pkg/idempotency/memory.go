@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"myapp/pkg/identity"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore tek replika için in-memory Store döner.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		records: make(map[string]Record),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	if rec, ok := s.records[key]; ok && now.Before(rec.ExpiresAt) {
+		return rec, false, nil
+	}
+	rec := Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      StatusInProgress,
+		UserID:      identity.FromContext(ctx).UserID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	s.records[key] = rec
+	return rec, true, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok {
+		return ErrNotFound
+	}
+	rec.Status = StatusCompleted
+	rec.StatusCode = statusCode
+	rec.ContentType = contentType
+	rec.Body = body
+	s.records[key] = rec
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *memoryStore) DeleteByUser(_ context.Context, userID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for key, rec := range s.records {
+		if rec.UserID == userID {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, rec := range s.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
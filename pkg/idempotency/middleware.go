@@ -0,0 +1,160 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"myapp/pkg/identity"
+	"myapp/pkg/logger"
+	"myapp/pkg/middleware"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+
+	// statusClientClosedRequest client bağlantıyı kapattığında dönen nginx kodudur
+	statusClientClosedRequest = 499
+)
+
+type Config struct {
+	Store Store
+	TTL   time.Duration
+}
+
+// Middleware Idempotency-Key header'ı olan istekleri bir kez çalıştırır. Aynı
+// anahtar ve aynı body ile gelen tekrarlar saklanan cevabı alır, handler
+// çağrılmaz. Aynı anahtar farklı body ile gelirse 422, ilk istek hâlâ
+// sürüyorsa 409 döner. Kesin olmayan cevaplar (5xx, 499, 409, 408, 429) ve
+// panic'ler saklanmaz, anahtar bırakılır ki client tekrar deneyebilsin.
+func Middleware(cfg Config) echo.MiddlewareFunc {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			clientKey := c.Request().Header.Get(HeaderKey)
+			if clientKey == "" {
+				return next(c)
+			}
+			if len(clientKey) > maxKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "idempotency key is too long")
+			}
+
+			req := c.Request()
+			ctx := req.Context()
+			log := logger.FromContext(ctx)
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "bad request")
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := scopedKey(c, clientKey)
+			rec, started, err := cfg.Store.Begin(ctx, key, fingerprint(req, body), cfg.TTL)
+			if err != nil {
+				log.Warn("idempotency store failed, processing without it", zap.Error(err))
+				return next(c)
+			}
+			if !started {
+				return replay(c, rec, fingerprint(req, body))
+			}
+
+			// client bağlantıyı kapatsa da anahtar in_progress'te kalmasın
+			storeCtx := context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := cfg.Store.Release(storeCtx, key); err != nil {
+					log.Error("failed to release idempotency key", zap.Error(err))
+				}
+			}()
+
+			capture := &bodyCapture{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = capture
+			if err := next(c); err != nil {
+				// cevabı saklayabilmek için hatayı burada yazdırıyoruz
+				c.Error(err)
+			}
+
+			res := c.Response()
+			if !isFinal(res.Status) {
+				return nil
+			}
+			// handler yeni session açtıysa id'si ancak şimdi belli
+			if id, ok := c.Get(middleware.SessionIDKey).(string); ok {
+				storeCtx = withSession(storeCtx, id)
+			}
+			if err := cfg.Store.Complete(storeCtx, key, res.Status, res.Header().Get(echo.HeaderContentType), capture.buf.Bytes()); err != nil {
+				log.Error("failed to store idempotent response", zap.Error(err))
+				return nil
+			}
+			completed = true
+			return nil
+		}
+	}
+}
+
+// isFinal aynı istek tekrarlandığında da aynı cevabın döneceği status'lar
+// için true döner; diğerleri geçici olduğundan saklanmaz.
+func isFinal(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests, statusClientClosedRequest:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+func replay(c echo.Context, rec Record, fp string) error {
+	if rec.Fingerprint != fp {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "idempotency key was already used with a different request")
+	}
+	if rec.Status != StatusCompleted {
+		c.Response().Header().Set("Retry-After", "1")
+		return echo.NewHTTPError(http.StatusConflict, "a request with this idempotency key is in progress")
+	}
+	logger.FromContext(c.Request().Context()).Info("replaying idempotent response")
+	c.Response().Header().Set(HeaderReplayed, "true")
+	return c.Blob(rec.StatusCode, rec.ContentType, rec.Body)
+}
+
+// scopedKey anahtarı route ve kullanıcıyla birleştirir; farklı kullanıcılar
+// aynı anahtarı kullanırsa birbirlerinin cevabını göremez.
+func scopedKey(c echo.Context, key string) string {
+	user := identity.FromContext(c.Request().Context()).UserID
+	sum := sha256.Sum256([]byte(c.Request().Method + " " + c.Path() + "\x00" + user + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "\x00"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type bodyCapture struct {
+	http.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bodyCapture) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
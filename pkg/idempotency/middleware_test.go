@@ -0,0 +1,220 @@
+package idempotency
+
+import (
+	"context"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const chatBody = `{"Message":"merhaba","SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`
+
+func newTestEcho(store Store, h echo.HandlerFunc) *echo.Echo {
+	logger.Log = zap.NewNop()
+	e := echo.New()
+	e.POST("/v1/chat", h, Middleware(Config{Store: store, TTL: time.Hour}))
+	return e
+}
+
+func post(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_ReplaysStoredResponse(t *testing.T) {
+	//arrange
+	var calls int32
+	e := newTestEcho(NewMemoryStore(), func(c echo.Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		return c.JSON(http.StatusOK, echo.Map{"Message": "cevap", "n": n})
+	})
+
+	//act
+	first := post(e, "k1", chatBody)
+	second := post(e, "k1", chatBody)
+
+	//assert
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(HeaderReplayed))
+	assert.Empty(t, first.Header().Get(HeaderReplayed))
+}
+
+func TestMiddleware_ConflictingBody(t *testing.T) {
+	e := newTestEcho(NewMemoryStore(), func(c echo.Context) error {
+		return c.JSON(http.StatusOK, echo.Map{"Message": "cevap"})
+	})
+
+	post(e, "k1", chatBody)
+	rec := post(e, "k1", `{"Message":"başka bir şey"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestMiddleware_InProgress(t *testing.T) {
+	//arrange
+	store := NewMemoryStore()
+	e := newTestEcho(store, func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	// ilk istek hâlâ sürüyormuş gibi anahtarı elle aç
+	probe := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/v1/chat", nil), httptest.NewRecorder())
+	probe.SetPath("/v1/chat")
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat", strings.NewReader(chatBody))
+	store.Begin(context.Background(), scopedKey(probe, "k1"), fingerprint(req, []byte(chatBody)), time.Hour)
+
+	//act
+	rec := post(e, "k1", chatBody)
+
+	//assert
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+}
+
+func TestMiddleware_ServerErrorIsNotStored(t *testing.T) {
+	var calls int32
+	e := newTestEcho(NewMemoryStore(), func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to perform chat completion")
+		}
+		return c.JSON(http.StatusOK, echo.Map{"Message": "cevap"})
+	})
+
+	first := post(e, "k1", chatBody)
+	second := post(e, "k1", chatBody)
+
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestMiddleware_TransientClientErrorsAreNotStored(t *testing.T) {
+	for _, status := range []int{http.StatusConflict, statusClientClosedRequest, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			var calls int32
+			e := newTestEcho(NewMemoryStore(), func(c echo.Context) error {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					return echo.NewHTTPError(status, "try again")
+				}
+				return c.JSON(http.StatusOK, echo.Map{"Message": "cevap"})
+			})
+
+			post(e, "k1", chatBody)
+			second := post(e, "k1", chatBody)
+
+			assert.Equal(t, http.StatusOK, second.Code)
+			assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+// ctxStore context'i bitmiş çağrıları gorm store gibi reddeder.
+type ctxStore struct{ Store }
+
+func (s ctxStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Complete(ctx, key, statusCode, contentType, body)
+}
+
+func TestMiddleware_ClientDisconnectStillCompletes(t *testing.T) {
+	//arrange
+	var calls int32
+	e := newTestEcho(ctxStore{NewMemoryStore()}, func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.JSON(http.StatusOK, echo.Map{"Message": "cevap"})
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat", strings.NewReader(chatBody)).WithContext(ctx)
+	req.Header.Set(HeaderKey, "k1")
+	cancel()
+
+	//act
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	second := post(e, "k1", chatBody)
+
+	//assert
+	assert.Equal(t, "true", second.Header().Get(HeaderReplayed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMiddleware_PanicReleasesKey(t *testing.T) {
+	//arrange
+	var calls int32
+	e := newTestEcho(NewMemoryStore(), func(c echo.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("boom")
+		}
+		return c.JSON(http.StatusOK, echo.Map{"Message": "cevap"})
+	})
+
+	//act
+	assert.Panics(t, func() { post(e, "k1", chatBody) })
+	second := post(e, "k1", chatBody)
+
+	//assert
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestMiddleware_ClientErrorIsStored(t *testing.T) {
+	var calls int32
+	e := newTestEcho(NewMemoryStore(), func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid message")
+	})
+
+	post(e, "k1", chatBody)
+	second := post(e, "k1", chatBody)
+
+	assert.Equal(t, http.StatusBadRequest, second.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMiddleware_WithoutKey(t *testing.T) {
+	var calls int32
+	e := newTestEcho(NewMemoryStore(), func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.NoContent(http.StatusOK)
+	})
+
+	post(e, "", chatBody)
+	post(e, "", chatBody)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestMemoryStore_ExpiredKeyStartsOver(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	now := time.Unix(1756212819, 0)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, started, _ := s.Begin(ctx, "k", "fp", time.Minute)
+	assert.True(t, started)
+	assert.NoError(t, s.Complete(ctx, "k", http.StatusOK, echo.MIMEApplicationJSON, []byte("{}")))
+
+	rec, started, _ := s.Begin(ctx, "k", "fp", time.Minute)
+	assert.False(t, started)
+	assert.Equal(t, StatusCompleted, rec.Status)
+
+	now = now.Add(time.Minute)
+	_, started, _ = s.Begin(ctx, "k", "fp", time.Minute)
+	assert.True(t, started)
+}
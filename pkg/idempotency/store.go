@@ -0,0 +1,68 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+)
+
+// Record bir idempotency anahtarının durumu ve (tamamlandıysa) saklanan cevabıdır.
+type Record struct {
+	Key         string `gorm:"column:idempotency_key;primaryKey;size:255"`
+	Fingerprint string `gorm:"size:64"`
+	Status      Status `gorm:"size:16"`
+	StatusCode  int
+	ContentType string `gorm:"size:128"`
+	Body        []byte
+	// TenantID ve KeyVersion şifreleme açıkken Body'yi şifreleyen tenant
+	// key'ini gösterir; KeyVersion 0 ise Body düz metindir.
+	TenantID   string `gorm:"size:64;not null;default:''"`
+	KeyVersion int    `gorm:"not null;default:0"`
+	// Persona ve SessionID retention'ın kayıtlara chat mesajlarıyla aynı
+	// kuralları uygulayıp legal hold'daki session'ları atlaması içindir.
+	Persona   string `gorm:"size:64;not null;default:''"`
+	SessionID string `gorm:"size:64;not null;default:''"`
+	// UserID anahtar hash'lendiği için kullanıcının kayıtlarını silebilmek içindir.
+	UserID    string `gorm:"size:255;not null;default:'';index"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_records"
+}
+
+var ErrNotFound = errors.New("idempotency record not found")
+
+type sessionKey struct{}
+
+// withSession Complete'e cevabın ait olduğu session'ı taşır.
+func withSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+func sessionFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return id
+}
+
+// Store anahtarları saklar. Begin atomik olmalıdır: aynı anahtarla eşzamanlı
+// gelen iki istekten sadece biri started=true almalıdır.
+type Store interface {
+	// Begin anahtar yoksa (ya da süresi dolmuşsa) in-progress bir kayıt açar ve
+	// started=true döner; varsa mevcut kaydı döner.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (rec Record, started bool, err error)
+	// Complete in-progress kaydı cevapla birlikte tamamlar.
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	// Release in-progress kaydı siler ki client aynı anahtarla tekrar deneyebilsin.
+	Release(ctx context.Context, key string) error
+	// DeleteByUser kullanıcının kayıtlarını kalıcı olarak siler ve sayısını döner.
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
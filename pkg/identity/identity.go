@@ -0,0 +1,55 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/labstack/echo"
+)
+
+// Servis authentication'ı yapan gateway'in arkasında çalışır; gateway
+// doğruladığı kimliği bu header'larla iletir.
+const (
+	HeaderUserID   = "X-User-ID"
+	HeaderTenantID = "X-Tenant-ID"
+	HeaderTier     = "X-User-Tier"
+	HeaderPersona  = "X-Persona"
+)
+
+// Identity isteği yapan principal'dır. Alanlar boş olabilir (anonim istek).
+type Identity struct {
+	UserID   string
+	TenantID string
+	Tier     string
+	Persona  string
+	ClientIP string // audit kayıtları için; gateway X-Forwarded-For'u iletir
+}
+
+type ctxKey struct{}
+
+func WithContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext context'teki kimliği döner; yoksa boş Identity.
+func FromContext(ctx context.Context) Identity {
+	id, _ := ctx.Value(ctxKey{}).(Identity)
+	return id
+}
+
+// Middleware gateway header'larından Identity'yi okuyup request context'ine koyar.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := Identity{
+				UserID:   req.Header.Get(HeaderUserID),
+				TenantID: req.Header.Get(HeaderTenantID),
+				Tier:     req.Header.Get(HeaderTier),
+				Persona:  req.Header.Get(HeaderPersona),
+				ClientIP: c.RealIP(),
+			}
+			c.SetRequest(req.WithContext(WithContext(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
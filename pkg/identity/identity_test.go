@@ -0,0 +1,38 @@
+package identity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_ReadsGatewayHeaders(t *testing.T) {
+	//arrange
+	e := echo.New()
+	var got Identity
+	e.GET("/", func(c echo.Context) error {
+		got = FromContext(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	}, Middleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderUserID, "u1")
+	req.Header.Set(HeaderTenantID, "t1")
+	req.Header.Set(HeaderTier, "pro")
+	req.Header.Set(HeaderPersona, "tutor")
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+
+	//act
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	//assert
+	assert.Equal(t, Identity{UserID: "u1", TenantID: "t1", Tier: "pro", Persona: "tutor", ClientIP: "203.0.113.7"}, got)
+}
+
+func TestFromContext_Empty(t *testing.T) {
+	assert.Equal(t, Identity{}, FromContext(context.Background()))
+}
@@ -1,14 +1,36 @@
 package logger
 
-import "go.uber.org/zap"
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
 
 var Log *zap.Logger
 
-func Init(isDev bool) {
+// Init global logger'ı kurar; opts ile core sarılabilir (ör. PII redaksiyonu).
+func Init(isDev bool, opts ...zap.Option) {
 
 	if isDev {
-		Log, _ = zap.NewDevelopment()
+		Log, _ = zap.NewDevelopment(opts...)
 	} else {
-		Log, _ = zap.NewProduction()
+		Log, _ = zap.NewProduction(opts...)
+	}
+}
+
+type ctxKey struct{}
+
+// WithContext request'e özel (requestID vb. alanlı) logger'ı context'e koyar.
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext context'teki request logger'ını döner, yoksa global Log'u.
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+			return l
+		}
 	}
+	return Log
 }
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"myapp/pkg/logger"
+	"time"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+// SessionIDKey handler'ların echo context'ine session id koyduğu anahtar;
+// POST isteklerinde id path'te değil body/response'ta olduğu için gerekli.
+const SessionIDKey = "sessionID"
+
+// AccessLog her isteği status, latency ve session id ile tek satır loglar.
+func AccessLog() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// status'u loglayabilmek için hatayı burada yazdırıyoruz
+				c.Error(err)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			fields := []zap.Field{
+				zap.String("method", req.Method),
+				zap.String("route", c.Path()),
+				zap.String("uri", req.RequestURI),
+				zap.Int("status", res.Status),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int64("bytes", res.Size),
+				zap.String("ip", c.RealIP()),
+			}
+			if sessionID := sessionID(c); sessionID != "" {
+				fields = append(fields, zap.String("sessionID", sessionID))
+			}
+			if err != nil {
+				fields = append(fields, zap.Error(err))
+			}
+
+			log := logger.FromContext(req.Context())
+			switch {
+			case res.Status >= 500:
+				log.Error("request completed", fields...)
+			case res.Status >= 400:
+				log.Warn("request completed", fields...)
+			default:
+				log.Info("request completed", fields...)
+			}
+			return nil
+		}
+	}
+}
+
+func sessionID(c echo.Context) string {
+	if id, ok := c.Get(SessionIDKey).(string); ok && id != "" {
+		return id
+	}
+	return c.Param("sessionId")
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"errors"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestEcho() (*echo.Echo, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.Log = zap.New(core)
+
+	e := echo.New()
+	e.Use(RequestID(), AccessLog(), Recover())
+	return e, logs
+}
+
+func TestRequestID_GeneratesAndPropagates(t *testing.T) {
+	//arrange
+	e, logs := newTestEcho()
+	var ctxID string
+	e.GET("/", func(c echo.Context) error {
+		ctxID = RequestIDFromContext(c.Request().Context())
+		logger.FromContext(c.Request().Context()).Info("in handler")
+		return c.NoContent(http.StatusOK)
+	})
+
+	//act
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	//assert
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, id, ctxID)
+	entry := logs.FilterMessage("in handler").All()
+	assert.Len(t, entry, 1)
+	assert.Equal(t, id, entry[0].ContextMap()["requestID"])
+}
+
+func TestRequestID_KeepsIncomingID(t *testing.T) {
+	e, _ := newTestEcho()
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc-123")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, "abc-123", rec.Header().Get(echo.HeaderXRequestID))
+}
+
+func TestRecover_ReturnsJSON500AndLogsStack(t *testing.T) {
+	//arrange
+	e, logs := newTestEcho()
+	e.GET("/", func(c echo.Context) error {
+		panic("boom")
+	})
+
+	//act
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	//assert
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+	panics := logs.FilterMessage("panic recovered").All()
+	assert.Len(t, panics, 1)
+	assert.Contains(t, panics[0].ContextMap()["stack"], "middleware_test.go")
+}
+
+func TestAccessLog_LogsStatusAndSessionID(t *testing.T) {
+	//arrange
+	e, logs := newTestEcho()
+	e.POST("/chat", func(c echo.Context) error {
+		c.Set(SessionIDKey, "sess-1")
+		return echo.NewHTTPError(http.StatusNotFound, "sessionId not found")
+	})
+	e.GET("/chat/:sessionId", func(c echo.Context) error {
+		return errors.New("db down")
+	})
+
+	//act
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/chat", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/chat/sess-2", nil))
+
+	//assert
+	entries := logs.FilterMessage("request completed").All()
+	assert.Len(t, entries, 2)
+
+	first := entries[0].ContextMap()
+	assert.Equal(t, int64(http.StatusNotFound), first["status"])
+	assert.Equal(t, "sess-1", first["sessionID"])
+	assert.Equal(t, "/chat", first["route"])
+	assert.Contains(t, first, "latency")
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+
+	second := entries[1].ContextMap()
+	assert.Equal(t, int64(http.StatusInternalServerError), second["status"])
+	assert.Equal(t, "sess-2", second["sessionID"])
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+}
+
+func TestAdminAuth(t *testing.T) {
+	//arrange
+	e, _ := newTestEcho()
+	e.GET("/debug/info", func(c echo.Context) error {
+		if !IsAdmin(c.Request().Context()) {
+			return echo.ErrForbidden
+		}
+		return c.NoContent(http.StatusOK)
+	}, AdminAuth("t0ken"))
+	e.GET("/debug/off", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, AdminAuth(""))
+	call := func(path, auth string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set(echo.HeaderAuthorization, auth)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	//act & assert
+	assert.Equal(t, http.StatusOK, call("/debug/info", "Bearer t0ken"))
+	assert.Equal(t, http.StatusUnauthorized, call("/debug/info", "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, call("/debug/info", ""))
+	assert.Equal(t, http.StatusNotFound, call("/debug/off", "Bearer "))
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"myapp/pkg/logger"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+// Recover handler'daki panic'i yakalar, stack trace'i loglar ve isteği
+// merkezi error handler üzerinden JSON 500 olarak bitirir.
+func Recover() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				logger.FromContext(c.Request().Context()).Error("panic recovered",
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()))
+				err = echo.NewHTTPError(http.StatusInternalServerError, "internal server error").
+					SetInternal(fmt.Errorf("panic: %v", r))
+			}()
+			return next(c)
+		}
+	}
+}
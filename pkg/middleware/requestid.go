@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"myapp/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+// maxRequestIDLength client'tan gelen id'nin log'ları şişirmemesi için sınır.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID gelen X-Request-ID'yi kullanır, yoksa üretir. Id response
+// header'ına yazılır ve request context'ine requestID alanlı bir logger konur.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if id == "" || len(id) > maxRequestIDLength {
+				id = uuid.New().String()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+			ctx := context.WithValue(req.Context(), requestIDKey{}, id)
+			ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(zap.String("requestID", id)))
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// RequestIDFromContext RequestID middleware'inin koyduğu id'yi döner.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,105 @@
+package migrate
+
+import (
+	"context"
+	"myapp/pkg/database"
+	"time"
+)
+
+const lockName = "schema_migrations"
+
+// lockKey postgres advisory lock'unun anahtarıdır ("schema_m" baytları).
+const lockKey int64 = 0x736368656d615f6d
+
+// withLock fn'i replica'lar arası migration kilidiyle çalıştırır ve
+// schema_migrations tablosunu gerekirse oluşturur. Kilit bağlantıya bağlı
+// olduğu için fn bitene kadar pool'dan bir bağlantı tutulur.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&Record{}) {
+		if err := db.Migrator().CreateTable(&Record{}); err != nil {
+			return err
+		}
+	}
+	return fn()
+}
+
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	switch m.db.Dialector.Name() {
+	case database.MySQL:
+		return m.lockMySQL(ctx)
+	case database.Postgres:
+		return m.lockPostgres(ctx)
+	default:
+		// SQLite'ta pool tek bağlantılıdır ve yazıcılar dosya kilidiyle sıralanır
+		return func() {}, nil
+	}
+}
+
+func (m *Migrator) lockMySQL(ctx context.Context) (func(), error) {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var got *int
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, int(m.lockTimeout.Seconds())).Scan(&got)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if got == nil || *got != 1 {
+		conn.Close()
+		return nil, ErrLockTimeout
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+		conn.Close()
+	}, nil
+}
+
+// lockPostgres pg_advisory_lock süresiz beklediği için try ile yoklar.
+func (m *Migrator) lockPostgres(ctx context.Context) (func(), error) {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		var got bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&got); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if got {
+			break
+		}
+		if time.Now().After(deadline) {
+			conn.Close()
+			return nil, ErrLockTimeout
+		}
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+		conn.Close()
+	}, nil
+}
@@ -0,0 +1,313 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migrate: applied migration was modified")
+	ErrNoDown           = errors.New("migrate: migration has no down script")
+	ErrLockTimeout      = errors.New("migrate: timed out waiting for the migration lock")
+)
+
+// Migration tek bir şema değişikliğidir. Dosyalar
+// <version>_<name>.up.sql ve (opsiyonel) <version>_<name>.down.sql adını taşır.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Checksum Up'ın sha256'sıdır; uygulanmış bir migration'ın dosyası
+	// sonradan değiştirilirse Up ErrChecksumMismatch döner.
+	Checksum string
+}
+
+// String dosya adındaki biçimdir: 0002_session_timestamp_index.
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Record uygulanan migration'ların tutulduğu schema_migrations satırıdır.
+type Record struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	Checksum  string `gorm:"size:64"`
+	AppliedAt time.Time
+}
+
+func (Record) TableName() string {
+	return "schema_migrations"
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load dir'deki migration dosyalarını version sırasıyla okur.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		data, err := fs.ReadFile(fsys, dir+"/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+			sum := sha256.Sum256(data)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d has no up script", mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status bir migration'ın veritabanındaki durumudur.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	// Modified dosya uygulandıktan sonra değiştirilmiş demektir.
+	Modified bool `json:"modified,omitempty"`
+	// Unknown veritabanında uygulanmış ama bu binary'de olmayan migration'dır
+	// (ör. rolling deploy sırasında yeni sürümün uyguladığı).
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+	adopt       func(ctx context.Context, db *gorm.DB) error
+}
+
+type Option func(*Migrator)
+
+// WithAdopt fn'i ilk migration uygulanmadan hemen önce, kilit altında
+// çalıştırır. Şeması migration'lardan önce başka yolla (ör. AutoMigrate)
+// kurulmuş veritabanlarını ilk migration'ın beklediği şekle getirmek içindir;
+// yarıda kalırsa bir sonraki Up'ta tekrar çalışacağı için fn tekrar
+// çalıştırılabilir olmalıdır.
+func WithAdopt(fn func(ctx context.Context, db *gorm.DB) error) Option {
+	return func(m *Migrator) {
+		m.adopt = fn
+	}
+}
+
+// New lockTimeout sıfırsa başka replica'nın kilidi bırakması 1 dakika beklenir.
+func New(db *gorm.DB, migrations []Migration, lockTimeout time.Duration, opts ...Option) *Migrator {
+	if lockTimeout <= 0 {
+		lockTimeout = time.Minute
+	}
+	m := &Migrator{db: db, migrations: migrations, lockTimeout: lockTimeout}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Up bekleyen migration'ları version sırasıyla uygular ve uygulananları döner.
+// Aynı anda başlayan replica'lardan sadece biri uygular, diğerleri kilidi
+// bekleyip uygulanacak bir şey bulamaz.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func() error {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if m.adopt != nil && len(pending) > 0 && pending[0].Version == m.migrations[0].Version {
+			if err := m.adopt(ctx, m.db.WithContext(ctx)); err != nil {
+				return fmt.Errorf("migrate: adopt: %w", err)
+			}
+		}
+		for _, mig := range pending {
+			if err := m.apply(ctx, mig, mig.Up, func(tx *gorm.DB) error {
+				return tx.Create(&Record{Version: mig.Version, Name: mig.Name, Checksum: mig.Checksum, AppliedAt: time.Now().UTC()}).Error
+			}); err != nil {
+				return fmt.Errorf("migrate: %s: %w", mig, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down uygulanmış son n migration'ı tersten geri alır.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func() error {
+		records, err := m.records(ctx)
+		if err != nil {
+			return err
+		}
+		known := m.byVersion()
+		for i := len(records) - 1; i >= 0 && len(reverted) < n; i-- {
+			mig, ok := known[records[i].Version]
+			if !ok {
+				return fmt.Errorf("migrate: applied version %d is not known to this binary", records[i].Version)
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("%w: %s", ErrNoDown, mig)
+			}
+			if err := m.apply(ctx, mig, mig.Down, func(tx *gorm.DB) error {
+				return tx.Delete(&Record{}, mig.Version).Error
+			}); err != nil {
+				return fmt.Errorf("migrate: %s down: %w", mig, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status bilinen ve veritabanında kayıtlı tüm migration'ları version sırasıyla döner.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	records, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &r.AppliedAt
+			s.Modified = r.Checksum != mig.Checksum
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range applied {
+		statuses = append(statuses, Status{Version: r.Version, Name: r.Name, Applied: true, AppliedAt: &r.AppliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending uygulanmamış migration'ları döner; uygulanmışlardan biri
+// değiştirildiyse ErrChecksumMismatch döner.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	records, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	var pending []Migration
+	for _, mig := range m.migrations {
+		r, ok := applied[mig.Version]
+		if !ok {
+			pending = append(pending, mig)
+			continue
+		}
+		if r.Checksum != mig.Checksum {
+			return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, mig)
+		}
+	}
+	return pending, nil
+}
+
+// records tablo henüz yoksa boş döner.
+func (m *Migrator) records(ctx context.Context) ([]Record, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&Record{}) {
+		return nil, nil
+	}
+	var records []Record
+	err := db.Order("version").Find(&records).Error
+	return records, err
+}
+
+func (m *Migrator) byVersion() map[int]Migration {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+	return known
+}
+
+// apply script'i ve kaydı tek transaction'da çalıştırır. MySQL'de DDL
+// transaction'ı örtük olarak commit ettiği için yarıda kalan bir migration
+// kısmen uygulanmış olabilir ve tekrar denendiğinde baştan çalışır; o yüzden
+// MySQL script'leri ya tek değişiklik içermeli ya da her ifadesi tekrar
+// çalıştırılabilir olmalıdır (ör. CREATE TABLE IF NOT EXISTS).
+func (m *Migrator) apply(ctx context.Context, mig Migration, script string, record func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range Split(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// Split script'i satır sonundaki ';' işaretlerinden ifadelere böler; MySQL
+// driver'ı tek Exec'te birden fazla ifadeye izin vermez. Sadece yorumdan
+// oluşan satırlar atlanır.
+func Split(script string) []string {
+	var stmts []string
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(cur.String()), ";"))
+			cur.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(cur.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
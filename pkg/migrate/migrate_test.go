@@ -0,0 +1,212 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"myapp/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"sqlite/0001_notes.up.sql":        {Data: []byte("CREATE TABLE notes (id integer PRIMARY KEY, body text);")},
+		"sqlite/0001_notes.down.sql":      {Data: []byte("DROP TABLE notes;")},
+		"sqlite/0002_notes_body.up.sql":   {Data: []byte("-- body ile arama\nCREATE INDEX idx_notes_body\n  ON notes (body);\n")},
+		"sqlite/0002_notes_body.down.sql": {Data: []byte("DROP INDEX idx_notes_body;")},
+		"sqlite/README.md":                {Data: []byte("dosya adı uymayanlar atlanır")},
+	}
+}
+
+func newTestMigrator(t *testing.T, fsys fstest.MapFS) (*Migrator, *gorm.DB) {
+	t.Helper()
+	db, err := database.Connect(database.Config{Driver: database.SQLite})
+	require.NoError(t, err)
+	list, err := Load(fsys, "sqlite")
+	require.NoError(t, err)
+	return New(db, list, 0), db
+}
+
+func TestLoad(t *testing.T) {
+	list, err := Load(testFS(), "sqlite")
+
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, 1, list[0].Version)
+	assert.Equal(t, "notes", list[0].Name)
+	assert.Equal(t, "DROP TABLE notes;", list[0].Down)
+	assert.Len(t, list[0].Checksum, 64)
+	assert.Equal(t, "notes_body", list[1].Name)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"down without up": {"d/0001_a.down.sql": {Data: []byte("DROP TABLE a;")}},
+		"two names":       {"d/0001_a.up.sql": {Data: []byte("SELECT 1;")}, "d/0001_b.up.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(fsys, "d")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	stmts := Split("-- yorum\nCREATE TABLE a (\n  id int\n);\n\nCREATE INDEX i ON a (id);\nSELECT 1")
+
+	assert.Equal(t, []string{"CREATE TABLE a (\n  id int\n)", "CREATE INDEX i ON a (id)", "SELECT 1"}, stmts)
+}
+
+func TestUp_AppliesPendingOnce(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	m, db := newTestMigrator(t, testFS())
+
+	//act
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	again, err := m.Up(ctx)
+
+	//assert
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.Empty(t, again)
+	assert.True(t, db.Migrator().HasIndex("notes", "idx_notes_body"))
+	var records []Record
+	require.NoError(t, db.Order("version").Find(&records).Error)
+	require.Len(t, records, 2)
+	assert.Equal(t, applied[1].Checksum, records[1].Checksum)
+}
+
+func TestUp_FailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	fsys := testFS()
+	fsys["sqlite/0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE tags (id integer);\nCREATE TABLE broken (;")}
+	m, db := newTestMigrator(t, fsys)
+
+	applied, err := m.Up(ctx)
+
+	assert.ErrorContains(t, err, "0003_broken")
+	assert.Len(t, applied, 2, "earlier migrations stay applied")
+	assert.False(t, db.Migrator().HasTable("tags"))
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 3, pending[0].Version)
+}
+
+func TestUp_AdoptRunsOnceBeforeFirstMigration(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	db, err := database.Connect(database.Config{Driver: database.SQLite})
+	require.NoError(t, err)
+	list, err := Load(testFS(), "sqlite")
+	require.NoError(t, err)
+	var calls int
+	m := New(db, list, 0, WithAdopt(func(ctx context.Context, db *gorm.DB) error {
+		calls++
+		assert.False(t, db.Migrator().HasTable("notes"), "runs before the first migration")
+		return nil
+	}))
+
+	//act
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+
+	//assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestUp_ModifiedMigration(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	fsys := testFS()
+	m, db := newTestMigrator(t, fsys)
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+	fsys["sqlite/0001_notes.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE notes (id integer PRIMARY KEY, title text);")}
+	list, err := Load(fsys, "sqlite")
+	require.NoError(t, err)
+	m = New(db, list, 0)
+
+	//act
+	_, err = m.Up(ctx)
+	statuses, statusErr := m.Status(ctx)
+
+	//assert
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	require.NoError(t, statusErr)
+	assert.True(t, statuses[0].Modified)
+	assert.False(t, statuses[1].Modified)
+}
+
+func TestDown(t *testing.T) {
+	ctx := context.Background()
+	m, db := newTestMigrator(t, testFS())
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, 2, reverted[0].Version)
+	assert.False(t, db.Migrator().HasIndex("notes", "idx_notes_body"))
+	assert.True(t, db.Migrator().HasTable("notes"))
+
+	reverted, err = m.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	assert.False(t, db.Migrator().HasTable("notes"))
+}
+
+func TestDown_WithoutScript(t *testing.T) {
+	ctx := context.Background()
+	fsys := testFS()
+	delete(fsys, "sqlite/0002_notes_body.down.sql")
+	m, _ := newTestMigrator(t, fsys)
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+
+	_, err = m.Down(ctx, 1)
+
+	assert.ErrorIs(t, err, ErrNoDown)
+}
+
+func TestStatus_UnknownVersion(t *testing.T) {
+	//arrange
+	ctx := context.Background()
+	m, db := newTestMigrator(t, testFS())
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+	// daha yeni bir sürümün uyguladığı migration
+	require.NoError(t, db.Create(&Record{Version: 3, Name: "newer"}).Error)
+
+	//act
+	statuses, err := m.Status(ctx)
+	pending, pendingErr := m.Pending(ctx)
+
+	//assert
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.True(t, statuses[2].Unknown)
+	assert.True(t, statuses[2].Applied)
+	require.NoError(t, pendingErr, "older binaries keep running during a rolling deploy")
+	assert.Empty(t, pending)
+}
+
+func TestStatus_NoTable(t *testing.T) {
+	m, _ := newTestMigrator(t, testFS())
+
+	statuses, err := m.Status(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[0].Applied)
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval dolmuş kovaların temizlenme sıklığı.
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	rule Rule
+}
+
+// NewMemoryStore tek process için in-memory bir Store döner.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (s *memoryStore) Take(_ context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{Bucket: NewBucket(rule, now), rule: rule}
+		s.buckets[key] = b
+	}
+	b.rule = rule
+	return b.Take(rule, now), nil
+}
+
+// sweep tekrar dolmuş kovaları siler; dolu kova ile yeni kova aynı şeydir,
+// böylece map sadece aktif client'lar kadar büyür.
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if b.Full(b.rule, now) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderAPIKey = "X-API-Key"
+	HeaderUserID = "X-User-ID"
+
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// KeyFunc isteğin hangi principal'a ait olduğunu belirler.
+type KeyFunc func(c echo.Context) string
+
+type Config struct {
+	Rule    Rule
+	Store   Store
+	KeyFunc KeyFunc
+	// Now testlerde saati sabitlemek için; boşsa time.Now.
+	Now func() time.Time
+}
+
+// Middleware verilen kurala göre istekleri token bucket ile sınırlar.
+// Store hata verirse istek geçirilir; limiter yüzünden servis düşmesin.
+func Middleware(cfg Config) echo.MiddlewareFunc {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = ByIP
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := cfg.KeyFunc(c)
+			res, err := cfg.Store.Take(c.Request().Context(), key, cfg.Rule, cfg.Now())
+			if err != nil {
+				logger.Log.Warn("rate limit store failed, allowing request",
+					zap.String("key", key),
+					zap.Error(err))
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			h.Set(HeaderLimit, strconv.Itoa(res.Limit))
+			h.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+			h.Set(HeaderReset, strconv.Itoa(seconds(res.ResetAfter)))
+
+			if !res.Allowed {
+				logger.Log.Warn("rate limit exceeded", zap.String("key", key))
+				h.Set(HeaderRetryAfter, strconv.Itoa(seconds(res.RetryAfter)))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+// ByIP client IP'sine göre limitler.
+func ByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// Proxies kimliği doğrulayıp X-API-Key ve X-User-ID header'larını ileten
+// gateway'lerin adresleridir.
+type Proxies []netip.Prefix
+
+// ParseProxies virgülle ayrılmış IP ya da CIDR listesini okur.
+func ParseProxies(s string) (Proxies, error) {
+	var proxies Proxies
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy address %q: %w", part, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy address %q: %w", part, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// Trusts isteğin doğrudan bir gateway'den gelip gelmediğine bakar. Bağlantının
+// adresi kullanılır; X-Forwarded-For'u client da yazabilir.
+func (p Proxies) Trusts(req *http.Request) bool {
+	addrPort, err := netip.ParseAddrPort(req.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ByAPIKey X-API-Key (ya da Bearer token) ile limitler. Anahtar sadece
+// proxies'ten gelen isteklerde kullanılır; yoksa ya da istek gateway'den
+// gelmiyorsa IP'ye düşer, yoksa her istekte farklı anahtar gönderen client
+// limitten kaçabilirdi.
+func ByAPIKey(proxies Proxies) KeyFunc {
+	return func(c echo.Context) string {
+		req := c.Request()
+		if !proxies.Trusts(req) {
+			return ByIP(c)
+		}
+		key := req.Header.Get(HeaderAPIKey)
+		if key == "" {
+			auth := req.Header.Get(echo.HeaderAuthorization)
+			if strings.HasPrefix(auth, "Bearer ") {
+				key = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+		if key == "" {
+			return ByIP(c)
+		}
+		return "key:" + key
+	}
+}
+
+// ByUser gateway'in set ettiği X-User-ID header'ına göre limitler; istek
+// proxies'ten gelmiyorsa header'a güvenilmez, IP'ye düşer.
+func ByUser(proxies Proxies) KeyFunc {
+	return func(c echo.Context) string {
+		req := c.Request()
+		if user := req.Header.Get(HeaderUserID); user != "" && proxies.Trusts(req) {
+			return "user:" + user
+		}
+		return ByIP(c)
+	}
+}
+
+// BySession body'deki SessionID'ye göre limitler. Body okunduktan sonra
+// handler'ın tekrar bind edebilmesi için geri konur.
+func BySession(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil {
+		return ByIP(c)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ByIP(c)
+	}
+
+	var input struct {
+		SessionID string
+	}
+	if err := json.Unmarshal(body, &input); err != nil || input.SessionID == "" {
+		// yeni session: henüz anahtar yok, IP kullan
+		return ByIP(c)
+	}
+	return "session:" + input.SessionID
+}
+
+// KeyFuncByName config'deki isimden KeyFunc seçer. api_key ve user
+// header'ları sadece proxies'ten gelen isteklerde kullanılır.
+func KeyFuncByName(name string, proxies Proxies) (KeyFunc, error) {
+	switch name {
+	case "", "ip":
+		return ByIP, nil
+	case "api_key":
+		return ByAPIKey(proxies), nil
+	case "user":
+		return ByUser(proxies), nil
+	case "session":
+		return BySession, nil
+	}
+	return nil, fmt.Errorf("unknown rate limit key %q", name)
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
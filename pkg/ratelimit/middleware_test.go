@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"io"
+	"myapp/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestEcho(cfg Config) *echo.Echo {
+	e := echo.New()
+	e.POST("/", func(c echo.Context) error {
+		body, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusOK, string(body))
+	}, Middleware(cfg))
+	return e
+}
+
+func TestMiddleware_SetsHeadersAndBlocks(t *testing.T) {
+	//arrange
+	logger.Log = zap.NewNop()
+	now := time.Unix(1756212819, 0)
+	e := newTestEcho(Config{
+		Rule: Rule{Rate: 0.5, Burst: 2},
+		Now:  func() time.Time { return now },
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	//act
+	first := send()
+	send()
+	blocked := send()
+
+	//assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get(HeaderLimit))
+	assert.Equal(t, "1", first.Header().Get(HeaderRemaining))
+	assert.Equal(t, "2", first.Header().Get(HeaderReset))
+
+	assert.Equal(t, http.StatusTooManyRequests, blocked.Code)
+	assert.Equal(t, "0", blocked.Header().Get(HeaderRemaining))
+	assert.Equal(t, "2", blocked.Header().Get(HeaderRetryAfter))
+	assert.Contains(t, blocked.Body.String(), "rate limit exceeded")
+}
+
+func TestMiddleware_ByAPIKey(t *testing.T) {
+	logger.Log = zap.NewNop()
+	proxies, err := ParseProxies("10.0.0.0/8")
+	require.NoError(t, err)
+	e := newTestEcho(Config{
+		Rule:    Rule{Rate: 1, Burst: 1},
+		KeyFunc: ByAPIKey(proxies),
+	})
+
+	send := func(key string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set(HeaderAPIKey, key)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("k1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("k1"))
+	assert.Equal(t, http.StatusOK, send("k2"))
+}
+
+func TestMiddleware_RotatingHeadersFromUntrustedClient(t *testing.T) {
+	logger.Log = zap.NewNop()
+	proxies, err := ParseProxies("10.0.0.1")
+	require.NoError(t, err)
+
+	for name, keyFunc := range map[string]KeyFunc{
+		HeaderAPIKey: ByAPIKey(proxies),
+		HeaderUserID: ByUser(proxies),
+	} {
+		t.Run(name, func(t *testing.T) {
+			//arrange
+			e := newTestEcho(Config{
+				Rule:    Rule{Rate: 1, Burst: 1},
+				KeyFunc: keyFunc,
+			})
+			send := func(value string) int {
+				req := httptest.NewRequest(http.MethodPost, "/", nil)
+				req.RemoteAddr = "203.0.113.7:1234"
+				req.Header.Set(name, value)
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				return rec.Code
+			}
+
+			//act
+			first := send("v1")
+			second := send("v2")
+
+			//assert
+			assert.Equal(t, http.StatusOK, first)
+			assert.Equal(t, http.StatusTooManyRequests, second, "a new header value must not open a new bucket")
+		})
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	proxies, err := ParseProxies("10.0.0.1, 192.168.0.0/16")
+	require.NoError(t, err)
+
+	trusts := func(addr string) bool {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = addr
+		return proxies.Trusts(req)
+	}
+	assert.True(t, trusts("10.0.0.1:80"))
+	assert.False(t, trusts("10.0.0.2:80"))
+	assert.True(t, trusts("192.168.4.2:80"))
+	assert.False(t, trusts("[::1]:80"))
+
+	_, err = ParseProxies("gateway")
+	assert.Error(t, err)
+}
+
+func TestBySession_RestoresBody(t *testing.T) {
+	logger.Log = zap.NewNop()
+	e := newTestEcho(Config{
+		Rule:    Rule{Rate: 1, Burst: 1},
+		KeyFunc: BySession,
+	})
+	body := `{"Message":"merhaba","SessionID":"811360d0-462f-4fbf-b90b-ccba665986f1"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, body, rec.Body.String())
+
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)), httptest.NewRecorder())
+	assert.Equal(t, "session:811360d0-462f-4fbf-b90b-ccba665986f1", BySession(c))
+}
+
+func TestKeyFuncByName_Unknown(t *testing.T) {
+	_, err := KeyFuncByName("tenant", nil)
+	assert.Error(t, err)
+}
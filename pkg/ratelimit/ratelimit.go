@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Rule bir token bucket tanımıdır: kova en fazla Burst token tutar ve
+// saniyede Rate token ile dolar. Her istek bir token harcar.
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute dakikada n istek ve verilen burst ile bir Rule oluşturur.
+func PerMinute(n int, burst int) Rule {
+	return Rule{Rate: float64(n) / 60, Burst: burst}
+}
+
+// Result tek bir Take çağrısının sonucudur; header'lar bundan üretilir.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // kova tamamen dolana kadar geçecek süre
+	RetryAfter time.Duration // reddedildiyse bir sonraki token'a kadar geçecek süre
+}
+
+// Store bucket durumunu tutan backend'dir. In-memory implementasyon tek
+// replika için yeterli; birden fazla replika için (redis vb.) paylaşımlı bir
+// implementasyon yazılıp aynı arayüzle takılabilir. Take atomik olmalıdır.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error)
+}
+
+// Bucket tek bir anahtarın token bucket durumudur. Store implementasyonları
+// durumu serialize edip Take ile aynı hesabı kullanabilir.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// NewBucket dolu bir kova döner.
+func NewBucket(rule Rule, now time.Time) Bucket {
+	return Bucket{Tokens: float64(rule.Burst), Updated: now}
+}
+
+// Take kovayı now anına kadar doldurur ve bir token harcamaya çalışır.
+func (b *Bucket) Take(rule Rule, now time.Time) Result {
+	burst := float64(rule.Burst)
+	if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed.Seconds()*rule.Rate)
+		b.Updated = now
+	}
+
+	res := Result{Limit: rule.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = rule.durationFor(1 - b.Tokens)
+	}
+	res.Remaining = int(math.Floor(b.Tokens))
+	res.ResetAfter = rule.durationFor(burst - b.Tokens)
+	return res
+}
+
+// Full kova now anında tamamen dolmuş mu, yani silinebilir mi.
+func (b *Bucket) Full(rule Rule, now time.Time) bool {
+	return b.Tokens+now.Sub(b.Updated).Seconds()*rule.Rate >= float64(rule.Burst)
+}
+
+func (r Rule) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if r.Rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / r.Rate * float64(time.Second))
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket_TakeUntilEmpty(t *testing.T) {
+	//arrange
+	rule := Rule{Rate: 1, Burst: 3}
+	now := time.Unix(1756212819, 0)
+	b := NewBucket(rule, now)
+
+	//act & assert
+	for i := 2; i >= 0; i-- {
+		res := b.Take(rule, now)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+		assert.Equal(t, 3, res.Limit)
+	}
+	res := b.Take(rule, now)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.ResetAfter)
+}
+
+func TestBucket_Refill(t *testing.T) {
+	rule := Rule{Rate: 2, Burst: 2}
+	now := time.Unix(1756212819, 0)
+	b := NewBucket(rule, now)
+	b.Take(rule, now)
+	b.Take(rule, now)
+
+	res := b.Take(rule, now.Add(250*time.Millisecond))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 250*time.Millisecond, res.RetryAfter)
+
+	res = b.Take(rule, now.Add(500*time.Millisecond))
+	assert.True(t, res.Allowed)
+
+	// uzun süre sonra burst'ü aşmamalı
+	res = b.Take(rule, now.Add(time.Hour))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+}
+
+func TestMemoryStore_KeysAreIndependent(t *testing.T) {
+	store := NewMemoryStore()
+	rule := Rule{Rate: 1, Burst: 1}
+	now := time.Unix(1756212819, 0)
+	ctx := context.Background()
+
+	res, err := store.Take(ctx, "a", rule, now)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, _ = store.Take(ctx, "a", rule, now)
+	assert.False(t, res.Allowed)
+
+	res, _ = store.Take(ctx, "b", rule, now)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore().(*memoryStore)
+	rule := Rule{Rate: 1, Burst: 1}
+	now := time.Unix(1756212819, 0)
+	ctx := context.Background()
+
+	store.Take(ctx, "a", rule, now)
+	store.Take(ctx, "b", rule, now.Add(2*time.Minute))
+
+	assert.Len(t, store.buckets, 1)
+	assert.Contains(t, store.buckets, "b")
+}
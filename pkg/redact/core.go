@@ -0,0 +1,69 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type core struct {
+	zapcore.Core
+	r Redactor
+}
+
+// NewCore log mesajını ve alanlarını yazılmadan önce redakte eden bir core
+// döner; logger.Init'e zap.WrapCore ile verilir. Struct ve slice alanları
+// (ör. zap.Any("chat_history", ...)) JSON'a çevrilip redakte edilir.
+func NewCore(c zapcore.Core, r Redactor) zapcore.Core {
+	return &core{Core: c, r: r}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(c.fields(fields)), r: c.r}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.Redact(ent.Message)
+	return c.Core.Write(ent, c.fields(fields))
+}
+
+func (c *core) fields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = c.field(f)
+	}
+	return out
+}
+
+func (c *core) field(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = c.r.Redact(f.String)
+	case zapcore.ByteStringType:
+		f.Interface = []byte(c.r.Redact(string(f.Interface.([]byte))))
+	case zapcore.ErrorType:
+		// hata mesajları upstream'den gelen prompt parçalarını içerebilir
+		if err, ok := f.Interface.(error); ok {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.r.Redact(err.Error())}
+		}
+	case zapcore.StringerType:
+		return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.r.Redact(fmt.Sprint(f.Interface))}
+	case zapcore.ReflectType, zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		data, err := json.Marshal(enc.Fields[f.Key])
+		if err != nil {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: "[UNREDACTABLE]"}
+		}
+		return zapcore.Field{Key: f.Key, Type: zapcore.ReflectType, Interface: json.RawMessage(c.r.Redact(string(data)))}
+	}
+	return f
+}
@@ -0,0 +1,219 @@
+package redact
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule bir PII türünü tanır. Valid nil değilse regex eşleşmesi ayrıca
+// doğrulanır (Luhn, IBAN mod-97, TCKN checksum) ki sıradan sayılar maskelenmesin.
+type Rule struct {
+	Name    string // placeholder'da kullanılır: [EMAIL], [EMAIL_1]
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool
+}
+
+// Redactor metindeki PII'ı siler ya da geri açılabilir şekilde maskeler.
+type Redactor interface {
+	// Redact eşleşmeleri [NAME] ile değiştirir; log'lar için.
+	Redact(s string) string
+	// Mask eşleşmeleri [NAME_n] ile değiştirir ve vault'a yazar; aynı değer
+	// aynı vault'ta hep aynı placeholder'ı alır.
+	Mask(s string, v *Vault) string
+}
+
+type redactor struct {
+	rules []Rule
+}
+
+// New kuralları sırayla uygular; daha özgül kurallar önce gelmelidir.
+// Kural verilmezse Default kullanılır.
+func New(rules ...Rule) Redactor {
+	if len(rules) == 0 {
+		rules = Default()
+	}
+	return &redactor{rules: rules}
+}
+
+// Default e-posta, IBAN, kredi kartı, TC kimlik no ve telefon kurallarıdır.
+// Sıra önemli: kart ve TCKN telefon regex'ine de uyabilir.
+func Default() []Rule {
+	return []Rule{
+		{Name: "EMAIL", Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+		{Name: "IBAN", Pattern: regexp.MustCompile(`(?i)\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`), Valid: validIBAN},
+		{Name: "CARD", Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: validLuhn},
+		{Name: "TCKN", Pattern: regexp.MustCompile(`\b[1-9]\d{10}\b`), Valid: validTCKN},
+		{Name: "PHONE", Pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(0?\d{3}\)|\b0?\d{3})[ .-]?\d{3}[ .-]?(?:\d{2}[ .-]?\d{2}|\d{4})\b`), Valid: validPhone},
+	}
+}
+
+// ParseRules "NAME=regex;NAME=regex" biçimindeki özel kuralları okur.
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, expr, ok := strings.Cut(part, "=")
+		if !ok || name == "" || expr == "" {
+			return nil, fmt.Errorf("invalid redaction rule %q, want NAME=regex", part)
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %s: %w", name, err)
+		}
+		rules = append(rules, Rule{Name: strings.ToUpper(strings.TrimSpace(name)), Pattern: pattern})
+	}
+	return rules, nil
+}
+
+func (r *redactor) Redact(s string) string {
+	return r.replace(s, func(rule Rule, _ string) string {
+		return "[" + rule.Name + "]"
+	})
+}
+
+func (r *redactor) Mask(s string, v *Vault) string {
+	return r.replace(s, func(rule Rule, match string) string {
+		return v.placeholder(rule.Name, match)
+	})
+}
+
+func (r *redactor) replace(s string, with func(Rule, string) string) string {
+	for _, rule := range r.rules {
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.Valid != nil && !rule.Valid(match) {
+				return match
+			}
+			return with(rule, match)
+		})
+	}
+	return s
+}
+
+// Vault tek bir istek boyunca maskelenen değerleri tutar; concurrent
+// kullanım için değildir.
+type Vault struct {
+	placeholders map[string]string // değer -> placeholder
+	values       map[string]string // placeholder -> değer
+	counts       map[string]int
+}
+
+func NewVault() *Vault {
+	return &Vault{
+		placeholders: make(map[string]string),
+		values:       make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+func (v *Vault) placeholder(name, value string) string {
+	if p, ok := v.placeholders[value]; ok {
+		return p
+	}
+	v.counts[name]++
+	p := "[" + name + "_" + strconv.Itoa(v.counts[name]) + "]"
+	v.placeholders[value] = p
+	v.values[p] = value
+	return p
+}
+
+// Restore metindeki placeholder'ları asıl değerlerle değiştirir.
+func (v *Vault) Restore(s string) string {
+	if len(v.values) == 0 || !strings.Contains(s, "[") {
+		return s
+	}
+	pairs := make([]string, 0, 2*len(v.values))
+	for p, value := range v.values {
+		pairs = append(pairs, p, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func validLuhn(match string) bool {
+	d := digits(match)
+	if len(d) < 13 || len(d) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(d) - 1; i >= 0; i-- {
+		n := int(d[i] - '0')
+		if (len(d)-i)%2 == 0 {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+// validTCKN TC kimlik no algoritması: 10. hane tek ve çift hanelerden,
+// 11. hane ilk 10 hanenin toplamından türetilir.
+func validTCKN(match string) bool {
+	if len(match) != 11 || match[0] == '0' {
+		return false
+	}
+	var d [11]int
+	for i := range match {
+		d[i] = int(match[i] - '0')
+	}
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return false
+	}
+	sum := 0
+	for _, n := range d[:10] {
+		sum += n
+	}
+	return sum%10 == d[10]
+}
+
+// validIBAN ISO 13616 mod-97 kontrolü.
+func validIBAN(match string) bool {
+	iban := strings.ToUpper(strings.ReplaceAll(match, " ", ""))
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var b strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			b.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validPhone ayırıcısız 10 haneli sayıları sadece 5 ile başlıyorsa (TR cep)
+// telefon sayar; yoksa unix timestamp'ler de maskelenirdi.
+func validPhone(match string) bool {
+	d := digits(match)
+	if len(d) < 10 || len(d) > 13 {
+		return false
+	}
+	if len(d) == len(match) && match[0] != '0' {
+		return len(d) == 10 && match[0] == '5'
+	}
+	return true
+}
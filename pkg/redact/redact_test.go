@@ -0,0 +1,102 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRedact(t *testing.T) {
+	r := New()
+	cases := []struct {
+		name, in, want string
+	}{
+		{"email", "mailim ali.veli@example.com.tr", "mailim [EMAIL]"},
+		{"iban", "IBAN: TR33 0006 1005 1978 6457 8413 26 olsun", "IBAN: [IBAN] olsun"},
+		{"iban compact", "GB82WEST12345698765432", "[IBAN]"},
+		{"invalid iban", "TR33 0006 1005 1978 6457 8413 27", "TR33 0006 1005 1978 6457 8413 27"},
+		{"card", "kart 4111 1111 1111 1111 son", "kart [CARD] son"},
+		{"card dashes", "5500-0000-0000-0004", "[CARD]"},
+		{"not luhn", "sipariş 4111111111111112", "sipariş 4111111111111112"},
+		{"tckn", "tc no 10000000146", "tc no [TCKN]"},
+		{"invalid tckn", "no 12345678901", "no 12345678901"},
+		{"phone", "beni +90 532 123 45 67 numarasından ara", "beni [PHONE] numarasından ara"},
+		{"phone local", "0532-123-4567", "[PHONE]"},
+		{"mobile", "5321234567", "[PHONE]"},
+		{"timestamp", `{"Timestamp":1760000000}`, `{"Timestamp":1760000000}`},
+		{"clean", "merhaba, nasılsın?", "merhaba, nasılsın?"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, r.Redact(tc.in))
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("employee=EMP-\\d{6}; ORDER=ORD-[0-9]+")
+	require.NoError(t, err)
+
+	r := New(append(Default(), rules...)...)
+
+	assert.Equal(t, "[EMPLOYEE] ve [ORDER]", r.Redact("EMP-123456 ve ORD-42"))
+
+	_, err = ParseRules("bozuk")
+	assert.Error(t, err)
+	_, err = ParseRules("X=(")
+	assert.Error(t, err)
+}
+
+func TestMaskAndRestore(t *testing.T) {
+	//arrange
+	r := New()
+	v := NewVault()
+
+	//act
+	first := r.Mask("mail a@b.com, tekrar a@b.com, diğer c@d.com", v)
+	second := r.Mask("yine a@b.com", v)
+	restored := v.Restore("[EMAIL_2] ve [EMAIL_1] adreslerine yazdım")
+
+	//assert
+	assert.Equal(t, "mail [EMAIL_1], tekrar [EMAIL_1], diğer [EMAIL_2]", first)
+	assert.Equal(t, "yine [EMAIL_1]", second)
+	assert.Equal(t, "c@d.com ve a@b.com adreslerine yazdım", restored)
+}
+
+type history struct {
+	Message string
+	Seq     int
+}
+
+func TestCore(t *testing.T) {
+	//arrange
+	var buf bytes.Buffer
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	log := zap.New(NewCore(zapcore.NewCore(enc, zapcore.AddSync(&buf), zap.InfoLevel), New())).
+		With(zap.String("user", "a@b.com"))
+
+	//act
+	log.Info("mesaj a@b.com",
+		zap.String("message", "kartım 4111111111111111"),
+		zap.Any("chat_history", []history{{Message: "tc 10000000146", Seq: 1}}),
+		zap.Error(errors.New("upstream rejected a@b.com")),
+		zap.Int("count", 3))
+	log.Debug("görünmemeli")
+
+	//assert
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, "mesaj [EMAIL]", out["msg"])
+	assert.Equal(t, "[EMAIL]", out["user"])
+	assert.Equal(t, "kartım [CARD]", out["message"])
+	assert.Equal(t, []any{map[string]any{"Message": "tc [TCKN]", "Seq": 1.0}}, out["chat_history"])
+	assert.Equal(t, "upstream rejected [EMAIL]", out["error"])
+	assert.Equal(t, 3.0, out["count"])
+	assert.NotContains(t, buf.String(), "görünmemeli")
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress webhook loopback, özel ağ ya da link-local bir adrese
+// gönderilmek istendiğinde döner.
+var ErrForbiddenAddress = errors.New("webhook: destination address is not allowed")
+
+// reserved Is* metotlarının kapsamadığı, dışarıdan erişilmemesi gereken aralıklardır.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 iç adreslere çevrilebilir
+}
+
+// PublicAddr ip'nin internete açık bir unicast adres olduğunu söyler.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+	for _, p := range reserved {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublicOnly net.Dialer.Control'dür. Kontrol DNS çözümlendikten sonra, bağlanılan
+// adres üzerinde yapılır; URL doğrulandıktan sonra DNS kaydı iç bir adrese
+// çevrilse ya da alıcı iç adrese yönlendirse de bağlantı kurulmaz.
+func PublicOnly(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(ap.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ap.Addr())
+	}
+	return nil
+}
+
+// NewClient webhook göndermek için bir http.Client döner. allowPrivate false
+// ise sadece public adreslere bağlanılır ve proxy kullanılmaz (proxy adresi
+// genelde iç ağdadır ve asıl hedefi gizler).
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer.Control = PublicOnly
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
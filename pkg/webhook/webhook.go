@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Servisin dışarı gönderdiği tüm webhook'lar (job sonuçları, domain event'leri)
+// aynı şekilde imzalanır; alıcı tek bir doğrulama koduyla hepsini kontrol eder.
+const (
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign webhook imzasını üretir: "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Timestamp imzaya dahil olduğu için alıcı eski payload'ların tekrar gönderilmesini
+// reddedebilir.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify alıcı tarafın imzayı doğrulaması içindir.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
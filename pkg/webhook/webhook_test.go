@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"j1"}`)
+	sig := Sign("s3cret", 1756212819, body)
+
+	assert.True(t, Verify("s3cret", 1756212819, body, sig))
+	assert.False(t, Verify("s3cret", 1756212820, body, sig))
+	assert.False(t, Verify("other", 1756212819, body, sig))
+	assert.False(t, Verify("s3cret", 1756212819, []byte(`{"id":"j2"}`), sig))
+}
+
+func TestPublicAddr(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"100.64.0.1":       false,
+		"::ffff:127.0.0.1": false,
+	}
+	for addr, want := range cases {
+		assert.Equal(t, want, PublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestNewClient_RefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second, false).Post(srv.URL, "application/json", nil)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+
+	res, err := NewClient(time.Second, true).Post(srv.URL, "application/json", nil)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+}